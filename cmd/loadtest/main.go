@@ -0,0 +1,91 @@
+// Command loadtest sends concurrent assign and unassign requests to a
+// running server and prints their latency percentiles:
+//
+//	go run ./cmd/loadtest -url http://localhost:8080 -rate 100 -duration 1m -users-file users.txt -top-up 10000
+//
+// The bikes still ridden are returned at the end, then the assignments and
+// the bikes are checked: it exits with status 1 when a bike is in two open
+// assignments or its usage count does not match its assignments.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/loadtest"
+)
+
+func main() {
+	config := loadtest.Config{}
+	var users, usersFile string
+
+	flag.StringVar(&config.URL, "url", "http://localhost:8080", "URL of the server")
+	flag.StringVar(&users, "users", "d0ab33d7-8fcc-463d-bade-fefd53b77a96,0b28a7ed-39ef-418f-a0e3-8ad3f794dfc7", "comma-separated UUIDs of the customers, the seeded ones by default")
+	flag.StringVar(&usersFile, "users-file", "", "file of customer UUIDs, one per line, instead of -users")
+	flag.Float64Var(&config.Rate, "rate", 50, "requests per second")
+	flag.DurationVar(&config.Duration, "duration", 30*time.Second, "duration of the traffic")
+	flag.IntVar(&config.Concurrency, "concurrency", 32, "requests in flight at most")
+	flag.DurationVar(&config.Timeout, "timeout", 10*time.Second, "timeout of each request")
+	flag.StringVar(&config.StationID, "station", "", "station the bikes are taken from and returned to, any when empty")
+	flag.Int64Var(&config.TopUp, "top-up", 0, "cents credited to each user before the run")
+	flag.StringVar(&config.Operator, "operator", "da690323-5a78-4d46-a214-943b2ec9d49e", "UUID of the supervisor recording the top-ups, the seeded admin by default")
+	flag.Int64Var(&config.Seed, "seed", time.Now().UnixNano(), "seed of the user picks")
+	flag.Parse()
+
+	var err error
+	if usersFile != "" {
+		config.Users, err = readUsers(usersFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read users: %v\n", err)
+			os.Exit(2)
+		}
+	} else {
+		for _, id := range strings.Split(users, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				config.Users = append(config.Users, id)
+			}
+		}
+	}
+
+	// Interrupting stops the traffic, the invariants are still checked
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := loadtest.Run(ctx, config)
+	if report != nil {
+		report.Print(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load test failed: %v\n", err)
+		os.Exit(2)
+	}
+	if len(report.Violations) > 0 {
+		os.Exit(1)
+	}
+}
+
+// readUsers reads the UUIDs of a file, one per line, skipping blank lines
+// and # comments
+func readUsers(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var users []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			users = append(users, line)
+		}
+	}
+	return users, scanner.Err()
+}
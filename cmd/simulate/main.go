@@ -0,0 +1,52 @@
+// Command simulate evaluates the assignment rules, the least used bike and
+// the cooldown, on a synthetic fleet and commuters:
+//
+//	go run ./cmd/simulate -bikes 150 -rate 90 -cooldown 2m
+//
+// It reports the wait times and rejections of the commuters, the spread of
+// the usage counts across the bikes and the rides becoming overdue.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/simulation"
+)
+
+func main() {
+	config := simulation.DefaultConfig()
+	start := config.Start.Format(time.RFC3339)
+
+	flag.IntVar(&config.Stations, "stations", config.Stations, "number of stations")
+	flag.IntVar(&config.Docks, "docks", config.Docks, "docks per station")
+	flag.IntVar(&config.Bikes, "bikes", config.Bikes, "number of bikes, spread evenly over the stations")
+	flag.StringVar(&start, "start", start, "start of the simulation, RFC 3339")
+	flag.DurationVar(&config.Duration, "duration", config.Duration, "simulated time")
+	flag.StringVar(&config.Arrivals, "arrivals", config.Arrivals, "arrival distribution: poisson, uniform or commute")
+	flag.Float64Var(&config.Rate, "rate", config.Rate, "commuters per hour over the network, on average")
+	flag.DurationVar(&config.RideMean, "ride-mean", config.RideMean, "mean ride duration")
+	flag.Float64Var(&config.RideSigma, "ride-sigma", config.RideSigma, "sigma of the log-normal ride durations, 0 for constant rides")
+	flag.Float64Var(&config.OverdueRate, "overdue-rate", config.OverdueRate, "share of the bikes kept over 24 hours")
+	flag.DurationVar(&config.Patience, "patience", config.Patience, "how long a commuter waits for a bike")
+	flag.DurationVar(&config.Cooldown, "cooldown", config.Cooldown, "rest of a returned bike before it can be handed over")
+	flag.StringVar(&config.Rule, "rule", config.Rule, "bike handed over: least-used or random")
+	flag.Int64Var(&config.Seed, "seed", config.Seed, "seed of the random generator, runs being reproducible")
+	flag.Parse()
+
+	var err error
+	config.Start, err = time.Parse(time.RFC3339, start)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid start: %v\n", err)
+		os.Exit(2)
+	}
+
+	report, err := simulation.Run(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid simulation: %v\n", err)
+		os.Exit(2)
+	}
+	report.Print(os.Stdout)
+}
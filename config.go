@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"github.com/yourusername/bike-rental/src/api"
+	"github.com/yourusername/bike-rental/src/database"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/reconcile"
+	"github.com/yourusername/bike-rental/src/tracing"
+)
+
+// Config is the configuration file, each package reading its own section
+type Config struct {
+	Database  database.Config  `toml:"database"`
+	API       api.Config       `toml:"api"`
+	Reconcile reconcile.Config `toml:"reconcile"`
+	Logging   logger.Config    `toml:"logging"`
+	Tracing   tracing.Config   `toml:"tracing"`
+}
+
+func loadConfig(path string) (*Config, error) {
+	config := &Config{}
+
+	// Open the config file
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Parse the config file
+	_, err = toml.NewDecoder(file).Decode(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
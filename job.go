@@ -0,0 +1,70 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/cronjobs"
+	"github.com/yourusername/bike-rental/src/database"
+)
+
+// cronJob is a job run by the server on schedule, or once with job run
+type cronJob struct {
+	name     string
+	schedule string
+	run      func(db *sql.DB, clk clock.Clock, config *Config)
+}
+
+var jobs = []cronJob{
+	{"overdue", "@every 5m", func(db *sql.DB, clk clock.Clock, config *Config) {
+		cronjobs.EscalateOverdueAssignments(db, clk, &config.API.Pricing)
+	}},
+	{"reservations", "@every 1m", func(db *sql.DB, clk clock.Clock, config *Config) {
+		cronjobs.ExpireReservations(db, clk)
+	}},
+	{"waitlist", "@every 1m", func(db *sql.DB, clk clock.Clock, config *Config) {
+		cronjobs.OfferBikesToWaitlist(db, clk, config.API.Reservations.Hold, config.API.Wallet.MinimumBalance)
+	}},
+	{"subscriptions", "@hourly", func(db *sql.DB, clk clock.Clock, config *Config) {
+		cronjobs.RenewSubscriptions(db, clk)
+	}},
+	{"invoices", "@daily", func(db *sql.DB, clk clock.Clock, config *Config) {
+		cronjobs.IssueMonthlyInvoices(db, clk)
+	}},
+	{"reconcile", "@every 15m", func(db *sql.DB, clk clock.Clock, config *Config) {
+		cronjobs.Reconcile(db, clk, &config.Reconcile)
+	}},
+}
+
+// job runs a cron job once, outside of the server. Failures are logged by
+// the job itself.
+func job(config *Config, args []string) error {
+	flags := newFlagSet("job", "job run NAME")
+	if err := parse(flags, args, 2); err != nil {
+		return err
+	}
+	if flags.Arg(0) != "run" || flags.NArg() != 2 {
+		flags.Usage()
+		return errUsage
+	}
+
+	var names []string
+	for _, job := range jobs {
+		if job.name != flags.Arg(1) {
+			names = append(names, job.name)
+			continue
+		}
+
+		db, err := database.InitDB(&config.Database)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		job.run(db, clock.Real{}, config)
+		return nil
+	}
+	return fmt.Errorf("unknown job %q, expected one of %s", flags.Arg(1), strings.Join(names, ", "))
+}
@@ -1,68 +1,115 @@
+// Command bikerental serves the API and runs the operations around it, each
+// on its own:
+//
+//	bikerental [-config config.toml] serve
+//	bikerental migrate up|down [N]|to VERSION|status
+//	bikerental seed -file src/database/fixtures/dev.json
+//	bikerental clean -confirm
+//	bikerental job run overdue|reservations|waitlist|subscriptions|invoices|reconcile
+//	bikerental reconcile [-repair]
 package main
 
 import (
-	"net/http"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/robfig/cron/v3"
-	"github.com/rs/zerolog/log"
-	"github.com/yourusername/bike-rental/src/controllers"
-	"github.com/yourusername/bike-rental/src/cronjobs"
-	"github.com/yourusername/bike-rental/src/database"
 	"github.com/yourusername/bike-rental/src/logger"
 )
 
+// errUsage is returned when the command line is invalid, the usage having
+// been printed already
+var errUsage = errors.New("invalid usage")
+
+// errInconsistent is returned when inconsistencies are left unrepaired,
+// exiting with status 1 without message
+var errInconsistent = errors.New("inconsistencies left")
+
+// commands are run with the configuration and the arguments following
+// their name
+var commands = map[string]func(config *Config, args []string) error{
+	"serve":     serve,
+	"migrate":   migrate,
+	"seed":      seed,
+	"clean":     clean,
+	"job":       job,
+	"reconcile": checkConsistency,
+}
+
+func usage() {
+	fmt.Fprint(flag.CommandLine.Output(), `Usage: bikerental [-config FILE] COMMAND [ARGS]
+
+Commands:
+  serve                        serve the API and run the cron jobs
+  migrate up                   apply the pending migrations
+  migrate down [N]             revert the last N migrations, 1 by default
+  migrate to VERSION           migrate up or down to VERSION
+  migrate status               print the version of the schema
+  seed -file FILE              insert the records of a fixtures file missing from the database
+  clean -confirm               delete the users, the fleet and the rides
+  job run NAME                 run a cron job once: overdue, reservations, waitlist, subscriptions, invoices or reconcile
+  reconcile [-repair]          list the bikes and assignments disagreeing, and repair them
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
 func main() {
-	logger.Init()
+	path := flag.String("config", "config.toml", "configuration file")
+	flag.Usage = usage
+	flag.Parse()
 
-	log.Info().Msg("Initializing db connection...")
+	command, ok := commands[flag.Arg(0)]
+	if !ok {
+		if flag.NArg() > 0 {
+			fmt.Fprintf(os.Stderr, "bikerental: unknown command %q\n", flag.Arg(0))
+		}
+		usage()
+		os.Exit(2)
+	}
 
 	// Load the configuration
-	config, err := database.LoadConfig("config.toml")
+	config, err := loadConfig(*path)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to load configuration")
+		fmt.Fprintf(os.Stderr, "bikerental: failed to load configuration: %v\n", err)
+		os.Exit(1)
 	}
+	logger.Init(&config.Logging)
 
-	// Initialize the database connection
-	db, err := database.InitDB(&config.Database)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to connect to the database")
+	switch err := command(config, flag.Args()[1:]); err {
+	case nil:
+	case errUsage:
+		os.Exit(2)
+	case errInconsistent:
+		os.Exit(1)
+	default:
+		fmt.Fprintf(os.Stderr, "bikerental: %v\n", err)
+		os.Exit(1)
 	}
+}
 
-	// Migrate the schema
-	database.Migrate(&config.Database)
-	// Clean the database
-	// database.CleanDatabase(db)
-	// Seed the database with fixtures
-	database.SeedDatabase(db)
-
-	// Initialize the HTTP server and routes...
-	r := chi.NewRouter()
-	// Installing logger middleware for debugging...
-	r.Use(logger.LoggerMiddleware)
-
-	r.Get("/assignments", func(w http.ResponseWriter, r *http.Request) {
-		controllers.GetAllAssignments(w, r, db)
-	})
-	r.Post("/bikes/assign", func(w http.ResponseWriter, r *http.Request) {
-		controllers.AssignBike(w, r, db)
-	})
-	r.Post("/bikes/unassign", func(w http.ResponseWriter, r *http.Request) {
-		controllers.UnassignBike(w, r, db)
-	})
-	r.Get("/bikes/available", func(w http.ResponseWriter, r *http.Request) {
-		controllers.GetAvailableBikes(w, r, db)
-	})
-	r.Get("/bikes", func(w http.ResponseWriter, r *http.Request) {
-		controllers.GetAllBikes(w, r, db)
-	})
-
-	// Set up the cron job to run the function every hour
-	log.Info().Msg("Setting up cronjobs...")
-	c := cron.New()
-	c.AddFunc("@hourly", func() { cronjobs.AutoUnassignOverdueBikes(db) })
-	c.Start()
+// newFlagSet returns the flags of a command, printing usage on errors
+func newFlagSet(name, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: bikerental %s\n", usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
 
-	log.Info().Msg("Starting server...")
-	http.ListenAndServe(":8080", r)
+// parse parses the flags of a command, returning errUsage when they are
+// invalid or arguments are left over beyond maxArgs
+func parse(flags *flag.FlagSet, args []string, maxArgs int) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > maxArgs {
+		fmt.Fprintf(flags.Output(), "bikerental %s: unexpected arguments %q\n", flags.Name(), flags.Args()[maxArgs:])
+		flags.Usage()
+		return errUsage
+	}
+	return nil
 }
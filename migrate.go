@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/yourusername/bike-rental/src/database"
+)
+
+// migrate applies or reverts the migrations of the schema, or prints its
+// version
+func migrate(config *Config, args []string) error {
+	flags := newFlagSet("migrate", "migrate up|down [N]|to VERSION|status")
+	migrationsPath := flags.String("migrations", database.MigrationsPath, "source of the migrations")
+	if err := parse(flags, args, 2); err != nil {
+		return err
+	}
+
+	// The argument of the subcommand, if any
+	var arg uint64
+	switch sub := flags.Arg(0); {
+	case (sub == "up" || sub == "status") && flags.NArg() == 1:
+	case sub == "down" && flags.NArg() == 1:
+		arg = 1
+	case (sub == "down" || sub == "to") && flags.NArg() == 2:
+		var err error
+		if arg, err = strconv.ParseUint(flags.Arg(1), 10, 32); err != nil || (sub == "down" && arg == 0) {
+			fmt.Fprintf(flags.Output(), "bikerental migrate %s: invalid number %q\n", sub, flags.Arg(1))
+			return errUsage
+		}
+	default:
+		flags.Usage()
+		return errUsage
+	}
+
+	db, err := database.InitDB(&config.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db, *migrationsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	switch flags.Arg(0) {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down(int(arg))
+	case "to":
+		err = migrator.To(uint(arg))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate: %w", err)
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		return fmt.Errorf("failed to read migration status: %w", err)
+	}
+	if flags.Arg(0) != "status" {
+		log.Info().Uint("version", status.Version).Msg("Migrations applied successfully")
+		return nil
+	}
+
+	fmt.Printf("Version: %d of %d\n", status.Version, status.Latest)
+	fmt.Printf("Pending: %d\n", status.Pending)
+	if status.Dirty {
+		fmt.Printf("Dirty: the last migration failed halfway, fix the schema by hand before forcing version %d\n", status.Version)
+	}
+	return nil
+}
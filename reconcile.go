@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database"
+	"github.com/yourusername/bike-rental/src/reconcile"
+)
+
+// checkConsistency lists the bikes and assignments disagreeing, repairing
+// them with -repair, and returns errInconsistent when some are left
+func checkConsistency(config *Config, args []string) error {
+	flags := newFlagSet("reconcile", "reconcile [-repair]")
+	repair := flags.Bool("repair", false, "repair the inconsistencies, recording them in the audit log, instead of only listing them")
+	settle := flags.Duration("settle", config.Reconcile.Settle, "delay before checking again, leaving out the requests in flight")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	db, err := database.InitDB(&config.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	found, err := reconcile.Run(context.Background(), db, clock.Real{}, &reconcile.Config{Repair: *repair, Settle: *settle})
+	if err != nil {
+		return fmt.Errorf("failed to reconcile: %w", err)
+	}
+
+	if len(found) == 0 {
+		fmt.Println("No inconsistencies found")
+		return nil
+	}
+
+	left := 0
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Kind\tRepaired\tDetail")
+	for _, inconsistency := range found {
+		fmt.Fprintf(tw, "%s\t%t\t%s\n", inconsistency.Kind, inconsistency.Repaired, inconsistency)
+		if !inconsistency.Repaired {
+			left++
+		}
+	}
+	tw.Flush()
+
+	if left > 0 {
+		return errInconsistent
+	}
+	return nil
+}
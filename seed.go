@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/yourusername/bike-rental/src/database"
+)
+
+// seed inserts the records of a fixtures file missing from the database
+func seed(config *Config, args []string) error {
+	flags := newFlagSet("seed", "seed -file FILE")
+	path := flags.String("file", "", "fixtures file, e.g. src/database/fixtures/dev.json")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *path == "" {
+		flags.Usage()
+		return errUsage
+	}
+
+	fixtures, err := database.LoadFixtures(*path)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.InitDB(&config.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return database.Seed(db, fixtures)
+}
+
+// clean deletes the users, the fleet and the rides, once confirmed
+func clean(config *Config, args []string) error {
+	flags := newFlagSet("clean", "clean -confirm")
+	confirm := flags.Bool("confirm", false, "confirm the records of the database of the configuration are to be deleted")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	if !*confirm {
+		return errors.New("clean deletes every user, bike and ride, run it with -confirm")
+	}
+
+	db, err := database.InitDB(&config.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return database.CleanDatabase(db)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/yourusername/bike-rental/src/api"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/tracing"
+)
+
+// serve serves the API and runs the cron jobs. The schema must have been
+// migrated, the database is neither migrated nor seeded.
+func serve(config *Config, args []string) error {
+	if err := parse(newFlagSet("serve", "serve"), args, 0); err != nil {
+		return err
+	}
+
+	// Export traces and metrics, the default providers being no-ops
+	shutdownTracing, err := tracing.Init(context.Background(), &config.Tracing)
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(context.Background())
+
+	log.Info().Msg("Initializing db connection...")
+
+	// Initialize the database connection
+	db, err := database.InitDB(&config.Database)
+	if err != nil {
+		return err
+	}
+
+	// The handlers and cron jobs read the time from the wall clock
+	clk := clock.Real{}
+
+	// The API counts with the global meter provider, installed with the
+	// tracer provider
+	metrics, err := api.NewMetrics(tracing.Meter("api"))
+	if err != nil {
+		return err
+	}
+
+	// Initialize the HTTP server and routes...
+	apiLogger := func() *zerolog.Logger { return logger.For("api") }
+	server, err := api.NewServer(db, clk, &config.API, apiLogger, metrics)
+	if err != nil {
+		return err
+	}
+
+	// Set up the cron jobs
+	log.Info().Msg("Setting up cronjobs...")
+	c := cron.New()
+	for _, job := range jobs {
+		job := job
+		c.AddFunc(job.schedule, func() { job.run(db, clk, config) })
+	}
+	c.Start()
+	defer c.Stop()
+
+	log.Info().Msg("Starting server...")
+	return http.ListenAndServe(":8080", server.Routes())
+}
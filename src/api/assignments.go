@@ -0,0 +1,475 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/handover"
+	"github.com/yourusername/bike-rental/src/invoices"
+	"github.com/yourusername/bike-rental/src/pricing"
+	"github.com/yourusername/bike-rental/src/subscriptions"
+	"github.com/yourusername/bike-rental/src/wallet"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// BikeHeader tells the user which bike they were assigned, to return it
+const BikeHeader = "X-Bike-UUID"
+
+// Assume models package is properly defined
+type AssignBikeRequest struct {
+	UserUUID  string `json:"user_uuid"`
+	StationID string `json:"station_id,omitempty"` // restricts the bikes to the ones parked at this station
+}
+
+func (req *AssignBikeRequest) Validate() []FieldError {
+	errs := checkUUID(nil, "user_uuid", req.UserUUID)
+	errs = checkOptionalUUID(errs, "station_id", req.StationID)
+	return errs
+}
+
+// checkRenter fetches a user and checks they can rent a bike: they exist,
+// are not an Admin and ride fewer bikes than their plan allows, a single
+// one without subscription. It writes the error response otherwise.
+func checkRenter(w http.ResponseWriter, r *http.Request, db *sql.DB, userID string, now time.Time) (models.User, bool) {
+	// Fetch the user based on UUID
+	var user models.User
+	query := "SELECT id, role FROM users WHERE id = $1"
+	if err := db.QueryRowContext(r.Context(), query, userID).Scan(&user.ID, &user.Role); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		}
+		return user, false
+	}
+
+	// Check if the user is an Admin
+	if user.Role == "Admin" {
+		http.Error(w, "Admins cannot be assigned bikes", http.StatusBadRequest)
+		return user, false
+	}
+
+	// Check if the user already has an active bike assignment
+	var active int
+	query = "SELECT COUNT(*) FROM assignments WHERE user_id = $1 AND unassigned_at IS NULL"
+	if err := db.QueryRowContext(r.Context(), query, user.ID).Scan(&active); err != nil {
+		http.Error(w, "Failed to check user assignments", http.StatusInternalServerError)
+		return user, false
+	}
+	if active == 0 {
+		return user, true
+	}
+
+	// Some plans allow riding several bikes at once
+	plan, err := subscriptions.ActivePlan(r.Context(), db, user.ID, now)
+	if err != nil {
+		http.Error(w, "Failed to fetch user subscription", http.StatusInternalServerError)
+		return user, false
+	}
+	if maxRentals := subscriptions.MaxRentals(plan); active >= maxRentals {
+		if maxRentals == 1 {
+			http.Error(w, "User already has an active bike assignment", http.StatusBadRequest)
+		} else {
+			http.Error(w, fmt.Sprintf("User already rides the %d bikes of their plan", maxRentals), http.StatusBadRequest)
+		}
+		return user, false
+	}
+
+	return user, true
+}
+
+// assignBike hands a bike over to a user whose wallet holds the minimum
+// balance of the wallet configuration
+func (s *Server) assignBike(w http.ResponseWriter, r *http.Request) {
+	// Parse and validate the JSON request body
+	var req AssignBikeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// Fetch the user and check they can rent a bike
+	now := s.Clock.Now()
+	user, ok := checkRenter(w, r, s.DB, req.UserUUID, now)
+	if !ok {
+		return
+	}
+
+	// Rides are paid from the wallet
+	if !checkBalance(w, r, s.DB, user.ID, &s.Config.Wallet) {
+		return
+	}
+
+	// A bike reserved by the user is handed over, otherwise the least used
+	// available one
+	var bike models.Bike
+	var reservationID uint
+	query := "SELECT id, bike_id FROM reservations WHERE user_id = $1 AND status = 'active' AND expires_at > $2"
+	err := s.DB.QueryRowContext(r.Context(), query, user.ID, now).Scan(&reservationID, &bike.ID)
+	switch {
+	case err == nil:
+		query = "SELECT id, is_assigned, usage_count, last_unassigned, station_id FROM bikes WHERE id = $1"
+		if err := s.DB.QueryRowContext(r.Context(), query, bike.ID).Scan(&bike.ID, &bike.IsAssigned, &bike.UsageCount, &bike.LastUnassigned, &bike.StationID); err != nil {
+			http.Error(w, "Failed to fetch reserved bike", http.StatusInternalServerError)
+			return
+		}
+		if bike.IsAssigned {
+			http.Error(w, "Reserved bike is already assigned", http.StatusConflict)
+			return
+		}
+	case err == sql.ErrNoRows:
+		// Fetch the least used bike that is not assigned and done cooling down
+		if bike, err = leastUsedAvailableBike(r.Context(), s.DB, now, req.StationID); err != nil {
+			if err == sql.ErrNoRows {
+				http.Error(w, "No available bikes", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to fetch bike", http.StatusInternalServerError)
+			}
+			return
+		}
+	default:
+		http.Error(w, "Failed to check user reservations", http.StatusInternalServerError)
+		return
+	}
+
+	before := map[string]interface{}{"is_assigned": bike.IsAssigned, "usage_count": bike.UsageCount}
+
+	// Hand the bike over in a single transaction
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Failed to assign bike", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Update bike status and usage count, unless a concurrent request
+	// assigned it or someone else reserved it meanwhile
+	query = `UPDATE bikes SET is_assigned = true, usage_count = usage_count + 1
+	         WHERE id = $1 AND is_assigned = false AND status = 'in_service'
+	           AND NOT EXISTS (SELECT 1 FROM reservations WHERE reservations.bike_id = bikes.id AND reservations.status = 'active' AND reservations.user_id <> $2)
+	         RETURNING usage_count`
+	if err := tx.QueryRowContext(r.Context(), query, bike.ID, user.ID).Scan(&bike.UsageCount); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Bike is no longer available", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to assign bike", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Unlock the bike from its dock slot
+	slot, err := releaseDock(r.Context(), tx, bike.ID, now)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Failed to release dock", http.StatusInternalServerError)
+		return
+	}
+
+	// Create a new assignment record, from the station the bike is parked at
+	query = `INSERT INTO assignments (user_id, bike_id, assigned_at, start_station_id)
+	         VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(r.Context(), query, user.ID, bike.ID, now, bike.StationID); err != nil {
+		http.Error(w, "Failed to create assignment", http.StatusInternalServerError)
+		return
+	}
+
+	// The reservation has been honoured
+	if reservationID != 0 {
+		query = "UPDATE reservations SET status = 'converted', updated_at = $1 WHERE id = $2"
+		if _, err := tx.ExecContext(r.Context(), query, now, reservationID); err != nil {
+			http.Error(w, "Failed to convert reservation", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// The user no longer waits for a bike: the one held for them was
+	// claimed, or they got another one
+	query = `UPDATE waitlist_entries
+	         SET status = CASE WHEN status = 'offered' AND reservation_id = $2 THEN 'claimed' ELSE 'left' END, updated_at = $3
+	         WHERE user_id = $1 AND status IN ('waiting', 'offered')`
+	if _, err := tx.ExecContext(r.Context(), query, user.ID, reservationID, now); err != nil {
+		http.Error(w, "Failed to update waitlist entries", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to assign bike", http.StatusInternalServerError)
+		return
+	}
+
+	// Record the state changes, the assignment being done already
+	s.recordAudit(r, audit.Event{
+		Actor:      audit.ActorFromRequest(r, user.ID),
+		Action:     audit.ActionBikeAssigned,
+		EntityType: audit.EntityBike,
+		EntityID:   bike.ID,
+		Before:     before,
+		After:      map[string]interface{}{"is_assigned": true, "usage_count": bike.UsageCount, "user_id": user.ID, "dock_slot": slot},
+	})
+	if reservationID != 0 {
+		s.recordAudit(r, audit.Event{
+			Actor:      audit.ActorFromRequest(r, user.ID),
+			Action:     audit.ActionReservationConverted,
+			EntityType: audit.EntityReservation,
+			EntityID:   fmt.Sprint(reservationID),
+			Before:     map[string]interface{}{"status": models.ReservationActive},
+			After:      map[string]interface{}{"status": models.ReservationConverted},
+		})
+	}
+	s.Metrics.RidesStarted.Add(r.Context(), 1)
+
+	// Respond with success
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set(BikeHeader, bike.ID)
+	if slot != 0 {
+		w.Header().Set(DockSlotHeader, strconv.Itoa(slot))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Bike assigned successfully"))
+}
+
+type UnassignBikeRequest struct {
+	BikeUUID  string `json:"bike_uuid"`
+	UserUUID  string `json:"user_uuid"`
+	StationID string `json:"station_id,omitempty"` // the station the bike is returned to, the one it was taken from by default
+	DockSlot  int    `json:"dock_slot,omitempty"`  // the slot the bike is locked in, the first free one by default
+	Reason    string `json:"reason,omitempty"`
+}
+
+// maxReasonLength caps the free text reasons stored in the audit log
+const maxReasonLength = 500
+
+func (req *UnassignBikeRequest) Validate() []FieldError {
+	errs := checkUUID(nil, "bike_uuid", req.BikeUUID)
+	errs = checkUUID(errs, "user_uuid", req.UserUUID)
+	errs = checkOptionalUUID(errs, "station_id", req.StationID)
+	if req.DockSlot < 0 {
+		errs = append(errs, FieldError{Field: "dock_slot", Message: "must be positive"})
+	}
+	if len(req.Reason) > maxReasonLength {
+		errs = append(errs, FieldError{Field: "reason", Message: fmt.Sprintf("must not exceed %d characters", maxReasonLength)})
+	}
+	return errs
+}
+
+// unassignBike ends the ride of a user, charged according to the pricing
+// configuration. The open assignment is locked first, the bike being docked
+// and the ride closed and charged in the same transaction.
+func (s *Server) unassignBike(w http.ResponseWriter, r *http.Request) {
+	// Parse and validate the JSON request body
+	var req UnassignBikeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Failed to update assignment record", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Fetch the bike and its assignment based on UUID and user ID
+	var bikeID string
+	var assignmentID uint
+	var startStation sql.NullString
+	var assignedAt, overdueAt sql.NullTime
+	query := `
+		SELECT b.id, a.id, a.start_station_id, a.assigned_at, a.overdue_at
+		FROM bikes b
+		INNER JOIN assignments a ON b.id = a.bike_id
+		WHERE b.id = $1 AND a.user_id = $2 AND a.unassigned_at IS NULL
+		FOR UPDATE OF a
+	`
+	if err := tx.QueryRowContext(r.Context(), query, req.BikeUUID, req.UserUUID).Scan(&bikeID, &assignmentID, &startStation, &assignedAt, &overdueAt); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Bike not found or not assigned to the user", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch bike assignment", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Rides are priced with the entitlements of the plan held when they
+	// started, an assignment without start time being charged the unlock fee only
+	now := s.Clock.Now()
+	if !assignedAt.Valid {
+		assignedAt.Time = now
+	}
+	plan, err := subscriptions.ActivePlan(r.Context(), tx, req.UserUUID, assignedAt.Time)
+	if err != nil {
+		http.Error(w, "Failed to fetch user subscription", http.StatusInternalServerError)
+		return
+	}
+
+	// The bike is returned to the given station, or the one it was taken from
+	station := startStation
+	if req.StationID != "" {
+		station = sql.NullString{String: req.StationID, Valid: true}
+	}
+	var slot int
+	if station.Valid {
+		var exists bool
+		query = "SELECT EXISTS(SELECT 1 FROM stations WHERE id = $1)"
+		if err := tx.QueryRowContext(r.Context(), query, station.String).Scan(&exists); err != nil {
+			http.Error(w, "Failed to fetch station", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.Error(w, "Station not found", http.StatusNotFound)
+			return
+		}
+
+		// Lock the bike in the reported dock slot, or the first free one
+		var err error
+		if slot, err = occupyDock(r.Context(), tx, station.String, req.DockSlot, bikeID, now); err != nil {
+			if err != sql.ErrNoRows {
+				http.Error(w, "Failed to occupy dock", http.StatusInternalServerError)
+			} else if req.DockSlot != 0 {
+				http.Error(w, "Dock slot is not free", http.StatusConflict)
+			} else {
+				http.Error(w, "Station has no free dock slots", http.StatusConflict)
+			}
+			return
+		}
+	}
+
+	// Update bike to be unassigned, parked at the station, and available once cooled down.
+	// A bike suspected lost is back in service once docked.
+	query = "UPDATE bikes SET is_assigned = false, status = 'in_service', last_unassigned = $1, available_from = $4, station_id = COALESCE($3, station_id) WHERE id = $2"
+	if _, err := tx.ExecContext(r.Context(), query, now, bikeID, station, now.Add(handover.Cooldown)); err != nil {
+		http.Error(w, "Failed to unassign bike", http.StatusInternalServerError)
+		return
+	}
+
+	// Close the ride with its fare
+	assignment := models.Assignment{ID: assignmentID, UserID: req.UserUUID, BikeID: bikeID, AssignedAt: assignedAt, StartStation: startStation, EndStation: station}
+	fare, err := endRide(r.Context(), tx, assignment, plan, overdueAt.Valid, &s.Config.Pricing, now, audit.ActorFromRequest(r, req.UserUUID).ID)
+	if err == errRideEnded {
+		http.Error(w, "Ride already ended", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update assignment record", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to update assignment record", http.StatusInternalServerError)
+		return
+	}
+
+	s.recordAudit(r, audit.Event{
+		Actor:      audit.ActorFromRequest(r, req.UserUUID),
+		Action:     audit.ActionBikeUnassigned,
+		EntityType: audit.EntityBike,
+		EntityID:   bikeID,
+		Before:     map[string]interface{}{"is_assigned": true, "user_id": req.UserUUID},
+		After:      map[string]interface{}{"is_assigned": false, "last_unassigned": now, "available_from": now.Add(handover.Cooldown), "station_id": station.String, "dock_slot": slot, "fare": fare},
+		Reason:     req.Reason,
+	})
+	s.Metrics.RidesEnded.Add(r.Context(), 1)
+	s.Metrics.FareCents.Add(r.Context(), fare.Total, metric.WithAttributes(attribute.String("currency", fare.Currency)))
+
+	// Respond with success
+	if slot != 0 {
+		w.Header().Set(DockSlotHeader, strconv.Itoa(slot))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Bike unassigned successfully"))
+}
+
+// errRideEnded is returned by endRide when the assignment was closed
+// meanwhile
+var errRideEnded = errors.New("ride already ended")
+
+// endRide closes the open assignment of a bike at endedAt, at its end
+// station if any, within tx. The ride is priced with the plan of the user,
+// charged to their wallet and receipted; the tasks opened for an overdue ride
+// are closed by closedBy.
+func endRide(ctx context.Context, tx *sql.Tx, assignment models.Assignment, plan *models.Plan, overdue bool, tariff *pricing.Tariff, endedAt time.Time, closedBy string) (pricing.Fare, error) {
+	fare := subscriptions.Tariff(tariff, plan).Calculate(assignment.AssignedAt.Time, endedAt, overdue)
+
+	// Update the corresponding assignment record to set the unassigned_at timestamp, the end station and the fare
+	query := "UPDATE assignments SET unassigned_at = $1, end_station_id = $3, fare_cents = $4, fare_currency = $5 WHERE id = $2 AND unassigned_at IS NULL"
+	result, err := tx.ExecContext(ctx, query, endedAt, assignment.ID, assignment.EndStation, fare.Total, fare.Currency)
+	if err != nil {
+		return fare, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return fare, err
+	} else if affected == 0 {
+		return fare, errRideEnded
+	}
+
+	// Debit the fare, the ride being over already, but the overdue penalty
+	// collected by the escalation job
+	if err := wallet.ChargeRide(ctx, tx, assignment.UserID, assignment.ID, fare.Total-fare.Penalty, endedAt); err != nil {
+		return fare, err
+	}
+
+	// The bike is recovered, closing the task of the supervisors
+	if overdue {
+		query = "UPDATE supervisor_tasks SET status = 'done', closed_at = $1, closed_by = $2 WHERE assignment_id = $3 AND status = 'open'"
+		if _, err := tx.ExecContext(ctx, query, endedAt, closedBy, assignment.ID); err != nil {
+			return fare, err
+		}
+	}
+
+	receipt := invoices.NewReceipt(assignment, endedAt, fare, plan)
+	return fare, invoices.RecordReceipt(ctx, tx, &receipt)
+}
+
+// recordAudit appends an event to the audit log, at the time of the server
+// clock. A failure is only logged, the state change it describes having
+// already been made.
+func (s *Server) recordAudit(r *http.Request, event audit.Event) {
+	event.OccurredAt = s.Clock.Now()
+	if err := audit.Record(r.Context(), s.DB, event); err != nil {
+		s.Logger().Err(err).Ctx(r.Context()).Str("action", event.Action).Msg("Failed to record audit event")
+	}
+}
+
+// getAllAssignments retrieves all assignments from the database using database/sql
+func (s *Server) getAllAssignments(w http.ResponseWriter, r *http.Request) {
+	// Prepare the query
+	query := "SELECT id, user_id, bike_id, assigned_at, unassigned_at, start_station_id, end_station_id, fare_cents, fare_currency FROM assignments"
+
+	// Execute the query
+	rows, err := s.DB.QueryContext(r.Context(), query)
+	if err != nil {
+		http.Error(w, "Failed to retrieve assignments", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	// Iterate over the rows and build the assignments slice
+	var assignments []models.Assignment
+	for rows.Next() {
+		var assignment models.Assignment
+		if err := rows.Scan(&assignment.ID, &assignment.UserID, &assignment.BikeID, &assignment.AssignedAt, &assignment.UnassignedAt, &assignment.StartStation, &assignment.EndStation, &assignment.FareCents, &assignment.FareCurrency); err != nil {
+			http.Error(w, "Failed to scan assignment", http.StatusInternalServerError)
+			return
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the list of assignments in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(assignments); err != nil {
+		http.Error(w, "Failed to encode assignments to JSON", http.StatusInternalServerError)
+		return
+	}
+}
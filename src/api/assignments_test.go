@@ -0,0 +1,595 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/pricing"
+)
+
+func TestAssignBike_Success(t *testing.T) {
+	// Create a new mock database
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	// Create a mock user and bike
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	// Prepare mock expectations
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+
+	mock.ExpectQuery("SELECT id, bike_id FROM reservations WHERE user_id = \\$1 AND status = 'active' AND expires_at > \\$2").
+		WithArgs(userUUID, fixedTime).
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id FROM bikes WHERE is_assigned = false AND status = 'in_service' AND available_from <= \\$1 (.+) ORDER BY usage_count, id LIMIT 1").
+		WithArgs(fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned", "available_from", "station_id"}).
+			AddRow(bikeID, false, 0, fixedTime.Add(-10*time.Minute), fixedTime.Add(-5*time.Minute), stationID))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE bikes SET is_assigned = true, usage_count = usage_count \\+ 1 WHERE id = \\$1 AND is_assigned = false AND status = 'in_service' AND NOT EXISTS (.+) RETURNING usage_count").
+		WithArgs(bikeID, userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"usage_count"}).AddRow(1))
+
+	mock.ExpectQuery("UPDATE docks SET state = 'free', bike_id = NULL, updated_at = \\$2 WHERE bike_id = \\$1 RETURNING slot").
+		WithArgs(bikeID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(3))
+
+	mock.ExpectExec("INSERT INTO assignments \\(user_id, bike_id, assigned_at, start_station_id\\) VALUES \\(\\$1, \\$2, \\$3, \\$4\\)").
+		WithArgs(userUUID, bikeID, fixedTime, stationID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec("UPDATE waitlist_entries SET status = CASE (.+) WHERE user_id = \\$1 AND status IN \\('waiting', 'offered'\\)").
+		WithArgs(userUUID, 0, fixedTime).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs("user", userUUID, "bike.assigned", "bike", bikeID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Create a new HTTP request
+	reqBody := `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96"}`
+	req, err := http.NewRequest(http.MethodPost, "/assign-bike", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Create a ResponseRecorder to record the response
+	rr := httptest.NewRecorder()
+
+	// Call the function to test
+	testServer(db, clk, &Config{Wallet: WalletConfig{MinimumBalance: 500}}).assignBike(rr, req)
+
+	// Check the status code
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+
+	// Check the response body
+	assert.Equal(t, "Bike assigned successfully", rr.Body.String())
+	assert.Equal(t, bikeID, rr.Header().Get(BikeHeader))
+	assert.Equal(t, "3", rr.Header().Get(DockSlotHeader))
+
+	// Assert that all expectations were met
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestAssignBike_UserNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC))
+
+	// Prepare mock expectations for user not found
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnError(sql.ErrNoRows)
+
+	// Create a new HTTP request
+	reqBody := `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96"}`
+	req, err := http.NewRequest(http.MethodPost, "/assign-bike", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Create a ResponseRecorder to record the response
+	rr := httptest.NewRecorder()
+
+	// Call the function to test
+	testServer(db, clk, &Config{Wallet: WalletConfig{MinimumBalance: 500}}).assignBike(rr, req)
+
+	// Check the status code
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status Not Found but got %v", rr.Code)
+
+	// Check the response body
+	assert.Equal(t, "User not found\n", rr.Body.String())
+
+	// Assert that all expectations were met
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestAssignBike_ActiveAssignmentExists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	// Prepare mock expectations for active assignment
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	// Without subscription, a single bike at a time
+	mock.ExpectQuery("SELECT p.id, p.name, p.period, p.price_cents, p.included_minutes, p.max_rentals FROM subscriptions s").
+		WithArgs(userUUID, fixedTime).
+		WillReturnError(sql.ErrNoRows)
+
+	// Create a new HTTP request
+	reqBody := `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96"}`
+	req, err := http.NewRequest(http.MethodPost, "/assign-bike", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Create a ResponseRecorder to record the response
+	rr := httptest.NewRecorder()
+
+	// Call the function to test
+	testServer(db, clk, &Config{Wallet: WalletConfig{MinimumBalance: 500}}).assignBike(rr, req)
+
+	// Check the status code
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status Bad Request but got %v", rr.Code)
+
+	// Check the response body
+	assert.Equal(t, "User already has an active bike assignment\n", rr.Body.String())
+
+	// Assert that all expectations were met
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestAssignBike_InsufficientBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC))
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	// Below the minimum balance, no bike is looked for
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(200))
+
+	req := httptest.NewRequest(http.MethodPost, "/bikes/assign", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Wallet: WalletConfig{MinimumBalance: 500}}).assignBike(rr, req)
+
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code, "Expected status Payment Required but got %v", rr.Code)
+	assert.Equal(t, "Insufficient wallet balance\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssignBike_NoAvailableBikes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	// Prepare mock expectations for no available bikes
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+
+	mock.ExpectQuery("SELECT id, bike_id FROM reservations WHERE user_id = \\$1 AND status = 'active' AND expires_at > \\$2").
+		WithArgs(userUUID, fixedTime).
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id FROM bikes WHERE is_assigned = false AND status = 'in_service' AND available_from <= \\$1 (.+) ORDER BY usage_count, id LIMIT 1").
+		WithArgs(fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned", "available_from", "station_id"}))
+
+	// Create a new HTTP request
+	reqBody := `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96"}`
+	req, err := http.NewRequest(http.MethodPost, "/assign-bike", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Create a ResponseRecorder to record the response
+	rr := httptest.NewRecorder()
+
+	// Call the function to test
+	testServer(db, clk, &Config{Wallet: WalletConfig{MinimumBalance: 500}}).assignBike(rr, req)
+
+	// Check the status code
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status Not Found but got %v", rr.Code)
+
+	// Check the response body
+	assert.Equal(t, "No available bikes\n", rr.Body.String())
+
+	// Assert that all expectations were met
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestAssignBike_AssignedMeanwhile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+
+	mock.ExpectQuery("SELECT id, bike_id FROM reservations WHERE user_id = \\$1 AND status = 'active' AND expires_at > \\$2").
+		WithArgs(userUUID, fixedTime).
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id FROM bikes WHERE is_assigned = false AND status = 'in_service' AND available_from <= \\$1 (.+) ORDER BY usage_count, id LIMIT 1").
+		WithArgs(fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned", "available_from", "station_id"}).
+			AddRow(bikeID, false, 0, fixedTime.Add(-10*time.Minute), fixedTime.Add(-5*time.Minute), stationID))
+
+	// A concurrent request took the bike since it was selected
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE bikes SET is_assigned = true, usage_count = usage_count \\+ 1 WHERE id = \\$1 (.+) RETURNING usage_count").
+		WithArgs(bikeID, userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"usage_count"}))
+	mock.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/bikes/assign", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Wallet: WalletConfig{MinimumBalance: 500}}).assignBike(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status Conflict but got %v", rr.Code)
+	assert.Equal(t, "Bike is no longer available\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnassignBike_OtherStation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+	otherStationID := "5b2e8f1c-9a3d-4c7e-8f6a-1d2c3b4a5e6f"
+
+	// The bike was taken from the seeded station, its ride is locked until
+	// it is closed, charged and receipted
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT b.id, a.id, a.start_station_id, a.assigned_at, a.overdue_at FROM bikes b INNER JOIN assignments a ON b.id = a.bike_id").
+		WithArgs(bikeID, userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "assignment_id", "start_station_id", "assigned_at", "overdue_at"}).AddRow(bikeID, 42, stationID, fixedTime.Add(-29*time.Minute-30*time.Second), nil))
+
+	mock.ExpectQuery("SELECT p.id, p.name, p.period, p.price_cents, p.included_minutes, p.max_rentals FROM subscriptions s").
+		WithArgs(userUUID, fixedTime.Add(-29*time.Minute-30*time.Second)).
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+		WithArgs(otherStationID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	// The docking station reported the slot the bike was locked in
+	mock.ExpectQuery("UPDATE docks SET state = 'occupied', bike_id = \\$2, updated_at = \\$3 WHERE id = \\(SELECT id FROM docks WHERE station_id = \\$1 AND state = 'free' AND slot = \\$4 ORDER BY slot LIMIT 1 FOR UPDATE SKIP LOCKED\\) RETURNING slot").
+		WithArgs(otherStationID, bikeID, fixedTime, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(7))
+
+	mock.ExpectExec("UPDATE bikes SET is_assigned = false, status = 'in_service', last_unassigned = \\$1, available_from = \\$4, station_id = COALESCE\\(\\$3, station_id\\) WHERE id = \\$2").
+		WithArgs(fixedTime, bikeID, otherStationID, fixedTime.Add(5*time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// 30 started minutes at 20 cents, plus the unlock fee
+	mock.ExpectExec("UPDATE assignments SET unassigned_at = \\$1, end_station_id = \\$3, fare_cents = \\$4, fare_currency = \\$5 WHERE id = \\$2 AND unassigned_at IS NULL").
+		WithArgs(fixedTime, uint(42), otherStationID, int64(700), "EUR").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// The fare is debited from the wallet of the user
+	mock.ExpectQuery("INSERT INTO wallet_accounts \\(user_id\\)").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("SELECT id FROM wallet_accounts WHERE name = \\$1").
+		WithArgs("revenue").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery("INSERT INTO wallet_transactions").
+		WithArgs("ride_charge", int64(42), nil, nil, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO wallet_entries").
+		WithArgs(int64(9), int64(3), int64(-700), int64(2), int64(700)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	mock.ExpectQuery("INSERT INTO receipts").
+		WithArgs(uint(42), userUUID, bikeID, sql.NullString{String: stationID, Valid: true}, sql.NullString{String: otherStationID, Valid: true}, fixedTime.Add(-29*time.Minute-30*time.Second), fixedTime, sql.NullString{},
+			int64(30), int64(100), int64(600), int64(0), int64(700), "EUR").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs("user", userUUID, "bike.unassigned", "bike", bikeID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	reqBody := `{"bike_uuid":"` + bikeID + `","user_uuid":"` + userUUID + `","station_id":"` + otherStationID + `","dock_slot":7}`
+	req := httptest.NewRequest(http.MethodPost, "/bikes/unassign", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Pricing: pricing.Tariff{UnlockFee: 100, PerMinute: 20}}).unassignBike(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+	assert.Equal(t, "Bike unassigned successfully", rr.Body.String())
+	assert.Equal(t, "7", rr.Header().Get(DockSlotHeader))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnassignBike_Overdue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	// The escalation job flagged the ride an hour ago
+	assignedAt := fixedTime.Add(-25*time.Hour + 30*time.Second)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT b.id, a.id, a.start_station_id, a.assigned_at, a.overdue_at FROM bikes b INNER JOIN assignments a ON b.id = a.bike_id").
+		WithArgs(bikeID, userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "assignment_id", "start_station_id", "assigned_at", "overdue_at"}).AddRow(bikeID, 42, stationID, assignedAt, fixedTime.Add(-time.Hour)))
+
+	mock.ExpectQuery("SELECT p.id, p.name, p.period, p.price_cents, p.included_minutes, p.max_rentals FROM subscriptions s").
+		WithArgs(userUUID, assignedAt).
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+		WithArgs(stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("UPDATE docks SET state = 'occupied', (.+) RETURNING slot").
+		WithArgs(stationID, bikeID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(2))
+
+	// Docked, the bike is back in service
+	mock.ExpectExec("UPDATE bikes SET is_assigned = false, status = 'in_service', (.+) WHERE id = \\$2").
+		WithArgs(fixedTime, bikeID, stationID, fixedTime.Add(5*time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// 1500 started minutes capped at 1000 cents a day, the unlock fee and the penalty
+	mock.ExpectExec("UPDATE assignments SET unassigned_at = (.+)").
+		WithArgs(fixedTime, uint(42), stationID, int64(7100), "EUR").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// The penalty was collected already
+	mock.ExpectQuery("INSERT INTO wallet_accounts \\(user_id\\)").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("SELECT id FROM wallet_accounts WHERE name = \\$1").
+		WithArgs("revenue").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery("INSERT INTO wallet_transactions").
+		WithArgs("ride_charge", int64(42), nil, nil, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO wallet_entries").
+		WithArgs(int64(9), int64(3), int64(-2100), int64(2), int64(2100)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	mock.ExpectExec("UPDATE supervisor_tasks SET status = 'done', closed_at = \\$1, closed_by = \\$2 WHERE assignment_id = \\$3 AND status = 'open'").
+		WithArgs(fixedTime, userUUID, uint(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery("INSERT INTO receipts").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO audit_events").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	reqBody := `{"bike_uuid":"` + bikeID + `","user_uuid":"` + userUUID + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/bikes/unassign", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Pricing: pricing.Tariff{UnlockFee: 100, PerMinute: 20, DailyCap: 1000, OverduePenalty: 5000}}).unassignBike(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnassignBike_StationFull(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	// No station given, the bike goes back where it was taken from
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT b.id, a.id, a.start_station_id, a.assigned_at, a.overdue_at FROM bikes b INNER JOIN assignments a ON b.id = a.bike_id").
+		WithArgs(bikeID, userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "assignment_id", "start_station_id", "assigned_at", "overdue_at"}).AddRow(bikeID, 42, stationID, fixedTime.Add(-30*time.Minute), nil))
+
+	mock.ExpectQuery("SELECT p.id, p.name, p.period, p.price_cents, p.included_minutes, p.max_rentals FROM subscriptions s").
+		WithArgs(userUUID, fixedTime.Add(-30*time.Minute)).
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+		WithArgs(stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	mock.ExpectQuery("UPDATE docks SET state = 'occupied', (.+) RETURNING slot").
+		WithArgs(stationID, bikeID, fixedTime).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	reqBody := `{"bike_uuid":"` + bikeID + `","user_uuid":"` + userUUID + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/bikes/unassign", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Pricing: pricing.Tariff{UnlockFee: 100, PerMinute: 20}}).unassignBike(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status Conflict but got %v", rr.Code)
+	assert.Equal(t, "Station has no free dock slots\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnassignBike_EndedMeanwhile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT b.id, a.id, a.start_station_id, a.assigned_at, a.overdue_at FROM bikes b INNER JOIN assignments a ON b.id = a.bike_id").
+		WithArgs(bikeID, userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "assignment_id", "start_station_id", "assigned_at", "overdue_at"}).AddRow(bikeID, 42, stationID, fixedTime.Add(-30*time.Minute), nil))
+	mock.ExpectQuery("SELECT p.id, p.name, p.period, p.price_cents, p.included_minutes, p.max_rentals FROM subscriptions s").
+		WithArgs(userUUID, fixedTime.Add(-30*time.Minute)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+		WithArgs(stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("UPDATE docks SET state = 'occupied', (.+) RETURNING slot").
+		WithArgs(stationID, bikeID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(2))
+	mock.ExpectExec("UPDATE bikes SET is_assigned = false, status = 'in_service', (.+) WHERE id = \\$2").
+		WithArgs(fixedTime, bikeID, stationID, fixedTime.Add(5*time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// The ride was closed by another request, nothing is charged nor docked
+	mock.ExpectExec("UPDATE assignments SET unassigned_at = (.+) WHERE id = \\$2 AND unassigned_at IS NULL").
+		WithArgs(fixedTime, uint(42), stationID, int64(700), "EUR").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	reqBody := `{"bike_uuid":"` + bikeID + `","user_uuid":"` + userUUID + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/bikes/unassign", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Pricing: pricing.Tariff{UnlockFee: 100, PerMinute: 20}}).unassignBike(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status Conflict but got %v", rr.Code)
+	assert.Equal(t, "Ride already ended\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
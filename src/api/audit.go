@@ -0,0 +1,167 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// authenticateOperator checks that the operator header of the requests
+// having one belongs to a Supervisor or an Admin, writing the error response
+// otherwise. The operator is then set in the context, for requireSupervisor
+// and the audit log.
+func (s *Server) authenticateOperator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operatorID := r.Header.Get(audit.OperatorHeader)
+		if operatorID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !isUUID(operatorID) {
+			writeValidationError(w, http.StatusBadRequest, "Invalid request", FieldError{Field: audit.OperatorHeader, Message: "must be a UUID"})
+			return
+		}
+
+		var operator models.User
+		query := "SELECT id, role FROM users WHERE id = $1"
+		if err := s.DB.QueryRowContext(r.Context(), query, operatorID).Scan(&operator.ID, &operator.Role); err != nil {
+			if err == sql.ErrNoRows {
+				http.Error(w, "Operator not found", http.StatusForbidden)
+			} else {
+				http.Error(w, "Failed to fetch operator", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if operator.Role != "Supervisor" && operator.Role != "Admin" {
+			http.Error(w, "Only supervisors can perform this operation", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(audit.WithOperator(r.Context(), operator.ID)))
+	})
+}
+
+// requireSupervisor returns the operator authenticateOperator verified for
+// the request. It writes the error response if there is none.
+func requireSupervisor(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	operatorID, ok := audit.OperatorFromContext(r.Context())
+	if !ok {
+		http.Error(w, audit.OperatorHeader+" header is required", http.StatusUnauthorized)
+		return models.User{}, false
+	}
+	return models.User{ID: operatorID}, true
+}
+
+// supervisorsOnly restricts the routes it wraps to the operators verified
+// by authenticateOperator
+func supervisorsOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireSupervisor(w, r); !ok {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// getAuditEvents lists audit events, most recent first. They can be
+// filtered by entity, actor, action and time range.
+func (s *Server) getAuditEvents(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireSupervisor(w, r); !ok {
+		return
+	}
+
+	params := r.URL.Query()
+
+	// Build the filters from the query string
+	var conditions []string
+	var args []interface{}
+	for _, column := range []string{"entity_type", "entity_id", "actor_type", "actor_id", "action"} {
+		if value := params.Get(column); value != "" {
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+	for _, bound := range []struct{ param, operator string }{{"since", ">="}, {"until", "<"}} {
+		param, operator := bound.param, bound.operator
+		value := params.Get(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, param+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		args = append(args, t)
+		conditions = append(conditions, fmt.Sprintf("occurred_at %s $%d", operator, len(args)))
+	}
+
+	limit := defaultAuditLimit
+	if value := params.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		if parsed < maxAuditLimit {
+			limit = parsed
+		} else {
+			limit = maxAuditLimit
+		}
+	}
+
+	query := `SELECT id, occurred_at, actor_type, actor_id, action, entity_type, entity_id, before, after, reason, request_id
+	          FROM audit_events`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY occurred_at DESC, id DESC LIMIT $%d", len(args))
+
+	// Execute the query
+	rows, err := s.DB.QueryContext(r.Context(), query, args...)
+	if err != nil {
+		http.Error(w, "Failed to retrieve audit events", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		var before, after []byte
+		if err := rows.Scan(&event.ID, &event.OccurredAt, &event.ActorType, &event.ActorID, &event.Action,
+			&event.EntityType, &event.EntityID, &before, &after, &event.Reason, &event.RequestID); err != nil {
+			http.Error(w, "Failed to scan audit event", http.StatusInternalServerError)
+			return
+		}
+		event.Before, event.After = before, after
+		events = append(events, event)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the list of events in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		http.Error(w, "Failed to encode audit events to JSON", http.StatusInternalServerError)
+		return
+	}
+}
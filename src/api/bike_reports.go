@@ -0,0 +1,355 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/handover"
+	"github.com/yourusername/bike-rental/src/pricing"
+	"github.com/yourusername/bike-rental/src/subscriptions"
+	"github.com/yourusername/bike-rental/src/wallet"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// maxReportLength caps the description of a bike declared lost or stolen
+const maxReportLength = 2000
+
+type DeclareBikeLostRequest struct {
+	Kind     string `json:"kind"`                // lost or stolen
+	Report   string `json:"report"`              // what happened, police reference
+	WaiveFee bool   `json:"waive_fee,omitempty"` // spares the replacement fee to the user riding the bike
+}
+
+func (req *DeclareBikeLostRequest) Validate() []FieldError {
+	var errs []FieldError
+	if req.Kind != models.BikeLost && req.Kind != models.BikeStolen {
+		errs = append(errs, FieldError{Field: "kind", Message: "must be lost or stolen"})
+	}
+	if req.Report == "" {
+		errs = append(errs, FieldError{Field: "report", Message: "is required"})
+	} else if len(req.Report) > maxReportLength {
+		errs = append(errs, FieldError{Field: "report", Message: fmt.Sprintf("must not exceed %d characters", maxReportLength)})
+	}
+	return errs
+}
+
+// bikeFromURL reads the bike of the URL and its status. It writes the error
+// response if it is malformed or unknown.
+func bikeFromURL(w http.ResponseWriter, r *http.Request, db *sql.DB) (string, string, bool) {
+	bikeID := chi.URLParam(r, "id")
+	if !isUUID(bikeID) {
+		writeValidationError(w, http.StatusBadRequest, "Invalid request", FieldError{Field: "id", Message: "must be a UUID"})
+		return "", "", false
+	}
+
+	var status string
+	query := "SELECT status FROM bikes WHERE id = $1"
+	if err := db.QueryRowContext(r.Context(), query, bikeID).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Bike not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch bike", http.StatusInternalServerError)
+		}
+		return "", "", false
+	}
+
+	return bikeID, status, true
+}
+
+// openAssignment locks the assignment of a bike not closed yet and returns
+// it with its overdue flag. It returns sql.ErrNoRows if there is none.
+func openAssignment(r *http.Request, db wallet.Queryer, bikeID string) (models.Assignment, bool, error) {
+	var assignment models.Assignment
+	var overdueAt sql.NullTime
+	query := `SELECT id, user_id, bike_id, assigned_at, start_station_id, overdue_at
+	          FROM assignments
+	          WHERE bike_id = $1 AND unassigned_at IS NULL
+	          ORDER BY assigned_at
+	          LIMIT 1
+	          FOR UPDATE`
+	err := db.QueryRowContext(r.Context(), query, bikeID).Scan(&assignment.ID, &assignment.UserID, &assignment.BikeID, &assignment.AssignedAt, &assignment.StartStation, &overdueAt)
+	return assignment, overdueAt.Valid, err
+}
+
+// closeAssignment ends an open assignment within tx, at endedAt and at the
+// end station of the assignment
+func closeAssignment(r *http.Request, tx *sql.Tx, assignment *models.Assignment, overdue bool, endStation sql.NullString, tariff *pricing.Tariff, endedAt time.Time, closedBy string) error {
+	// Rides are priced with the entitlements of the plan held when they started
+	if !assignment.AssignedAt.Valid {
+		assignment.AssignedAt.Time = endedAt
+	}
+	plan, err := subscriptions.ActivePlan(r.Context(), tx, assignment.UserID, assignment.AssignedAt.Time)
+	if err != nil {
+		return err
+	}
+
+	assignment.EndStation = endStation
+	_, err = endRide(r.Context(), tx, *assignment, plan, overdue, tariff, endedAt, closedBy)
+	return err
+}
+
+// closeOpenAssignment ends the ride going on with a bike, if any, within tx
+func closeOpenAssignment(r *http.Request, tx *sql.Tx, bikeID string, endStation sql.NullString, tariff *pricing.Tariff, endedAt time.Time, closedBy string) error {
+	assignment, overdue, err := openAssignment(r, tx, bikeID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return closeAssignment(r, tx, &assignment, overdue, endStation, tariff, endedAt, closedBy)
+}
+
+// declareBikeLost takes a bike lost or stolen out of service. The report is
+// recorded first, a bike being declared once only, then the ride going on
+// with it ends, its user being charged the replacement fee, and the bike
+// leaves its dock if it was parked, all in one transaction.
+func (s *Server) declareBikeLost(w http.ResponseWriter, r *http.Request) {
+	operator, ok := requireSupervisor(w, r)
+	if !ok {
+		return
+	}
+
+	bikeID, _, ok := bikeFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	// Parse and validate the JSON request body
+	var req DeclareBikeLostRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Failed to declare bike", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the bike while it is declared, the ride being left untouched if
+	// it was declared already
+	var status string
+	query := "SELECT status FROM bikes WHERE id = $1 FOR UPDATE"
+	if err := tx.QueryRowContext(r.Context(), query, bikeID).Scan(&status); err != nil {
+		http.Error(w, "Failed to fetch bike", http.StatusInternalServerError)
+		return
+	}
+	if status == models.BikeLost || status == models.BikeStolen {
+		http.Error(w, "Bike is already declared lost or stolen", http.StatusConflict)
+		return
+	}
+
+	var ride *models.Assignment
+	assignment, overdue, err := openAssignment(r, tx, bikeID)
+	switch err {
+	case nil:
+		ride = &assignment
+	case sql.ErrNoRows:
+	default:
+		http.Error(w, "Failed to fetch bike assignment", http.StatusInternalServerError)
+		return
+	}
+
+	now := s.Clock.Now()
+	report := models.BikeReport{
+		BikeID:     bikeID,
+		Kind:       req.Kind,
+		Report:     req.Report,
+		ReportedBy: operator.ID,
+		ReportedAt: now,
+	}
+	if ride != nil {
+		report.AssignmentID = sql.NullInt64{Int64: int64(ride.ID), Valid: true}
+		report.UserID = sql.NullString{String: ride.UserID, Valid: true}
+		if !req.WaiveFee {
+			report.FeeCents = s.Config.Pricing.ReplacementFee
+		}
+	}
+	query = `INSERT INTO bike_reports (bike_id, kind, report, assignment_id, user_id, fee_cents, reported_by, reported_at)
+	         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	         RETURNING id`
+	if err := tx.QueryRowContext(r.Context(), query, report.BikeID, report.Kind, report.Report, report.AssignmentID, report.UserID, report.FeeCents, report.ReportedBy, report.ReportedAt).Scan(&report.ID); err != nil {
+		if isUniqueViolation(err) {
+			http.Error(w, "Bike is already declared lost or stolen", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to record bike report", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if ride != nil {
+		if err := closeAssignment(r, tx, ride, overdue, sql.NullString{}, &s.Config.Pricing, now, operator.ID); err != nil {
+			http.Error(w, "Failed to close bike assignment", http.StatusInternalServerError)
+			return
+		}
+		if err := wallet.ChargeReplacement(r.Context(), tx, ride.UserID, ride.ID, report.FeeCents, now); err != nil {
+			http.Error(w, "Failed to charge replacement fee", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// A bike gone from its dock frees the slot, and the reservation holding it
+	if _, err := releaseDock(r.Context(), tx, bikeID, now); err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Failed to release dock", http.StatusInternalServerError)
+		return
+	}
+	query = "UPDATE reservations SET status = 'cancelled', updated_at = $1 WHERE bike_id = $2 AND status = 'active'"
+	if _, err := tx.ExecContext(r.Context(), query, now, bikeID); err != nil {
+		http.Error(w, "Failed to cancel bike reservation", http.StatusInternalServerError)
+		return
+	}
+
+	query = "UPDATE bikes SET status = $2, is_assigned = false, station_id = NULL WHERE id = $1"
+	if _, err := tx.ExecContext(r.Context(), query, bikeID, req.Kind); err != nil {
+		http.Error(w, "Failed to update bike", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to declare bike", http.StatusInternalServerError)
+		return
+	}
+	s.Metrics.BikesLost.Add(r.Context(), 1, metric.WithAttributes(attribute.String("kind", report.Kind)))
+
+	s.recordAudit(r, audit.Event{
+		Actor:      audit.ActorFromRequest(r, operator.ID),
+		Action:     audit.ActionBikeDeclaredLost,
+		EntityType: audit.EntityBike,
+		EntityID:   bikeID,
+		Before:     map[string]interface{}{"status": status},
+		After:      map[string]interface{}{"status": report.Kind, "report_id": report.ID, "user_id": report.UserID.String, "fee_cents": report.FeeCents},
+		Reason:     report.Report,
+	})
+
+	// Respond with the report in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Failed to encode bike report to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+type RecoverBikeRequest struct {
+	StationID string `json:"station_id"`
+	DockSlot  int    `json:"dock_slot,omitempty"` // the slot the bike was locked in, the first free one if not given
+}
+
+func (req *RecoverBikeRequest) Validate() []FieldError {
+	var errs []FieldError
+	if !isUUID(req.StationID) {
+		errs = append(errs, FieldError{Field: "station_id", Message: "must be a UUID"})
+	}
+	if req.DockSlot < 0 {
+		errs = append(errs, FieldError{Field: "dock_slot", Message: "must be positive"})
+	}
+	return errs
+}
+
+// recoverBike docks a bike found again at a station and puts it back in
+// service. The ride of a bike suspected lost ends there; the one of a bike
+// declared lost or stolen ended when it was declared.
+func (s *Server) recoverBike(w http.ResponseWriter, r *http.Request) {
+	operator, ok := requireSupervisor(w, r)
+	if !ok {
+		return
+	}
+
+	bikeID, status, ok := bikeFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	// Parse and validate the JSON request body
+	var req RecoverBikeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if status == models.BikeInService {
+		http.Error(w, "Bike is in service", http.StatusConflict)
+		return
+	}
+
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM stations WHERE id = $1)"
+	if err := s.DB.QueryRowContext(r.Context(), query, req.StationID).Scan(&exists); err != nil {
+		http.Error(w, "Failed to fetch station", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Station not found", http.StatusNotFound)
+		return
+	}
+
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Failed to recover bike", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	now := s.Clock.Now()
+	slot, err := occupyDock(r.Context(), tx, req.StationID, req.DockSlot, bikeID, now)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			http.Error(w, "Failed to occupy dock", http.StatusInternalServerError)
+		} else if req.DockSlot != 0 {
+			http.Error(w, "Dock slot is not free", http.StatusConflict)
+		} else {
+			http.Error(w, "Station has no free dock slots", http.StatusConflict)
+		}
+		return
+	}
+
+	station := sql.NullString{String: req.StationID, Valid: true}
+	if err := closeOpenAssignment(r, tx, bikeID, station, &s.Config.Pricing, now, operator.ID); err != nil {
+		http.Error(w, "Failed to close bike assignment", http.StatusInternalServerError)
+		return
+	}
+
+	bike := models.Bike{ID: bikeID, StationID: station, Status: models.BikeInService, LastUnassigned: sql.NullTime{Time: now, Valid: true}, AvailableFrom: now.Add(handover.Cooldown)}
+	query = "UPDATE bikes SET status = 'in_service', is_assigned = false, last_unassigned = $2, available_from = $4, station_id = $3 WHERE id = $1 RETURNING usage_count"
+	if err := tx.QueryRowContext(r.Context(), query, bikeID, now, req.StationID, bike.AvailableFrom).Scan(&bike.UsageCount); err != nil {
+		http.Error(w, "Failed to update bike", http.StatusInternalServerError)
+		return
+	}
+
+	query = "UPDATE bike_reports SET recovered_at = $2, recovered_by = $3, recovery_station_id = $4 WHERE bike_id = $1 AND recovered_at IS NULL"
+	if _, err := tx.ExecContext(r.Context(), query, bikeID, now, operator.ID, req.StationID); err != nil {
+		http.Error(w, "Failed to close bike report", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to recover bike", http.StatusInternalServerError)
+		return
+	}
+
+	s.recordAudit(r, audit.Event{
+		Actor:      audit.ActorFromRequest(r, operator.ID),
+		Action:     audit.ActionBikeRecovered,
+		EntityType: audit.EntityBike,
+		EntityID:   bikeID,
+		Before:     map[string]interface{}{"status": status},
+		After:      map[string]interface{}{"status": models.BikeInService, "station_id": req.StationID, "dock_slot": slot},
+	})
+
+	// Respond with the bike in JSON format
+	w.Header().Set(DockSlotHeader, strconv.Itoa(slot))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(bike); err != nil {
+		http.Error(w, "Failed to encode bike to JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/pricing"
+)
+
+// withBike sets the bike of the URL, as the router does
+func withBike(req *http.Request, bikeID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", bikeID)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestDeclareBikeLost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	mock.ExpectQuery("SELECT status FROM bikes WHERE id = \\$1").
+		WithArgs(bikeID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("in_service"))
+
+	// The bike is locked while the report is recorded, before the ride
+	// going on with it is touched
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM bikes WHERE id = \\$1 FOR UPDATE").
+		WithArgs(bikeID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("in_service"))
+	mock.ExpectQuery("SELECT id, user_id, bike_id, assigned_at, start_station_id, overdue_at FROM assignments WHERE bike_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(bikeID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bike_id", "assigned_at", "start_station_id", "overdue_at"}).
+			AddRow(42, userUUID, bikeID, fixedTime.Add(-time.Hour), stationID, nil))
+	mock.ExpectQuery("INSERT INTO bike_reports \\(bike_id, kind, report, assignment_id, user_id, fee_cents, reported_by, reported_at\\)").
+		WithArgs(bikeID, "stolen", "Taken while parked outside the station, police report 2024-1234", int64(42), userUUID, int64(30000), supervisorUUID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	// The bike was stolen during a ride, which ends now
+	mock.ExpectQuery("SELECT p.id, p.name, p.period, p.price_cents, p.included_minutes, p.max_rentals FROM subscriptions s").
+		WithArgs(userUUID, fixedTime.Add(-time.Hour)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("UPDATE assignments SET unassigned_at = \\$1, end_station_id = \\$3, fare_cents = \\$4, fare_currency = \\$5 WHERE id = \\$2 AND unassigned_at IS NULL").
+		WithArgs(fixedTime, uint(42), nil, int64(0), "EUR").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO receipts").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	// The user riding it is charged the replacement fee
+	mock.ExpectQuery("INSERT INTO wallet_accounts \\(user_id\\)").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("SELECT id FROM wallet_accounts WHERE name = \\$1").
+		WithArgs("revenue").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery("INSERT INTO wallet_transactions").
+		WithArgs("replacement_fee", int64(42), nil, nil, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO wallet_entries").
+		WithArgs(int64(9), int64(3), int64(-30000), int64(2), int64(30000)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	// It was not docked, nor reserved
+	mock.ExpectQuery("UPDATE docks SET state = 'free', bike_id = NULL, updated_at = \\$2 WHERE bike_id = \\$1 RETURNING slot").
+		WithArgs(bikeID, fixedTime).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("UPDATE reservations SET status = 'cancelled', updated_at = \\$1 WHERE bike_id = \\$2 AND status = 'active'").
+		WithArgs(fixedTime, bikeID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec("UPDATE bikes SET status = \\$2, is_assigned = false, station_id = NULL WHERE id = \\$1").
+		WithArgs(bikeID, "stolen").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs("operator", supervisorUUID, "bike.declared_lost", "bike", bikeID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	reqBody := `{"kind":"stolen","report":"Taken while parked outside the station, police report 2024-1234"}`
+	req := httptest.NewRequest(http.MethodPost, "/bikes/"+bikeID+"/report", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req = asOperator(req, supervisorUUID)
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Pricing: pricing.Tariff{ReplacementFee: 30000}}).declareBikeLost(rr, withBike(req, bikeID))
+
+	assert.Equal(t, http.StatusCreated, rr.Code, "Expected status Created but got %v", rr.Code)
+
+	var report models.BikeReport
+	err = json.NewDecoder(rr.Body).Decode(&report)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), report.ID)
+	assert.Equal(t, models.BikeStolen, report.Kind)
+	assert.Equal(t, userUUID, report.UserID.String)
+	assert.Equal(t, int64(30000), report.FeeCents)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeclareBikeLost_AlreadyDeclared(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC))
+
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	mock.ExpectQuery("SELECT status FROM bikes WHERE id = \\$1").
+		WithArgs(bikeID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("lost"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM bikes WHERE id = \\$1 FOR UPDATE").
+		WithArgs(bikeID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("lost"))
+	mock.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/bikes/"+bikeID+"/report", strings.NewReader(`{"kind":"stolen","report":"Seen on a second hand site"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = asOperator(req, supervisorUUID)
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Pricing: pricing.Tariff{ReplacementFee: 30000}}).declareBikeLost(rr, withBike(req, bikeID))
+
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status Conflict but got %v", rr.Code)
+	assert.Equal(t, "Bike is already declared lost or stolen\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeclareBikeLost_DeclaredConcurrently(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	mock.ExpectQuery("SELECT status FROM bikes WHERE id = \\$1").
+		WithArgs(bikeID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("in_service"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM bikes WHERE id = \\$1 FOR UPDATE").
+		WithArgs(bikeID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("in_service"))
+	mock.ExpectQuery("SELECT id, user_id, bike_id, assigned_at, start_station_id, overdue_at FROM assignments WHERE bike_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(bikeID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bike_id", "assigned_at", "start_station_id", "overdue_at"}).
+			AddRow(42, userUUID, bikeID, fixedTime.Add(-time.Hour), stationID, nil))
+
+	// Another report is open already: the ride is neither closed nor charged
+	mock.ExpectQuery("INSERT INTO bike_reports").
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/bikes/"+bikeID+"/report", strings.NewReader(`{"kind":"lost","report":"Not returned after a week"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = asOperator(req, supervisorUUID)
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Pricing: pricing.Tariff{ReplacementFee: 30000}}).declareBikeLost(rr, withBike(req, bikeID))
+
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status Conflict but got %v", rr.Code)
+	assert.Equal(t, "Bike is already declared lost or stolen\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecoverBike(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	mock.ExpectQuery("SELECT status FROM bikes WHERE id = \\$1").
+		WithArgs(bikeID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("lost"))
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+		WithArgs(stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE docks SET state = 'occupied', (.+) RETURNING slot").
+		WithArgs(stationID, bikeID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(4))
+
+	// The ride ended when the bike was declared lost
+	mock.ExpectQuery("SELECT id, user_id, bike_id, assigned_at, start_station_id, overdue_at FROM assignments WHERE bike_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(bikeID).
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectQuery("UPDATE bikes SET status = 'in_service', is_assigned = false, last_unassigned = \\$2, available_from = \\$4, station_id = \\$3 WHERE id = \\$1 RETURNING usage_count").
+		WithArgs(bikeID, fixedTime, stationID, fixedTime.Add(5*time.Minute)).
+		WillReturnRows(sqlmock.NewRows([]string{"usage_count"}).AddRow(12))
+	mock.ExpectExec("UPDATE bike_reports SET recovered_at = \\$2, recovered_by = \\$3, recovery_station_id = \\$4 WHERE bike_id = \\$1 AND recovered_at IS NULL").
+		WithArgs(bikeID, fixedTime, supervisorUUID, stationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs("operator", supervisorUUID, "bike.recovered", "bike", bikeID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/bikes/"+bikeID+"/recover", strings.NewReader(`{"station_id":"`+stationID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = asOperator(req, supervisorUUID)
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).recoverBike(rr, withBike(req, bikeID))
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+	assert.Equal(t, "4", rr.Header().Get(DockSlotHeader))
+
+	var bike models.Bike
+	err = json.NewDecoder(rr.Body).Decode(&bike)
+	assert.NoError(t, err)
+	assert.Equal(t, models.BikeInService, bike.Status)
+	assert.Equal(t, stationID, bike.StationID.String)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
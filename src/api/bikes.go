@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+// availableBikeCondition selects the bikes that can be handed over: in
+// service and not assigned, available from $1 on and not held by a
+// reservation, matching the idx_bikes_available partial index
+const availableBikeCondition = `is_assigned = false AND status = 'in_service'
+	          AND available_from <= $1
+	          AND NOT EXISTS (SELECT 1 FROM reservations WHERE reservations.bike_id = bikes.id AND reservations.status = 'active')`
+
+// leastUsedAvailableBike returns the available bike with the lowest usage
+// count at time now, ties going to the lowest ID, at the given station if
+// any, sql.ErrNoRows if there is none. The simulation follows the same rule,
+// see handover.LeastUsed.
+func leastUsedAvailableBike(ctx context.Context, db *sql.DB, now time.Time, stationID string) (models.Bike, error) {
+	args := []interface{}{now}
+	condition := availableBikeCondition
+	if stationID != "" {
+		args = append(args, stationID)
+		condition += " AND station_id = $2"
+	}
+
+	var bike models.Bike
+	query := `SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id
+	          FROM bikes
+	          WHERE ` + condition + `
+	          ORDER BY usage_count, id
+	          LIMIT 1`
+	err := db.QueryRowContext(ctx, query, args...).Scan(&bike.ID, &bike.IsAssigned, &bike.UsageCount, &bike.LastUnassigned, &bike.AvailableFrom, &bike.StationID)
+	return bike, err
+}
+
+// getAvailableBikes lists the bikes ready to be assigned
+func (s *Server) getAvailableBikes(w http.ResponseWriter, r *http.Request) {
+	// Prepare the query
+	query := `SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id
+	          FROM bikes
+	          WHERE ` + availableBikeCondition
+
+	// Execute the query
+	rows, err := s.DB.QueryContext(r.Context(), query, s.Clock.Now())
+	if err != nil {
+		s.Logger().Err(err).Msg("Query error")
+		http.Error(w, "Failed to retrieve available bikes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	// Initialize the bikes slice
+	bikes := []models.Bike{}
+
+	// Iterate over the rows and build the bikes slice
+	for rows.Next() {
+		var bike models.Bike
+		if err := rows.Scan(&bike.ID, &bike.IsAssigned, &bike.UsageCount, &bike.LastUnassigned, &bike.AvailableFrom, &bike.StationID); err != nil {
+			s.Logger().Err(err).Msg("Scan error")
+			http.Error(w, "Failed to scan bike", http.StatusInternalServerError)
+			return
+		}
+		bikes = append(bikes, bike)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		s.Logger().Err(err).Msg("Rows error")
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the list of available bikes in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(bikes); err != nil {
+		s.Logger().Err(err).Msg("JSON encoding error")
+		http.Error(w, "Failed to encode bikes to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) getAllBikes(w http.ResponseWriter, r *http.Request) {
+	// Prepare the query
+	query := "SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id, status FROM bikes"
+
+	// Execute the query
+	rows, err := s.DB.QueryContext(r.Context(), query)
+	if err != nil {
+		http.Error(w, "Failed to retrieve bikes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	// Initialize the bikes slice
+	bikes := []models.Bike{}
+
+	// Iterate over the rows and build the bikes slice
+	for rows.Next() {
+		var bike models.Bike
+		if err := rows.Scan(&bike.ID, &bike.IsAssigned, &bike.UsageCount, &bike.LastUnassigned, &bike.AvailableFrom, &bike.StationID, &bike.Status); err != nil {
+			http.Error(w, "Failed to scan bike", http.StatusInternalServerError)
+			return
+		}
+		bikes = append(bikes, bike)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the list of bikes in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(bikes); err != nil {
+		http.Error(w, "Failed to encode bikes to JSON", http.StatusInternalServerError)
+		return
+	}
+}
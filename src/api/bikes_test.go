@@ -1,4 +1,4 @@
-package controllers
+package api
 
 import (
 	"database/sql"
@@ -10,6 +10,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/test-go/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
 	"github.com/yourusername/bike-rental/src/database/models"
 )
 
@@ -23,21 +24,16 @@ func TestGetAvailableBikes(t *testing.T) {
 
 	// Use a fixed time for testing
 	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
-	timeNow = func() time.Time {
-		return fixedTime
-	}
-	defer func() { timeNow = time.Now }() // Restore the original timeNow after the test
-
-	gracePeriod := fixedTime.Add(-5 * time.Minute)
+	clk := clock.NewFake(fixedTime)
 
 	// Prepare mock data
-	mockRows := sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned"}).
-		AddRow("bike-1", false, 10, sql.NullTime{Time: gracePeriod.Add(-10 * time.Minute), Valid: true}).
-		AddRow("bike-2", false, 5, sql.NullTime{Time: gracePeriod.Add(-15 * time.Minute), Valid: true})
+	mockRows := sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned", "available_from", "station_id"}).
+		AddRow("bike-1", false, 10, sql.NullTime{Time: fixedTime.Add(-15 * time.Minute), Valid: true}, fixedTime.Add(-10*time.Minute), stationID).
+		AddRow("bike-2", false, 5, sql.NullTime{Time: fixedTime.Add(-20 * time.Minute), Valid: true}, fixedTime.Add(-15*time.Minute), stationID)
 
 	// Set up the expectations
-	mock.ExpectQuery(`SELECT id, is_assigned, usage_count, last_unassigned FROM bikes WHERE is_assigned = false AND \(last_unassigned IS NULL OR last_unassigned < \$1\)`).
-		WithArgs(gracePeriod).
+	mock.ExpectQuery(`SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id FROM bikes WHERE is_assigned = false AND status = 'in_service' AND available_from <= \$1 AND NOT EXISTS \(SELECT 1 FROM reservations WHERE reservations.bike_id = bikes.id AND reservations.status = 'active'\)`).
+		WithArgs(fixedTime).
 		WillReturnRows(mockRows)
 
 	// Create a new HTTP request
@@ -50,7 +46,7 @@ func TestGetAvailableBikes(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	// Call the function
-	GetAvailableBikes(rr, req, db)
+	testServer(db, clk, &Config{}).getAvailableBikes(rr, req)
 
 	// Check the status code
 	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
@@ -81,12 +77,12 @@ func TestGetAllBikes(t *testing.T) {
 	defer db.Close()
 
 	// Prepare mock data
-	mockRows := sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned"}).
-		AddRow("bike-1", false, 10, sql.NullTime{Time: time.Now(), Valid: true}).
-		AddRow("bike-2", true, 5, sql.NullTime{Time: time.Now(), Valid: true})
+	mockRows := sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned", "available_from", "station_id", "status"}).
+		AddRow("bike-1", false, 10, sql.NullTime{Time: time.Now(), Valid: true}, time.Now().Add(5*time.Minute), stationID, "in_service").
+		AddRow("bike-2", true, 5, sql.NullTime{Time: time.Now(), Valid: true}, time.Now(), stationID, "lost_suspected")
 
 	// Set up the expectations for the SELECT query
-	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned FROM bikes").
+	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id, status FROM bikes").
 		WillReturnRows(mockRows)
 
 	// Create a new HTTP request
@@ -99,7 +95,7 @@ func TestGetAllBikes(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	// Call the function to test
-	GetAllBikes(rr, req, db)
+	testServer(db, nil, &Config{}).getAllBikes(rr, req)
 
 	// Check the status code
 	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
@@ -113,6 +109,7 @@ func TestGetAllBikes(t *testing.T) {
 	assert.Len(t, bikes, 2, "Expected 2 bikes but got %v", len(bikes))
 	assert.Equal(t, "bike-1", bikes[0].ID)
 	assert.Equal(t, "bike-2", bikes[1].ID)
+	assert.Equal(t, models.BikeLostSuspected, bikes[1].Status)
 
 	// Assert that all expectations were met
 	err = mock.ExpectationsWereMet()
@@ -128,7 +125,7 @@ func TestGetAllBikes_DBError(t *testing.T) {
 	defer db.Close()
 
 	// Set up the expectations for the SELECT query to return an error
-	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned FROM bikes").
+	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id, status FROM bikes").
 		WillReturnError(sql.ErrConnDone)
 
 	// Create a new HTTP request
@@ -141,7 +138,7 @@ func TestGetAllBikes_DBError(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	// Call the function to test
-	GetAllBikes(rr, req, db)
+	testServer(db, nil, &Config{}).getAllBikes(rr, req)
 
 	// Check the status code
 	assert.Equal(t, http.StatusInternalServerError, rr.Code, "Expected status Internal Server Error but got %v", rr.Code)
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/wallet"
+)
+
+// DockSlotHeader tells the docking station which slot was unlocked on
+// assignment, or locked on return
+const DockSlotHeader = "X-Dock-Slot"
+
+// releaseDock frees the dock slot a bike is locked in. It returns
+// sql.ErrNoRows if the bike is not docked.
+func releaseDock(ctx context.Context, db wallet.Queryer, bikeID string, now time.Time) (int, error) {
+	var slot int
+	query := "UPDATE docks SET state = 'free', bike_id = NULL, updated_at = $2 WHERE bike_id = $1 RETURNING slot"
+	err := db.QueryRowContext(ctx, query, bikeID, now).Scan(&slot)
+	return slot, err
+}
+
+// occupyDock locks a bike in the given free slot of a station, or in its
+// first free one if slot is 0. It returns sql.ErrNoRows if there is none.
+func occupyDock(ctx context.Context, db wallet.Queryer, stationID string, slot int, bikeID string, now time.Time) (int, error) {
+	args := []interface{}{stationID, bikeID, now}
+	condition := "station_id = $1 AND state = 'free'"
+	if slot != 0 {
+		args = append(args, slot)
+		condition += " AND slot = $4"
+	}
+
+	query := `UPDATE docks SET state = 'occupied', bike_id = $2, updated_at = $3
+	          WHERE id = (SELECT id FROM docks WHERE ` + condition + ` ORDER BY slot LIMIT 1 FOR UPDATE SKIP LOCKED)
+	          RETURNING slot`
+	err := db.QueryRowContext(ctx, query, args...).Scan(&slot)
+	return slot, err
+}
+
+// StationOccupancy summarises the docks of a station
+type StationOccupancy struct {
+	StationID        string        `json:"station_id"`
+	Docks            int           `json:"docks"`
+	FreeDocks        int           `json:"free_docks"`
+	OutOfService     int           `json:"out_of_service_docks"`
+	BikesReady       int           `json:"bikes_ready"`        // can be assigned right away
+	BikesCoolingDown int           `json:"bikes_cooling_down"` // returned less than 5 minutes ago
+	BikesReserved    int           `json:"bikes_reserved"`     // held for a user
+	Slots            []models.Dock `json:"slots"`
+}
+
+// getStationOccupancy lists the dock slots of a station and counts the free
+// ones and the bikes ready to be assigned
+func (s *Server) getStationOccupancy(w http.ResponseWriter, r *http.Request) {
+	stationID, ok := stationFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	query := `SELECT d.id, d.station_id, d.slot, d.state, d.bike_id, b.available_from,
+	                 EXISTS (SELECT 1 FROM reservations WHERE reservations.bike_id = d.bike_id AND reservations.status = 'active')
+	          FROM docks d
+	          LEFT JOIN bikes b ON b.id = d.bike_id
+	          WHERE d.station_id = $1
+	          ORDER BY d.slot`
+	rows, err := s.DB.QueryContext(r.Context(), query, stationID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve docks", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	now := s.Clock.Now()
+	occupancy := StationOccupancy{StationID: stationID, Slots: []models.Dock{}}
+	for rows.Next() {
+		var dock models.Dock
+		var availableFrom sql.NullTime
+		var reserved bool
+		if err := rows.Scan(&dock.ID, &dock.StationID, &dock.Slot, &dock.State, &dock.BikeID, &availableFrom, &reserved); err != nil {
+			http.Error(w, "Failed to scan dock", http.StatusInternalServerError)
+			return
+		}
+
+		occupancy.Docks++
+		switch {
+		case dock.State == models.DockFree:
+			occupancy.FreeDocks++
+		case dock.State == models.DockOutOfService:
+			occupancy.OutOfService++
+		case reserved:
+			occupancy.BikesReserved++
+		case availableFrom.Valid && availableFrom.Time.After(now):
+			occupancy.BikesCoolingDown++
+		default:
+			occupancy.BikesReady++
+		}
+		occupancy.Slots = append(occupancy.Slots, dock)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the occupancy in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(occupancy); err != nil {
+		http.Error(w, "Failed to encode occupancy to JSON", http.StatusInternalServerError)
+		return
+	}
+}
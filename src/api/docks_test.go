@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/handover"
+)
+
+func TestGetStationOccupancy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+		WithArgs(stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	mock.ExpectQuery("SELECT d.id, d.station_id, d.slot, d.state, d.bike_id, b.available_from, (.+) FROM docks d LEFT JOIN bikes b ON b.id = d.bike_id WHERE d.station_id = \\$1 ORDER BY d.slot").
+		WithArgs(stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "station_id", "slot", "state", "bike_id", "available_from", "reserved"}).
+			AddRow(1, stationID, 1, "occupied", "bike-1", fixedTime.Add(-time.Hour), false).
+			AddRow(2, stationID, 2, "occupied", "bike-2", fixedTime.Add(3*time.Minute), false).
+			AddRow(3, stationID, 3, "occupied", "bike-3", nil, true).
+			AddRow(4, stationID, 4, "free", nil, nil, false).
+			AddRow(5, stationID, 5, "out_of_service", nil, nil, false))
+
+	req := httptest.NewRequest(http.MethodGet, "/stations/"+stationID+"/occupancy", nil)
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).getStationOccupancy(rr, withStation(req))
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+
+	var occupancy StationOccupancy
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&occupancy))
+	assert.Equal(t, 5, occupancy.Docks)
+	assert.Equal(t, 1, occupancy.FreeDocks)
+	assert.Equal(t, 1, occupancy.OutOfService)
+	assert.Equal(t, 1, occupancy.BikesReady)
+	assert.Equal(t, 1, occupancy.BikesCoolingDown)
+	assert.Equal(t, 1, occupancy.BikesReserved)
+	assert.Len(t, occupancy.Slots, 5)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetStationOccupancy_CooldownBoundary(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// The bike was docked when the clock starts
+	returnedAt := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(returnedAt)
+
+	occupancy := func() StationOccupancy {
+		mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+			WithArgs(stationID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("SELECT (.+) FROM docks d LEFT JOIN bikes b ON b.id = d.bike_id WHERE d.station_id = \\$1 ORDER BY d.slot").
+			WithArgs(stationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "station_id", "slot", "state", "bike_id", "available_from", "reserved"}).
+				AddRow(1, stationID, 1, "occupied", "bike-1", returnedAt.Add(handover.Cooldown), false))
+
+		req := httptest.NewRequest(http.MethodGet, "/stations/"+stationID+"/occupancy", nil)
+		rr := httptest.NewRecorder()
+		testServer(db, clk, &Config{}).getStationOccupancy(rr, withStation(req))
+		assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+
+		var occupancy StationOccupancy
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&occupancy))
+		return occupancy
+	}
+
+	// Still cooling down a nanosecond before the 5 minutes
+	clk.Advance(handover.Cooldown - time.Nanosecond)
+	before := occupancy()
+	assert.Equal(t, 1, before.BikesCoolingDown)
+	assert.Equal(t, 0, before.BikesReady)
+
+	// Ready exactly 5 minutes after it was docked
+	clk.Advance(time.Nanosecond)
+	after := occupancy()
+	assert.Equal(t, 0, after.BikesCoolingDown)
+	assert.Equal(t, 1, after.BikesReady)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/invoices"
+)
+
+// wantsPDF reports whether the client asked for a PDF rather than JSON
+func wantsPDF(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/pdf")
+}
+
+// writePDF renders a document in memory first, so a rendering error can
+// still be answered with a 500
+func writePDF(w http.ResponseWriter, filename string, render func(*bytes.Buffer) error) {
+	var buf bytes.Buffer
+	if err := render(&buf); err != nil {
+		http.Error(w, "Failed to render PDF", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.Write(buf.Bytes())
+}
+
+const receiptColumns = `id, assignment_id, user_id, bike_id, start_station_id, end_station_id, started_at, ended_at, plan_id,
+	                       minutes, unlock_cents, time_cents, penalty_cents, total_cents, currency`
+
+func scanReceipt(row interface{ Scan(...interface{}) error }, receipt *models.Receipt) error {
+	return row.Scan(&receipt.ID, &receipt.AssignmentID, &receipt.UserID, &receipt.BikeID, &receipt.StartStationID, &receipt.EndStationID, &receipt.StartedAt, &receipt.EndedAt, &receipt.PlanID,
+		&receipt.Minutes, &receipt.UnlockCents, &receipt.TimeCents, &receipt.PenaltyCents, &receipt.TotalCents, &receipt.Currency)
+}
+
+// getReceipts lists the receipts of a user, most recent first
+func (s *Server) getReceipts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	query := "SELECT " + receiptColumns + " FROM receipts WHERE user_id = $1 ORDER BY ended_at DESC, id DESC"
+	rows, err := s.DB.QueryContext(r.Context(), query, userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve receipts", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	receipts := []models.Receipt{}
+	for rows.Next() {
+		var receipt models.Receipt
+		if err := scanReceipt(rows, &receipt); err != nil {
+			http.Error(w, "Failed to scan receipt", http.StatusInternalServerError)
+			return
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the receipts in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(receipts); err != nil {
+		http.Error(w, "Failed to encode receipts to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getReceipt returns the receipt of an assignment of a user, as a PDF when
+// asked with Accept: application/pdf
+func (s *Server) getReceipt(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	assignmentID, err := strconv.ParseUint(chi.URLParam(r, "assignment_id"), 10, 64)
+	if err != nil {
+		writeValidationError(w, http.StatusBadRequest, "Invalid request", FieldError{Field: "assignment_id", Message: "must be a positive integer"})
+		return
+	}
+
+	var receipt models.Receipt
+	query := "SELECT " + receiptColumns + " FROM receipts WHERE user_id = $1 AND assignment_id = $2"
+	if err := scanReceipt(s.DB.QueryRowContext(r.Context(), query, userID, assignmentID), &receipt); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Receipt not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch receipt", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if wantsPDF(r) {
+		writePDF(w, fmt.Sprintf("receipt-%d.pdf", receipt.AssignmentID), func(buf *bytes.Buffer) error {
+			return invoices.WriteReceiptPDF(buf, receipt)
+		})
+		return
+	}
+
+	// Respond with the receipt in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(receipt); err != nil {
+		http.Error(w, "Failed to encode receipt to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+const invoiceColumns = `id, number, user_id, period_start, period_end, currency, rides,
+	                       unlock_cents, time_cents, penalty_cents, subscription_cents, replacement_cents, refund_cents, total_cents, issued_at`
+
+func scanInvoice(row interface{ Scan(...interface{}) error }, invoice *models.Invoice) error {
+	return row.Scan(&invoice.ID, &invoice.Number, &invoice.UserID, &invoice.PeriodStart, &invoice.PeriodEnd, &invoice.Currency, &invoice.Rides,
+		&invoice.UnlockCents, &invoice.TimeCents, &invoice.PenaltyCents, &invoice.SubscriptionCents,
+		&invoice.ReplacementCents, &invoice.RefundCents, &invoice.TotalCents, &invoice.IssuedAt)
+}
+
+// getInvoices lists the invoices of a user, most recent first, without
+// their lines
+func (s *Server) getInvoices(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	query := "SELECT " + invoiceColumns + " FROM invoices WHERE user_id = $1 ORDER BY period_start DESC"
+	rows, err := s.DB.QueryContext(r.Context(), query, userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve invoices", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []models.Invoice{}
+	for rows.Next() {
+		var invoice models.Invoice
+		if err := scanInvoice(rows, &invoice); err != nil {
+			http.Error(w, "Failed to scan invoice", http.StatusInternalServerError)
+			return
+		}
+		list = append(list, invoice)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the invoices in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		http.Error(w, "Failed to encode invoices to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getInvoice returns an invoice of a user with its lines, as a PDF when
+// asked with Accept: application/pdf
+func (s *Server) getInvoice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	var invoice models.Invoice
+	query := "SELECT " + invoiceColumns + " FROM invoices WHERE user_id = $1 AND number = $2"
+	if err := scanInvoice(s.DB.QueryRowContext(r.Context(), query, userID, chi.URLParam(r, "number")), &invoice); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch invoice", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	query = `SELECT kind, description, assignment_id, occurred_at, amount_cents
+	         FROM invoice_lines
+	         WHERE invoice_id = $1
+	         ORDER BY occurred_at, id`
+	rows, err := s.DB.QueryContext(r.Context(), query, invoice.ID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve invoice lines", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	invoice.Lines = []models.InvoiceLine{}
+	for rows.Next() {
+		var line models.InvoiceLine
+		if err := rows.Scan(&line.Kind, &line.Description, &line.AssignmentID, &line.OccurredAt, &line.AmountCents); err != nil {
+			http.Error(w, "Failed to scan invoice line", http.StatusInternalServerError)
+			return
+		}
+		invoice.Lines = append(invoice.Lines, line)
+	}
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	if wantsPDF(r) {
+		writePDF(w, invoice.Number+".pdf", func(buf *bytes.Buffer) error {
+			return invoices.WriteInvoicePDF(buf, invoice)
+		})
+		return
+	}
+
+	// Respond with the invoice in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(invoice); err != nil {
+		http.Error(w, "Failed to encode invoice to JSON", http.StatusInternalServerError)
+		return
+	}
+}
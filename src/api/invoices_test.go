@@ -0,0 +1,113 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func expectInvoice(mock sqlmock.Sqlmock, userUUID string) {
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM users WHERE id = \\$1\\)").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	periodStart := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "number", "user_id", "period_start", "period_end", "currency", "rides",
+		"unlock_cents", "time_cents", "penalty_cents", "subscription_cents", "replacement_cents", "refund_cents", "total_cents", "issued_at"}
+	mock.ExpectQuery("SELECT (.+) FROM invoices WHERE user_id = \\$1 AND number = \\$2").
+		WithArgs(userUUID, "INV-2024-000042").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(7, "INV-2024-000042", userUUID, periodStart, periodStart.AddDate(0, 1, 0), "EUR", 1, 100, 450, 0, 1990, 0, 0, 2540, periodStart.AddDate(0, 1, 0)))
+	mock.ExpectQuery("SELECT kind, description, assignment_id, occurred_at, amount_cents FROM invoice_lines WHERE invoice_id = \\$1").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "description", "assignment_id", "occurred_at", "amount_cents"}).
+			AddRow("ride", "Ride of 30 min", 12, time.Date(2024, 7, 3, 8, 30, 0, 0, time.UTC), 550).
+			AddRow("subscription", "Monthly pass from 2024-07-20 to 2024-08-20", nil, time.Date(2024, 7, 20, 7, 0, 0, 0, time.UTC), 1990))
+}
+
+func TestGetInvoice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	expectInvoice(mock, userUUID)
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/users/"+userUUID+"/invoices/INV-2024-000042", nil), userUUID)
+	chi.RouteContext(req.Context()).URLParams.Add("number", "INV-2024-000042")
+	rr := httptest.NewRecorder()
+
+	testServer(db, nil, &Config{}).getInvoice(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+
+	var invoice models.Invoice
+	err = json.NewDecoder(rr.Body).Decode(&invoice)
+	assert.NoError(t, err)
+	assert.Equal(t, "INV-2024-000042", invoice.Number)
+	assert.Equal(t, int64(2540), invoice.TotalCents)
+	assert.Len(t, invoice.Lines, 2)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetInvoice_PDF(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	expectInvoice(mock, userUUID)
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/users/"+userUUID+"/invoices/INV-2024-000042", nil), userUUID)
+	req.Header.Set("Accept", "application/pdf")
+	chi.RouteContext(req.Context()).URLParams.Add("number", "INV-2024-000042")
+	rr := httptest.NewRecorder()
+
+	testServer(db, nil, &Config{}).getInvoice(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+	assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+	assert.True(t, bytes.HasPrefix(rr.Body.Bytes(), []byte("%PDF-")), "Expected a PDF document")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetReceipt_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM users WHERE id = \\$1\\)").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	// Receipts of other users are not found either
+	mock.ExpectQuery("SELECT (.+) FROM receipts WHERE user_id = \\$1 AND assignment_id = \\$2").
+		WithArgs(userUUID, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/users/"+userUUID+"/receipts/42", nil), userUUID)
+	chi.RouteContext(req.Context()).URLParams.Add("assignment_id", "42")
+	rr := httptest.NewRecorder()
+
+	testServer(db, nil, &Config{}).getReceipt(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status Not Found but got %v", rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metrics are the counters of the API, exported by the meter provider they
+// were created with
+type Metrics struct {
+	RidesStarted metric.Int64Counter
+	RidesEnded   metric.Int64Counter
+	FareCents    metric.Int64Counter
+	BikesLost    metric.Int64Counter
+}
+
+// NewMetrics creates the counters of the API with meter
+func NewMetrics(meter metric.Meter) (*Metrics, error) {
+	var metrics Metrics
+	var err error
+	if metrics.RidesStarted, err = meter.Int64Counter("bikerental.rides.started", metric.WithDescription("Bikes assigned")); err != nil {
+		return nil, err
+	}
+	if metrics.RidesEnded, err = meter.Int64Counter("bikerental.rides.ended", metric.WithDescription("Bikes returned by their riders")); err != nil {
+		return nil, err
+	}
+	if metrics.FareCents, err = meter.Int64Counter("bikerental.rides.fare", metric.WithDescription("Fares of the rides returned"), metric.WithUnit("{cent}")); err != nil {
+		return nil, err
+	}
+	if metrics.BikesLost, err = meter.Int64Counter("bikerental.bikes.lost", metric.WithDescription("Bikes declared lost or stolen")); err != nil {
+		return nil, err
+	}
+	return &metrics, nil
+}
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/rebalancing"
+)
+
+// Days of assignments the demand of the stations is computed from
+const (
+	defaultDemandDays = 7
+	maxDemandDays     = 90
+)
+
+// maxMoveBikes caps the bikes of a single move, about what a van carries
+const maxMoveBikes = 50
+
+// RebalancingPlan is the list of moves suggested to the van crews
+type RebalancingPlan struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	DemandSince time.Time          `json:"demand_since"`
+	Moves       []rebalancing.Move `json:"moves"`
+}
+
+// getRebalancingPlan suggests the bikes to move between stations, from
+// their current locations, the docks and the demand of the last days
+func (s *Server) getRebalancingPlan(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireSupervisor(w, r); !ok {
+		return
+	}
+
+	days := defaultDemandDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxDemandDays {
+			writeValidationError(w, http.StatusBadRequest, "Invalid request", FieldError{Field: "days", Message: fmt.Sprintf("must be an integer between 1 and %d", maxDemandDays)})
+			return
+		}
+		days = parsed
+	}
+
+	now := s.Clock.Now()
+	plan := RebalancingPlan{GeneratedAt: now, DemandSince: now.AddDate(0, 0, -days)}
+
+	// Docks and parked bikes of each station
+	query := `SELECT s.id,
+	                 COUNT(d.id) FILTER (WHERE d.state <> 'out_of_service'),
+	                 COUNT(d.id) FILTER (WHERE d.state = 'occupied')
+	          FROM stations s
+	          LEFT JOIN docks d ON d.station_id = s.id
+	          WHERE s.deleted_at IS NULL
+	          GROUP BY s.id
+	          ORDER BY s.id`
+	rows, err := s.DB.QueryContext(r.Context(), query)
+	if err != nil {
+		http.Error(w, "Failed to retrieve stations", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var stations []rebalancing.Station
+	index := map[string]int{}
+	for rows.Next() {
+		var station rebalancing.Station
+		if err := rows.Scan(&station.ID, &station.Docks, &station.Parked); err != nil {
+			http.Error(w, "Failed to scan station", http.StatusInternalServerError)
+			return
+		}
+		index[station.ID] = len(stations)
+		stations = append(stations, station)
+	}
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Bikes taken from each station over the window
+	query = `SELECT start_station_id, COUNT(*)
+	         FROM assignments
+	         WHERE start_station_id IS NOT NULL AND assigned_at >= $1
+	         GROUP BY start_station_id`
+	rows, err = s.DB.QueryContext(r.Context(), query, plan.DemandSince)
+	if err != nil {
+		http.Error(w, "Failed to retrieve demand", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stationID string
+		var demand int
+		if err := rows.Scan(&stationID, &demand); err != nil {
+			http.Error(w, "Failed to scan demand", http.StatusInternalServerError)
+			return
+		}
+		if i, ok := index[stationID]; ok {
+			stations[i].Demand = demand
+		}
+	}
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Docked bikes not held for a user can be moved, the most used first
+	query = `SELECT d.station_id, d.bike_id
+	         FROM docks d
+	         INNER JOIN bikes b ON b.id = d.bike_id
+	         WHERE d.state = 'occupied'
+	           AND NOT EXISTS (SELECT 1 FROM reservations WHERE reservations.bike_id = d.bike_id AND reservations.status = 'active')
+	         ORDER BY d.station_id, b.usage_count DESC, d.bike_id`
+	rows, err = s.DB.QueryContext(r.Context(), query)
+	if err != nil {
+		http.Error(w, "Failed to retrieve bikes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stationID, bikeID string
+		if err := rows.Scan(&stationID, &bikeID); err != nil {
+			http.Error(w, "Failed to scan bike", http.StatusInternalServerError)
+			return
+		}
+		if i, ok := index[stationID]; ok {
+			stations[i].Bikes = append(stations[i].Bikes, bikeID)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	plan.Moves = rebalancing.Plan(stations)
+
+	// Respond with the plan in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		http.Error(w, "Failed to encode plan to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+type RebalancingMoveRequest struct {
+	FromStationID string   `json:"from_station_id"`
+	ToStationID   string   `json:"to_station_id"`
+	BikeIDs       []string `json:"bike_ids"`
+}
+
+func (req *RebalancingMoveRequest) Validate() []FieldError {
+	errs := checkUUID(nil, "from_station_id", req.FromStationID)
+	errs = checkUUID(errs, "to_station_id", req.ToStationID)
+	if req.FromStationID != "" && req.FromStationID == req.ToStationID {
+		errs = append(errs, FieldError{Field: "to_station_id", Message: "must differ from from_station_id"})
+	}
+	switch {
+	case len(req.BikeIDs) == 0:
+		errs = append(errs, FieldError{Field: "bike_ids", Message: "is required"})
+	case len(req.BikeIDs) > maxMoveBikes:
+		errs = append(errs, FieldError{Field: "bike_ids", Message: fmt.Sprintf("must not exceed %d bikes", maxMoveBikes)})
+	}
+	for i, bikeID := range req.BikeIDs {
+		if !isUUID(bikeID) {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("bike_ids[%d]", i), Message: "must be a UUID"})
+		}
+	}
+	return errs
+}
+
+// recordRebalancingMove relocates the bikes a van crew moved between two
+// stations. No assignment is created: the bikes go from the docks of one
+// station to the free docks of the other.
+func (s *Server) recordRebalancingMove(w http.ResponseWriter, r *http.Request) {
+	operator, ok := requireSupervisor(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate the JSON request body
+	var req RebalancingMoveRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// The whole move is recorded at once, or not at all
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Failed to record move", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// The bikes must all be docked at the origin and not held for a user,
+	// their docks being locked until the move is recorded
+	var docked int
+	query := `SELECT COUNT(*) FROM (
+	            SELECT 1 FROM docks
+	            WHERE station_id = $1 AND bike_id = ANY($2)
+	              AND NOT EXISTS (SELECT 1 FROM reservations WHERE reservations.bike_id = docks.bike_id AND reservations.status = 'active')
+	            FOR UPDATE
+	          ) AS docked`
+	if err := tx.QueryRowContext(r.Context(), query, req.FromStationID, pq.Array(req.BikeIDs)).Scan(&docked); err != nil {
+		http.Error(w, "Failed to fetch docks", http.StatusInternalServerError)
+		return
+	}
+	if docked != len(req.BikeIDs) {
+		http.Error(w, "Bikes must be docked at the origin station and not reserved", http.StatusConflict)
+		return
+	}
+
+	// The destination must have a free dock for each of them
+	var free int
+	query = `SELECT (SELECT COUNT(*) FROM docks WHERE docks.station_id = s.id AND docks.state = 'free')
+	         FROM stations s WHERE s.id = $1`
+	if err := tx.QueryRowContext(r.Context(), query, req.ToStationID).Scan(&free); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Station not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch station", http.StatusInternalServerError)
+		}
+		return
+	}
+	if free < len(req.BikeIDs) {
+		http.Error(w, "Station does not have enough free dock slots", http.StatusConflict)
+		return
+	}
+
+	now := s.Clock.Now()
+	moves := []models.RebalancingMove{}
+	slots := map[string]int{}
+	for _, bikeID := range req.BikeIDs {
+		if _, err := releaseDock(r.Context(), tx, bikeID, now); err != nil {
+			http.Error(w, "Failed to release dock", http.StatusInternalServerError)
+			return
+		}
+		slot, err := occupyDock(r.Context(), tx, req.ToStationID, 0, bikeID, now)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				// The free docks were taken by bikes returned meanwhile
+				http.Error(w, "Station does not have enough free dock slots", http.StatusConflict)
+			} else {
+				http.Error(w, "Failed to occupy dock", http.StatusInternalServerError)
+			}
+			return
+		}
+		slots[bikeID] = slot
+
+		query = "UPDATE bikes SET station_id = $1 WHERE id = $2"
+		if _, err := tx.ExecContext(r.Context(), query, req.ToStationID, bikeID); err != nil {
+			http.Error(w, "Failed to relocate bike", http.StatusInternalServerError)
+			return
+		}
+
+		move := models.RebalancingMove{BikeID: bikeID, FromStationID: req.FromStationID, ToStationID: req.ToStationID, OperatorID: operator.ID, MovedAt: now}
+		query = `INSERT INTO rebalancing_moves (bike_id, from_station_id, to_station_id, operator_id, moved_at)
+		         VALUES ($1, $2, $3, $4, $5)
+		         RETURNING id`
+		if err := tx.QueryRowContext(r.Context(), query, move.BikeID, move.FromStationID, move.ToStationID, move.OperatorID, move.MovedAt).Scan(&move.ID); err != nil {
+			http.Error(w, "Failed to record move", http.StatusInternalServerError)
+			return
+		}
+		moves = append(moves, move)
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to record move", http.StatusInternalServerError)
+		return
+	}
+
+	for _, move := range moves {
+		s.recordAudit(r, audit.Event{
+			Actor:      audit.ActorFromRequest(r, operator.ID),
+			Action:     audit.ActionBikeRelocated,
+			EntityType: audit.EntityBike,
+			EntityID:   move.BikeID,
+			Before:     map[string]interface{}{"station_id": move.FromStationID},
+			After:      map[string]interface{}{"station_id": move.ToStationID, "dock_slot": slots[move.BikeID]},
+		})
+	}
+
+	// Respond with the recorded moves in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(moves); err != nil {
+		http.Error(w, "Failed to encode moves to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getRebalancingMoves lists the last bikes relocated by the van crews
+func (s *Server) getRebalancingMoves(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireSupervisor(w, r); !ok {
+		return
+	}
+
+	query := `SELECT id, bike_id, from_station_id, to_station_id, operator_id, moved_at
+	          FROM rebalancing_moves
+	          ORDER BY moved_at DESC, id DESC
+	          LIMIT 100`
+	rows, err := s.DB.QueryContext(r.Context(), query)
+	if err != nil {
+		http.Error(w, "Failed to retrieve moves", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	moves := []models.RebalancingMove{}
+	for rows.Next() {
+		var move models.RebalancingMove
+		if err := rows.Scan(&move.ID, &move.BikeID, &move.FromStationID, &move.ToStationID, &move.OperatorID, &move.MovedAt); err != nil {
+			http.Error(w, "Failed to scan move", http.StatusInternalServerError)
+			return
+		}
+		moves = append(moves, move)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the moves in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(moves); err != nil {
+		http.Error(w, "Failed to encode moves to JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func TestRecordRebalancingMove(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+	toStationID := "5b2e8f1c-9a3d-4c7e-8f6a-1d2c3b4a5e6f"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM \\( SELECT 1 FROM docks WHERE station_id = \\$1 AND bike_id = ANY\\(\\$2\\) (.+) FOR UPDATE \\) AS docked").
+		WithArgs(stationID, pq.Array([]string{bikeID})).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	mock.ExpectQuery("SELECT \\(SELECT COUNT\\(\\*\\) FROM docks (.+)\\) FROM stations s WHERE s.id = \\$1").
+		WithArgs(toStationID).
+		WillReturnRows(sqlmock.NewRows([]string{"free"}).AddRow(3))
+
+	mock.ExpectQuery("UPDATE docks SET state = 'free', bike_id = NULL, updated_at = \\$2 WHERE bike_id = \\$1 RETURNING slot").
+		WithArgs(bikeID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(1))
+
+	mock.ExpectQuery("UPDATE docks SET state = 'occupied', (.+) RETURNING slot").
+		WithArgs(toStationID, bikeID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(4))
+
+	// The bike is relocated without any assignment
+	mock.ExpectExec("UPDATE bikes SET station_id = \\$1 WHERE id = \\$2").
+		WithArgs(toStationID, bikeID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery("INSERT INTO rebalancing_moves \\(bike_id, from_station_id, to_station_id, operator_id, moved_at\\)").
+		WithArgs(bikeID, stationID, toStationID, supervisorUUID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs("operator", supervisorUUID, "bike.relocated", "bike", bikeID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	reqBody := `{"from_station_id":"` + stationID + `","to_station_id":"` + toStationID + `","bike_ids":["` + bikeID + `"]}`
+	req := httptest.NewRequest(http.MethodPost, "/rebalancing/moves", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req = asOperator(req, supervisorUUID)
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).recordRebalancingMove(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code, "Expected status Created but got %v", rr.Code)
+
+	var moves []models.RebalancingMove
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&moves))
+	if assert.Len(t, moves, 1) {
+		assert.Equal(t, uint(5), moves[0].ID)
+		assert.Equal(t, toStationID, moves[0].ToStationID)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordRebalancingMove_BikeNotDocked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC))
+
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+	toStationID := "5b2e8f1c-9a3d-4c7e-8f6a-1d2c3b4a5e6f"
+	bikeIDs := []string{"331e7ffb-e583-4535-ba41-4c28dc34016d", "e4ef2d9b-5d5a-4f85-bb3a-b2df8bf42ac1"}
+
+	// One of the bikes is ridden, or parked elsewhere
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM \\( SELECT 1 FROM docks WHERE station_id = \\$1 AND bike_id = ANY\\(\\$2\\) (.+) FOR UPDATE \\) AS docked").
+		WithArgs(stationID, pq.Array(bikeIDs)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	reqBody := `{"from_station_id":"` + stationID + `","to_station_id":"` + toStationID + `","bike_ids":["` + strings.Join(bikeIDs, `","`) + `"]}`
+	req := httptest.NewRequest(http.MethodPost, "/rebalancing/moves", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req = asOperator(req, supervisorUUID)
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).recordRebalancingMove(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status Conflict but got %v", rr.Code)
+	assert.Equal(t, "Bikes must be docked at the origin station and not reserved\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordRebalancingMove_DestinationFilledMeanwhile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+	toStationID := "5b2e8f1c-9a3d-4c7e-8f6a-1d2c3b4a5e6f"
+	bikeIDs := []string{"331e7ffb-e583-4535-ba41-4c28dc34016d", "e4ef2d9b-5d5a-4f85-bb3a-b2df8bf42ac1"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM \\( SELECT 1 FROM docks (.+) FOR UPDATE \\) AS docked").
+		WithArgs(stationID, pq.Array(bikeIDs)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	mock.ExpectQuery("SELECT \\(SELECT COUNT\\(\\*\\) FROM docks (.+)\\) FROM stations s WHERE s.id = \\$1").
+		WithArgs(toStationID).
+		WillReturnRows(sqlmock.NewRows([]string{"free"}).AddRow(2))
+
+	// The first bike is moved
+	mock.ExpectQuery("UPDATE docks SET state = 'free', bike_id = NULL, updated_at = \\$2 WHERE bike_id = \\$1 RETURNING slot").
+		WithArgs(bikeIDs[0], fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(1))
+	mock.ExpectQuery("UPDATE docks SET state = 'occupied', (.+) RETURNING slot").
+		WithArgs(toStationID, bikeIDs[0], fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(4))
+	mock.ExpectExec("UPDATE bikes SET station_id = \\$1 WHERE id = \\$2").
+		WithArgs(toStationID, bikeIDs[0]).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO rebalancing_moves").
+		WithArgs(bikeIDs[0], stationID, toStationID, supervisorUUID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	// A bike returned meanwhile took the last free dock: nothing is moved
+	mock.ExpectQuery("UPDATE docks SET state = 'free', bike_id = NULL, updated_at = \\$2 WHERE bike_id = \\$1 RETURNING slot").
+		WithArgs(bikeIDs[1], fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(2))
+	mock.ExpectQuery("UPDATE docks SET state = 'occupied', (.+) RETURNING slot").
+		WithArgs(toStationID, bikeIDs[1], fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}))
+	mock.ExpectRollback()
+
+	reqBody := `{"from_station_id":"` + stationID + `","to_station_id":"` + toStationID + `","bike_ids":["` + strings.Join(bikeIDs, `","`) + `"]}`
+	req := httptest.NewRequest(http.MethodPost, "/rebalancing/moves", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req = asOperator(req, supervisorUUID)
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).recordRebalancingMove(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status Conflict but got %v", rr.Code)
+	assert.Equal(t, "Station does not have enough free dock slots\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
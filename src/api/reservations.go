@@ -0,0 +1,183 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lib/pq"
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+// defaultReservationHold is how long a bike is held when not configured
+const defaultReservationHold = 15 * time.Minute
+
+// ReservationConfig holds the [api.reservations] section of the
+// configuration file
+type ReservationConfig struct {
+	Hold time.Duration `toml:"hold"` // how long a bike is held for the user, e.g. "15m"
+}
+
+func (config *ReservationConfig) hold() time.Duration {
+	if config == nil || config.Hold <= 0 {
+		return defaultReservationHold
+	}
+	return config.Hold
+}
+
+type CreateReservationRequest struct {
+	UserUUID  string `json:"user_uuid"`
+	StationID string `json:"station_id,omitempty"` // restricts the bikes to the ones parked at this station
+}
+
+func (req *CreateReservationRequest) Validate() []FieldError {
+	errs := checkUUID(nil, "user_uuid", req.UserUUID)
+	errs = checkOptionalUUID(errs, "station_id", req.StationID)
+	return errs
+}
+
+type CancelReservationRequest struct {
+	UserUUID string `json:"user_uuid"`
+}
+
+func (req *CancelReservationRequest) Validate() []FieldError {
+	return checkUUID(nil, "user_uuid", req.UserUUID)
+}
+
+// isUniqueViolation tells whether an error comes from a unique index
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
+
+// createReservation holds the least used available bike for a user until
+// they tap their card, or the reservation expires. Like assignBike, it
+// requires the minimum balance of the wallet configuration.
+func (s *Server) createReservation(w http.ResponseWriter, r *http.Request) {
+	// Parse and validate the JSON request body
+	var req CreateReservationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// Fetch the user and check they can rent a bike
+	now := s.Clock.Now()
+	user, ok := checkRenter(w, r, s.DB, req.UserUUID, now)
+	if !ok {
+		return
+	}
+
+	// A user who cannot ride must not keep a bike from others
+	if !checkBalance(w, r, s.DB, user.ID, &s.Config.Wallet) {
+		return
+	}
+
+	// Check if the user already holds a bike
+	var existingReservation models.Reservation
+	query := "SELECT id FROM reservations WHERE user_id = $1 AND status = 'active'"
+	if err := s.DB.QueryRowContext(r.Context(), query, user.ID).Scan(&existingReservation.ID); err == nil {
+		http.Error(w, "User already has an active reservation", http.StatusBadRequest)
+		return
+	} else if err != sql.ErrNoRows {
+		http.Error(w, "Failed to check user reservations", http.StatusInternalServerError)
+		return
+	}
+
+	// Hold the least used available bike
+	bike, err := leastUsedAvailableBike(r.Context(), s.DB, now, req.StationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "No available bikes", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch bike", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	reservation := models.Reservation{
+		UserID:     user.ID,
+		BikeID:     bike.ID,
+		Status:     models.ReservationActive,
+		ReservedAt: now,
+		ExpiresAt:  now.Add(s.Config.Reservations.hold()),
+	}
+	query = `INSERT INTO reservations (user_id, bike_id, status, reserved_at, expires_at, created_at)
+	         VALUES ($1, $2, $3, $4, $5, $4)
+	         RETURNING id`
+	if err := s.DB.QueryRowContext(r.Context(), query, reservation.UserID, reservation.BikeID, reservation.Status, reservation.ReservedAt, reservation.ExpiresAt).Scan(&reservation.ID); err != nil {
+		// The bike, or a bike for this user, was reserved in the meantime
+		if isUniqueViolation(err) {
+			http.Error(w, "Bike was reserved concurrently, please retry", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to create reservation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.recordAudit(r, audit.Event{
+		Actor:      audit.ActorFromRequest(r, user.ID),
+		Action:     audit.ActionReservationCreated,
+		EntityType: audit.EntityReservation,
+		EntityID:   fmt.Sprint(reservation.ID),
+		After:      reservation,
+	})
+
+	// Respond with the reservation in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(reservation); err != nil {
+		http.Error(w, "Failed to encode reservation to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// cancelReservation releases the bike held by an active reservation
+func (s *Server) cancelReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeValidationError(w, http.StatusBadRequest, "Invalid request", FieldError{Field: "id", Message: "must be a reservation ID"})
+		return
+	}
+
+	// Parse and validate the JSON request body
+	var req CancelReservationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// Only the user holding the reservation can cancel it
+	query := "UPDATE reservations SET status = 'cancelled', updated_at = $1 WHERE id = $2 AND user_id = $3 AND status = 'active'"
+	result, err := s.DB.ExecContext(r.Context(), query, s.Clock.Now(), id, req.UserUUID)
+	if err != nil {
+		http.Error(w, "Failed to cancel reservation", http.StatusInternalServerError)
+		return
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		http.Error(w, "Active reservation not found", http.StatusNotFound)
+		return
+	}
+
+	// Turning down a bike held from the waitlist leaves the queue
+	query = "UPDATE waitlist_entries SET status = 'left', updated_at = $1 WHERE reservation_id = $2 AND status = 'offered'"
+	if _, err := s.DB.ExecContext(r.Context(), query, s.Clock.Now(), id); err != nil {
+		s.Logger().Err(err).Ctx(r.Context()).Uint64("reservation", id).Msg("Failed to update waitlist entry")
+	}
+
+	s.recordAudit(r, audit.Event{
+		Actor:      audit.ActorFromRequest(r, req.UserUUID),
+		Action:     audit.ActionReservationCancelled,
+		EntityType: audit.EntityReservation,
+		EntityID:   fmt.Sprint(id),
+		Before:     map[string]interface{}{"status": models.ReservationActive},
+		After:      map[string]interface{}{"status": models.ReservationCancelled},
+	})
+
+	// Respond with success
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Reservation cancelled successfully"))
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func TestCreateReservation_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(2000))
+
+	mock.ExpectQuery("SELECT id FROM reservations WHERE user_id = \\$1 AND status = 'active'").
+		WithArgs(userUUID).
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id FROM bikes WHERE (.+) ORDER BY usage_count, id LIMIT 1").
+		WithArgs(fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned", "available_from", "station_id"}).AddRow(bikeID, false, 3, nil, fixedTime.Add(-time.Hour), stationID))
+
+	mock.ExpectQuery("INSERT INTO reservations \\(user_id, bike_id, status, reserved_at, expires_at, created_at\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$4\\) RETURNING id").
+		WithArgs(userUUID, bikeID, "active", fixedTime, fixedTime.Add(10*time.Minute)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs("user", userUUID, "reservation.created", "reservation", "7", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/reservations", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Reservations: ReservationConfig{Hold: 10 * time.Minute}, Wallet: WalletConfig{MinimumBalance: 500}}).createReservation(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code, "Expected status Created but got %v", rr.Code)
+
+	var reservation models.Reservation
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&reservation))
+	assert.Equal(t, uint(7), reservation.ID)
+	assert.Equal(t, bikeID, reservation.BikeID)
+	assert.True(t, reservation.ExpiresAt.Equal(fixedTime.Add(10*time.Minute)))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateReservation_InsufficientBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	// No bike is held for a user who cannot ride it
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(300))
+
+	req := httptest.NewRequest(http.MethodPost, "/reservations", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Reservations: ReservationConfig{Hold: 10 * time.Minute}, Wallet: WalletConfig{MinimumBalance: 500}}).createReservation(rr, req)
+
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+	assert.Equal(t, "Insufficient wallet balance\n", rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssignBike_ConvertsReservation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bikeID := "e4ef2d9b-5d5a-4f85-bb3a-b2df8bf42ac1"
+
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+
+	// The reserved bike is handed over instead of the least used one
+	mock.ExpectQuery("SELECT id, bike_id FROM reservations WHERE user_id = \\$1 AND status = 'active' AND expires_at > \\$2").
+		WithArgs(userUUID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "bike_id"}).AddRow(7, bikeID))
+
+	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned, station_id FROM bikes WHERE id = \\$1").
+		WithArgs(bikeID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned", "station_id"}).AddRow(bikeID, false, 4, nil, stationID))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE bikes SET is_assigned = true, usage_count = usage_count \\+ 1 WHERE id = \\$1 (.+) RETURNING usage_count").
+		WithArgs(bikeID, userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"usage_count"}).AddRow(5))
+
+	mock.ExpectQuery("UPDATE docks SET state = 'free', bike_id = NULL, updated_at = \\$2 WHERE bike_id = \\$1 RETURNING slot").
+		WithArgs(bikeID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(2))
+
+	mock.ExpectExec("INSERT INTO assignments").
+		WithArgs(userUUID, bikeID, fixedTime, stationID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec("UPDATE reservations SET status = 'converted', updated_at = \\$1 WHERE id = \\$2").
+		WithArgs(fixedTime, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// A bike held from the waitlist is claimed
+	mock.ExpectExec("UPDATE waitlist_entries SET status = CASE WHEN status = 'offered' AND reservation_id = \\$2 THEN 'claimed' ELSE 'left' END").
+		WithArgs(userUUID, 7, fixedTime).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs("user", userUUID, "bike.assigned", "bike", bikeID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs("user", userUUID, "reservation.converted", "reservation", "7", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/bikes/assign", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Wallet: WalletConfig{MinimumBalance: 500}}).assignBike(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+	assert.Equal(t, "Bike assigned successfully", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCancelReservation_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	// The reservation belongs to someone else, or is no longer active
+	mock.ExpectExec("UPDATE reservations SET status = 'cancelled', updated_at = \\$1 WHERE id = \\$2 AND user_id = \\$3 AND status = 'active'").
+		WithArgs(fixedTime, 7, userUUID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	req := httptest.NewRequest(http.MethodPost, "/reservations/7/cancel", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "7")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).cancelReservation(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status Not Found but got %v", rr.Code)
+	assert.Equal(t, "Active reservation not found\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
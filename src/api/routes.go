@@ -0,0 +1,108 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/pricing"
+)
+
+// Config holds the [api] section of the configuration file
+type Config struct {
+	// LegacySunset is the date after which the unversioned routes will be
+	// removed, advertised in their Sunset header
+	LegacySunset time.Time `toml:"legacy_sunset"`
+
+	Reservations ReservationConfig `toml:"reservations"`
+	Pricing      pricing.Tariff    `toml:"pricing"`
+	Wallet       WalletConfig      `toml:"wallet"`
+}
+
+// Mount registers every version of the API on the router. The v1 routes
+// are also served unversioned, as deprecated aliases, for the docking
+// stations not upgraded yet, the admin routes excepted. A v2 gets its own RegisterV2, reusing the
+// handlers whose behaviour did not change.
+func (s *Server) Mount(r chi.Router) {
+	r.Route("/v1", func(r chi.Router) {
+		s.RegisterV1(r)
+		s.RegisterAdmin(r)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(Deprecated(s.Config.LegacySunset, "/v1"))
+		s.RegisterV1(r)
+	})
+}
+
+// RegisterV1 registers the v1 routes on the router
+func (s *Server) RegisterV1(r chi.Router) {
+	r.Get("/assignments", s.getAllAssignments)
+	r.Post("/bikes/assign", s.assignBike)
+	r.Post("/bikes/unassign", s.unassignBike)
+	r.Get("/bikes/available", s.getAvailableBikes)
+	r.Get("/bikes", s.getAllBikes)
+	r.Post("/bikes/{id}/report", s.declareBikeLost)
+	r.Post("/bikes/{id}/recover", s.recoverBike)
+
+	r.Post("/reservations", s.createReservation)
+	r.Post("/reservations/{id}/cancel", s.cancelReservation)
+
+	r.Get("/stations/{id}/occupancy", s.getStationOccupancy)
+	r.Get("/stations/{id}/waitlist", s.getWaitlist)
+	r.Post("/stations/{id}/waitlist", s.joinWaitlist)
+	r.Post("/stations/{id}/waitlist/leave", s.leaveWaitlist)
+
+	r.Get("/rebalancing/plan", s.getRebalancingPlan)
+	r.Get("/rebalancing/moves", s.getRebalancingMoves)
+	r.Post("/rebalancing/moves", s.recordRebalancingMove)
+
+	r.Get("/tasks", s.getSupervisorTasks)
+	r.Post("/tasks/{id}/close", s.closeSupervisorTask)
+
+	r.Get("/users/{id}/wallet", s.getWalletBalance)
+	r.Get("/users/{id}/wallet/statement", s.getWalletStatement)
+	r.Post("/users/{id}/wallet/top-ups", s.topUpWallet)
+	r.Post("/users/{id}/wallet/refunds", s.refundWallet)
+
+	r.Get("/plans", s.getPlans)
+	r.Get("/users/{id}/subscription", s.getSubscription)
+	r.Post("/users/{id}/subscription", s.subscribe)
+	r.Post("/users/{id}/subscription/cancel", s.cancelSubscription)
+
+	r.Get("/users/{id}/receipts", s.getReceipts)
+	r.Get("/users/{id}/receipts/{assignment_id}", s.getReceipt)
+	r.Get("/users/{id}/invoices", s.getInvoices)
+	r.Get("/users/{id}/invoices/{number}", s.getInvoice)
+
+	r.Get("/audit", s.getAuditEvents)
+}
+
+// RegisterAdmin registers the routes operating the server itself. They are
+// restricted to supervisors and not served unversioned.
+func (s *Server) RegisterAdmin(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(supervisorsOnly)
+		r.Get("/admin/log-level", logger.GetLevels)
+		r.Put("/admin/log-level", logger.UpdateLevel)
+	})
+}
+
+// Deprecated flags the responses of the routes it wraps as deprecated
+// (RFC 9745), with their sunset date (RFC 8594) when known and a link to
+// the same route under the successor version prefix
+func Deprecated(sunset time.Time, successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", successor, r.URL.Path))
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+)
+
+func TestMount_LegacyRoutesAreDeprecated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sunset := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	r := chi.NewRouter()
+	server := testServer(db, clock.Real{}, &Config{LegacySunset: sunset})
+	server.Mount(r)
+
+	tests := []struct {
+		path       string
+		deprecated bool
+	}{
+		{path: "/v1/bikes/assign", deprecated: false},
+		{path: "/bikes/assign", deprecated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			// An invalid payload is rejected before reaching the database
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(`{}`))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			// Both routes are served by the same handler
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+			if tt.deprecated {
+				assert.Equal(t, "true", rr.Header().Get("Deprecation"))
+				assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", rr.Header().Get("Sunset"))
+				assert.Equal(t, `</v1/bikes/assign>; rel="successor-version"`, rr.Header().Get("Link"))
+			} else {
+				assert.Empty(t, rr.Header().Get("Deprecation"))
+				assert.Empty(t, rr.Header().Get("Sunset"))
+			}
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterAdmin_RestrictedToSupervisors(t *testing.T) {
+	ts, mock := newTestServer(t, clock.Real{})
+
+	customerUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+
+	tests := []struct {
+		name     string
+		path     string
+		operator string
+		role     string
+		status   int
+	}{
+		{name: "anonymous", path: "/v1/admin/log-level", status: http.StatusUnauthorized},
+		{name: "customer", path: "/v1/admin/log-level", operator: customerUUID, role: "Customer", status: http.StatusForbidden},
+		{name: "supervisor", path: "/v1/admin/log-level", operator: supervisorUUID, role: "Supervisor", status: http.StatusOK},
+		{name: "unversioned", path: "/admin/log-level", operator: supervisorUUID, role: "Supervisor", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.operator != "" {
+				mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+					WithArgs(tt.operator).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(tt.operator, tt.role))
+			}
+
+			// Resetting a level it does not override leaves the logger as is
+			req, err := http.NewRequest(http.MethodPut, ts.URL+tt.path, strings.NewReader(`{"package":"api"}`))
+			if err != nil {
+				t.Fatalf("Failed to build the request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if tt.operator != "" {
+				req.Header.Set("X-Operator-UUID", tt.operator)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call the API: %v", err)
+			}
+			resp.Body.Close()
+
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/openapi"
+	"github.com/yourusername/bike-rental/src/tracing"
+)
+
+// Server holds the dependencies of the HTTP API, its handlers being its
+// methods, so the whole API can be built in tests with a mock database, a
+// fake clock and discarded logs and metrics.
+type Server struct {
+	DB      *sql.DB
+	Clock   clock.Clock
+	Config  *Config
+	Logger  func() *zerolog.Logger // read on every use, so that level changes apply at runtime
+	Metrics *Metrics
+
+	validator func(http.Handler) http.Handler
+}
+
+// NewServer returns a server for the API of config, with the request
+// validator built from the OpenAPI document
+func NewServer(db *sql.DB, clk clock.Clock, config *Config, log func() *zerolog.Logger, metrics *Metrics) (*Server, error) {
+	spec, err := openapi.Load()
+	if err != nil {
+		return nil, err
+	}
+	validator, err := openapi.NewValidator(spec, rejectRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{DB: db, Clock: clk, Config: config, Logger: log, Metrics: metrics, validator: validator}, nil
+}
+
+// Routes returns the router of the whole API, middlewares included
+func (s *Server) Routes() http.Handler {
+	r := chi.NewRouter()
+	// Request IDs are recorded in the audit log
+	r.Use(middleware.RequestID)
+	// Tracing comes first so request logs carry the trace ID
+	r.Use(tracing.Middleware)
+	r.Use(logger.LoggerMiddleware)
+	// Reject requests not matching the OpenAPI document before they reach
+	// Postgres, their bodies being capped first as the validator reads them
+	r.Use(limitBody)
+	r.Use(s.validator)
+	// Operators are verified before the handlers and the audit log trust them
+	r.Use(s.authenticateOperator)
+
+	r.Get("/openapi.json", openapi.ServeSpec)
+	r.Get("/docs", openapi.ServeSwaggerUI)
+
+	// The API routes, under /v1 and as deprecated unversioned aliases
+	s.Mount(r)
+
+	return r
+}
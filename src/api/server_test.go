@@ -0,0 +1,156 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// testServer returns a server of config on db and clk, for the handlers to
+// be called directly. Its logs and metrics are discarded.
+func testServer(db *sql.DB, clk clock.Clock, config *Config) *Server {
+	return &Server{DB: db, Clock: clk, Config: config, Logger: discardLogs, Metrics: discardMetrics()}
+}
+
+func discardLogs() *zerolog.Logger {
+	logger := zerolog.Nop()
+	return &logger
+}
+
+func discardMetrics() *Metrics {
+	metrics, err := NewMetrics(noop.Meter{})
+	if err != nil {
+		panic(err)
+	}
+	return metrics
+}
+
+// newTestServer serves the whole API, middlewares included, from a mock
+// database and a fake clock
+func newTestServer(t *testing.T, clk clock.Clock) (*httptest.Server, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	server, err := NewServer(db, clk, &Config{}, discardLogs, discardMetrics())
+	if err != nil {
+		t.Fatalf("Failed to build the server: %v", err)
+	}
+
+	ts := httptest.NewServer(server.Routes())
+	t.Cleanup(ts.Close)
+	return ts, mock
+}
+
+func TestServer_GetAvailableBikes(t *testing.T) {
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	ts, mock := newTestServer(t, clock.NewFake(fixedTime))
+
+	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id FROM bikes WHERE (.+)").
+		WithArgs(fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned", "available_from", "station_id"}).
+			AddRow("331e7ffb-e583-4535-ba41-4c28dc34016d", false, 3, nil, fixedTime.Add(-time.Hour), "7c1a3b5e-2f4d-4e6a-9b8c-0d1e2f3a4b5c"))
+
+	resp, err := http.Get(ts.URL + "/v1/bikes/available")
+	if err != nil {
+		t.Fatalf("Failed to call the API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var bikes []models.Bike
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&bikes))
+	assert.Len(t, bikes, 1)
+	assert.Equal(t, 3, bikes[0].UsageCount)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestServer_RejectsInvalidRequests(t *testing.T) {
+	ts, mock := newTestServer(t, clock.Real{})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		expected    ValidationError
+	}{
+		{
+			name:        "malformed UUID",
+			contentType: "application/json",
+			body:        `{"user_uuid":"not-a-uuid"}`,
+			status:      http.StatusBadRequest,
+			expected:    ValidationError{Error: "Invalid request payload", Fields: []FieldError{{Field: "user_uuid", Message: "must be a UUID"}}},
+		},
+		{
+			name:        "unknown field",
+			contentType: "application/json",
+			body:        `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96","bike_uuid":"331e7ffb-e583-4535-ba41-4c28dc34016d"}`,
+			status:      http.StatusBadRequest,
+			expected:    ValidationError{Error: "Invalid request payload", Fields: []FieldError{{Field: "bike_uuid", Message: "is not allowed"}}},
+		},
+		{
+			name:        "wrong content type",
+			contentType: "text/plain",
+			body:        `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96"}`,
+			status:      http.StatusUnsupportedMediaType,
+			expected:    ValidationError{Error: "Content-Type must be application/json"},
+		},
+		{
+			name:        "oversized body",
+			contentType: "application/json",
+			body:        `{"user_uuid":"` + strings.Repeat("a", maxBodyBytes) + `"}`,
+			status:      http.StatusRequestEntityTooLarge,
+			expected:    ValidationError{Error: "Request body must not exceed 16384 bytes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The OpenAPI validator answers before any handler runs
+			resp, err := http.Post(ts.URL+"/v1/bikes/assign", tt.contentType, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Failed to call the API: %v", err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+			var body ValidationError
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tt.expected, body)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestServer_ServesOpenAPIDocument(t *testing.T) {
+	ts, _ := newTestServer(t, clock.Real{})
+
+	resp, err := http.Get(ts.URL + "/openapi.json")
+	if err != nil {
+		t.Fatalf("Failed to call the API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+}
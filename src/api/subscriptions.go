@@ -0,0 +1,181 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/subscriptions"
+)
+
+// getPlans lists the subscription plans, cheapest first
+func (s *Server) getPlans(w http.ResponseWriter, r *http.Request) {
+	query := "SELECT id, name, period, price_cents, included_minutes, max_rentals FROM plans ORDER BY price_cents, id"
+	rows, err := s.DB.QueryContext(r.Context(), query)
+	if err != nil {
+		http.Error(w, "Failed to retrieve plans", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	plans := []models.Plan{}
+	for rows.Next() {
+		var plan models.Plan
+		if err := rows.Scan(&plan.ID, &plan.Name, &plan.Period, &plan.PriceCents, &plan.IncludedMinutes, &plan.MaxRentals); err != nil {
+			http.Error(w, "Failed to scan plan", http.StatusInternalServerError)
+			return
+		}
+		plans = append(plans, plan)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the plans in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(plans); err != nil {
+		http.Error(w, "Failed to encode plans to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getSubscription returns the active subscription of a user
+func (s *Server) getSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	var subscription models.Subscription
+	query := "SELECT id, user_id, plan_id, status, auto_renew, started_at, expires_at FROM subscriptions WHERE user_id = $1 AND status = 'active'"
+	if err := s.DB.QueryRowContext(r.Context(), query, userID).Scan(&subscription.ID, &subscription.UserID, &subscription.PlanID, &subscription.Status, &subscription.AutoRenew, &subscription.StartedAt, &subscription.ExpiresAt); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "No active subscription", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch subscription", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Respond with the subscription in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(subscription); err != nil {
+		http.Error(w, "Failed to encode subscription to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+type SubscribeRequest struct {
+	PlanID    string `json:"plan_id"`
+	AutoRenew *bool  `json:"auto_renew,omitempty"` // renewed at the end of every period unless false
+}
+
+func (req *SubscribeRequest) Validate() []FieldError {
+	if req.PlanID == "" {
+		return []FieldError{{Field: "plan_id", Message: "is required"}}
+	}
+	return nil
+}
+
+// subscribe starts a subscription for a user, the first period being paid
+// from their wallet
+func (s *Server) subscribe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	// Parse and validate the JSON request body
+	var req SubscribeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	var plan models.Plan
+	query := "SELECT id, name, period, price_cents, included_minutes, max_rentals FROM plans WHERE id = $1"
+	if err := s.DB.QueryRowContext(r.Context(), query, req.PlanID).Scan(&plan.ID, &plan.Name, &plan.Period, &plan.PriceCents, &plan.IncludedMinutes, &plan.MaxRentals); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Plan not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch plan", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	actor := audit.ActorFromRequest(r, userID)
+	subscription := models.Subscription{
+		UserID:    userID,
+		AutoRenew: req.AutoRenew == nil || *req.AutoRenew,
+		StartedAt: s.Clock.Now(),
+	}
+	if err := subscriptions.Subscribe(r.Context(), s.DB, &subscription, plan, actor.ID); err != nil {
+		switch {
+		case err == subscriptions.ErrInsufficientBalance:
+			http.Error(w, "Insufficient wallet balance", http.StatusPaymentRequired)
+		case isUniqueViolation(err):
+			http.Error(w, "User already has an active subscription", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to create subscription", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.recordAudit(r, audit.Event{
+		Actor:      actor,
+		Action:     audit.ActionSubscriptionCreated,
+		EntityType: audit.EntitySubscription,
+		EntityID:   fmt.Sprint(subscription.ID),
+		After:      subscription,
+	})
+
+	// Respond with the subscription in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(subscription); err != nil {
+		http.Error(w, "Failed to encode subscription to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// cancelSubscription stops the renewals of the active subscription of a
+// user, which stays active until the end of the period paid
+func (s *Server) cancelSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	var subscription models.Subscription
+	query := `UPDATE subscriptions SET auto_renew = false, updated_at = $2
+	          WHERE user_id = $1 AND status = 'active'
+	          RETURNING id, user_id, plan_id, status, auto_renew, started_at, expires_at`
+	if err := s.DB.QueryRowContext(r.Context(), query, userID, s.Clock.Now()).Scan(&subscription.ID, &subscription.UserID, &subscription.PlanID, &subscription.Status, &subscription.AutoRenew, &subscription.StartedAt, &subscription.ExpiresAt); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "No active subscription", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to cancel subscription", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.recordAudit(r, audit.Event{
+		Actor:      audit.ActorFromRequest(r, userID),
+		Action:     audit.ActionSubscriptionCancelled,
+		EntityType: audit.EntitySubscription,
+		EntityID:   fmt.Sprint(subscription.ID),
+		After:      map[string]interface{}{"auto_renew": false, "expires_at": subscription.ExpiresAt},
+	})
+
+	// Respond with the subscription in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(subscription); err != nil {
+		http.Error(w, "Failed to encode subscription to JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func TestSubscribe(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM users WHERE id = \\$1\\)").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	mock.ExpectQuery("SELECT id, name, period, price_cents, included_minutes, max_rentals FROM plans WHERE id = \\$1").
+		WithArgs("monthly").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "period", "price_cents", "included_minutes", "max_rentals"}).AddRow("monthly", "Monthly pass", "month", 1990, 45, 1))
+
+	// The subscription and its first month are recorded together
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO subscriptions \\(user_id, plan_id, status, auto_renew, started_at, expires_at, created_at\\)").
+		WithArgs(userUUID, "monthly", "active", false, fixedTime, fixedTime.AddDate(0, 1, 0)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(2000))
+	mock.ExpectQuery("INSERT INTO wallet_accounts \\(user_id\\)").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("SELECT id FROM wallet_accounts WHERE name = \\$1").
+		WithArgs("revenue").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery("INSERT INTO wallet_transactions").
+		WithArgs("subscription", nil, "Monthly pass from 2024-08-21 to 2024-09-21", userUUID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO wallet_entries").
+		WithArgs(int64(9), int64(3), int64(-1990), int64(2), int64(1990)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs("user", userUUID, "subscription.created", "subscription", "4", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/users/"+userUUID+"/subscription", strings.NewReader(`{"plan_id":"monthly","auto_renew":false}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).subscribe(rr, withUser(req, userUUID))
+
+	assert.Equal(t, http.StatusCreated, rr.Code, "Expected status Created but got %v", rr.Code)
+
+	var subscription models.Subscription
+	err = json.NewDecoder(rr.Body).Decode(&subscription)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(4), subscription.ID)
+	assert.Equal(t, models.SubscriptionActive, subscription.Status)
+	assert.False(t, subscription.AutoRenew)
+	assert.Equal(t, fixedTime.AddDate(0, 1, 0), subscription.ExpiresAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscribe_InsufficientBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC))
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM users WHERE id = \\$1\\)").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	mock.ExpectQuery("SELECT id, name, period, price_cents, included_minutes, max_rentals FROM plans WHERE id = \\$1").
+		WithArgs("annual").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "period", "price_cents", "included_minutes", "max_rentals"}).AddRow("annual", "Annual pass", "year", 19900, 45, 2))
+
+	// Nothing is kept when the wallet cannot pay
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO subscriptions").
+		WithArgs(userUUID, "annual", "active", true, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(2000))
+	mock.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/users/"+userUUID+"/subscription", strings.NewReader(`{"plan_id":"annual"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).subscribe(rr, withUser(req, userUUID))
+
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code, "Expected status Payment Required but got %v", rr.Code)
+	assert.Equal(t, "Insufficient wallet balance\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssignBike_PlanRentalCap(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	// The annual pass allows two bikes at once, both already ridden
+	mock.ExpectQuery("SELECT p.id, p.name, p.period, p.price_cents, p.included_minutes, p.max_rentals FROM subscriptions s").
+		WithArgs(userUUID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "period", "price_cents", "included_minutes", "max_rentals"}).AddRow("annual", "Annual pass", "year", 19900, 45, 2))
+
+	req := httptest.NewRequest(http.MethodPost, "/bikes/assign", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Wallet: WalletConfig{MinimumBalance: 500}}).assignBike(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status Bad Request but got %v", rr.Code)
+	assert.Equal(t, "User already rides the 2 bikes of their plan\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+// maxNoteLength caps the note left when closing a task
+const maxNoteLength = 1000
+
+const taskColumns = "id, kind, status, bike_id, assignment_id, user_id, note, created_at, closed_at, closed_by"
+
+func scanTask(row interface{ Scan(...interface{}) error }, task *models.SupervisorTask) error {
+	return row.Scan(&task.ID, &task.Kind, &task.Status, &task.BikeID, &task.AssignmentID, &task.UserID, &task.Note, &task.CreatedAt, &task.ClosedAt, &task.ClosedBy)
+}
+
+// getSupervisorTasks lists the tasks of the supervisors, the open ones by
+// default, oldest first
+func (s *Server) getSupervisorTasks(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireSupervisor(w, r); !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = models.TaskOpen
+	}
+	if status != models.TaskOpen && status != models.TaskDone {
+		writeValidationError(w, http.StatusBadRequest, "Invalid request", FieldError{Field: "status", Message: "must be open or done"})
+		return
+	}
+
+	query := "SELECT " + taskColumns + " FROM supervisor_tasks WHERE status = $1 ORDER BY created_at, id LIMIT 500"
+	rows, err := s.DB.QueryContext(r.Context(), query, status)
+	if err != nil {
+		http.Error(w, "Failed to retrieve tasks", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.SupervisorTask{}
+	for rows.Next() {
+		var task models.SupervisorTask
+		if err := scanTask(rows, &task); err != nil {
+			http.Error(w, "Failed to scan task", http.StatusInternalServerError)
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the tasks in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tasks); err != nil {
+		http.Error(w, "Failed to encode tasks to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+type CloseTaskRequest struct {
+	Note string `json:"note,omitempty"`
+}
+
+func (req *CloseTaskRequest) Validate() []FieldError {
+	if len(req.Note) > maxNoteLength {
+		return []FieldError{{Field: "note", Message: fmt.Sprintf("must not exceed %d characters", maxNoteLength)}}
+	}
+	return nil
+}
+
+// closeSupervisorTask marks a task done, e.g. once the customer of an
+// overdue bike was reached. The tasks of overdue bikes are also closed when
+// the bike is docked.
+func (s *Server) closeSupervisorTask(w http.ResponseWriter, r *http.Request) {
+	operator, ok := requireSupervisor(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeValidationError(w, http.StatusBadRequest, "Invalid request", FieldError{Field: "id", Message: "must be a positive integer"})
+		return
+	}
+
+	// Parse and validate the JSON request body
+	var req CloseTaskRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	var task models.SupervisorTask
+	query := `UPDATE supervisor_tasks SET status = 'done', closed_at = $2, closed_by = $3, note = COALESCE($4, note)
+	          WHERE id = $1 AND status = 'open'
+	          RETURNING ` + taskColumns
+	note := sql.NullString{String: req.Note, Valid: req.Note != ""}
+	if err := scanTask(s.DB.QueryRowContext(r.Context(), query, taskID, s.Clock.Now(), operator.ID, note), &task); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Open task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to close task", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.recordAudit(r, audit.Event{
+		Actor:      audit.ActorFromRequest(r, operator.ID),
+		Action:     audit.ActionTaskClosed,
+		EntityType: audit.EntityTask,
+		EntityID:   fmt.Sprint(task.ID),
+		Before:     map[string]interface{}{"status": models.TaskOpen},
+		After:      map[string]interface{}{"status": task.Status, "note": task.Note.String},
+	})
+
+	// Respond with the task in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(task); err != nil {
+		http.Error(w, "Failed to encode task to JSON", http.StatusInternalServerError)
+		return
+	}
+}
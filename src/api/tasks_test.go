@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func TestGetSupervisorTasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	// The open tasks are listed by default
+	columns := []string{"id", "kind", "status", "bike_id", "assignment_id", "user_id", "note", "created_at", "closed_at", "closed_by"}
+	mock.ExpectQuery("SELECT (.+) FROM supervisor_tasks WHERE status = \\$1 ORDER BY created_at, id").
+		WithArgs("open").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(7, "overdue_bike", "open", bikeID, 42, userUUID, "Bike not docked 24 hours after its assignment", time.Now(), nil, nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	req = asOperator(req, supervisorUUID)
+	rr := httptest.NewRecorder()
+
+	testServer(db, nil, &Config{}).getSupervisorTasks(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+
+	var tasks []models.SupervisorTask
+	err = json.NewDecoder(rr.Body).Decode(&tasks)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, models.TaskOverdueBike, tasks[0].Kind)
+	assert.Equal(t, int64(42), tasks[0].AssignmentID.Int64)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCloseSupervisorTask(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	columns := []string{"id", "kind", "status", "bike_id", "assignment_id", "user_id", "note", "created_at", "closed_at", "closed_by"}
+	mock.ExpectQuery("UPDATE supervisor_tasks SET status = 'done', closed_at = \\$2, closed_by = \\$3, note = COALESCE\\(\\$4, note\\) WHERE id = \\$1 AND status = 'open'").
+		WithArgs(uint64(7), fixedTime, supervisorUUID, "Customer reached, returning it tonight").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(7, "overdue_bike", "done", bikeID, 42, userUUID, "Customer reached, returning it tonight", fixedTime.Add(-time.Hour), fixedTime, supervisorUUID))
+
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs("operator", supervisorUUID, "task.closed", "supervisor_task", "7", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks/7/close", strings.NewReader(`{"note":"Customer reached, returning it tonight"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = asOperator(req, supervisorUUID)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "7")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).closeSupervisorTask(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+
+	var task models.SupervisorTask
+	err = json.NewDecoder(rr.Body).Decode(&task)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TaskDone, task.Status)
+	assert.Equal(t, supervisorUUID, task.ClosedBy.String)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/yourusername/bike-rental/src/openapi"
+)
+
+// maxBodyBytes caps the size of request bodies, the payloads of the API
+// being a handful of fields
+const maxBodyBytes = 16 << 10
+
+var uuidRegexp = regexp.MustCompile(openapi.UUIDPattern)
+
+// FieldError describes why a field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is the body of the responses to invalid requests
+type ValidationError struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// validator is implemented by the request payloads
+type validator interface {
+	Validate() []FieldError
+}
+
+func isUUID(value string) bool {
+	return uuidRegexp.MatchString(value)
+}
+
+// checkUUID appends an error if a required UUID field is missing or malformed
+func checkUUID(errs []FieldError, field, value string) []FieldError {
+	if value == "" {
+		return append(errs, FieldError{Field: field, Message: "is required"})
+	}
+	if !isUUID(value) {
+		return append(errs, FieldError{Field: field, Message: "must be a UUID"})
+	}
+	return errs
+}
+
+// checkOptionalUUID appends an error if an optional UUID field is malformed
+func checkOptionalUUID(errs []FieldError, field, value string) []FieldError {
+	if value != "" && !isUUID(value) {
+		return append(errs, FieldError{Field: field, Message: "must be a UUID"})
+	}
+	return errs
+}
+
+func writeValidationError(w http.ResponseWriter, status int, message string, fields ...FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ValidationError{Error: message, Fields: fields})
+}
+
+// rejectRequest writes the response to a request rejected by the OpenAPI
+// validator, in the format of the handlers' own validation errors
+func rejectRequest(w http.ResponseWriter, rejection openapi.Rejection) {
+	if rejection.Field == "" {
+		writeValidationError(w, rejection.Status, rejection.Message)
+		return
+	}
+	writeValidationError(w, rejection.Status, rejection.Message, FieldError{Field: rejection.Field, Message: rejection.Reason})
+}
+
+// limitBody caps the request bodies at maxBodyBytes, before the OpenAPI
+// validator reads them
+func limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decodeJSON strictly decodes a JSON request body into dst and validates it.
+// The body must be sent as application/json, stay under maxBodyBytes, hold
+// a single object without unknown fields and pass dst's own validation. It
+// writes the error response and returns false otherwise.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst validator) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeValidationError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return false
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeValidationError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", maxBodyBytes))
+		case errors.As(err, &typeErr):
+			writeValidationError(w, http.StatusBadRequest, "Invalid request payload",
+				FieldError{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()})
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+			writeValidationError(w, http.StatusBadRequest, "Invalid request payload",
+				FieldError{Field: field, Message: "is not allowed"})
+		default:
+			writeValidationError(w, http.StatusBadRequest, "Invalid request payload")
+		}
+		return false
+	}
+
+	// Only one JSON object is expected
+	if _, err := decoder.Token(); err != io.EOF {
+		writeValidationError(w, http.StatusBadRequest, "Request body must contain a single JSON object")
+		return false
+	}
+
+	if errs := dst.Validate(); len(errs) > 0 {
+		writeValidationError(w, http.StatusBadRequest, "Invalid request payload", errs...)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+)
+
+func TestPayloadValidation(t *testing.T) {
+	assignBike := func(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+		testServer(db, clock.Real{}, &Config{}).assignBike(w, r)
+	}
+
+	tests := []struct {
+		name        string
+		handler     func(http.ResponseWriter, *http.Request, *sql.DB)
+		contentType string
+		body        string
+		status      int
+		fields      []FieldError
+	}{
+		{
+			name:        "malformed user UUID",
+			handler:     assignBike,
+			contentType: "application/json",
+			body:        `{"user_uuid":"user-uuid-1"}`,
+			status:      http.StatusBadRequest,
+			fields:      []FieldError{{Field: "user_uuid", Message: "must be a UUID"}},
+		},
+		{
+			name:        "unknown field",
+			handler:     assignBike,
+			contentType: "application/json",
+			body:        `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96","bike_uuid":"331e7ffb-e583-4535-ba41-4c28dc34016d"}`,
+			status:      http.StatusBadRequest,
+			fields:      []FieldError{{Field: "bike_uuid", Message: "is not allowed"}},
+		},
+		{
+			name:        "wrong field type",
+			handler:     assignBike,
+			contentType: "application/json",
+			body:        `{"user_uuid":42}`,
+			status:      http.StatusBadRequest,
+			fields:      []FieldError{{Field: "user_uuid", Message: "must be a string"}},
+		},
+		{
+			name:        "trailing data",
+			handler:     assignBike,
+			contentType: "application/json",
+			body:        `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96"}{}`,
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "wrong content type",
+			handler:     assignBike,
+			contentType: "text/plain",
+			body:        `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96"}`,
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "oversized body",
+			handler:     assignBike,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"user_uuid":"` + strings.Repeat("a", maxBodyBytes) + `"}`,
+			status:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "missing fields",
+			handler: func(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+				testServer(db, clock.Real{}, &Config{}).unassignBike(w, r)
+			},
+			contentType: "application/json",
+			body:        `{"reason":"` + strings.Repeat("a", maxReasonLength+1) + `"}`,
+			status:      http.StatusBadRequest,
+			fields: []FieldError{
+				{Field: "bike_uuid", Message: "is required"},
+				{Field: "user_uuid", Message: "is required"},
+				{Field: "reason", Message: "must not exceed 500 characters"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No query is expected, invalid requests never reach the database
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Failed to create mock database: %v", err)
+			}
+			defer db.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/bikes/assign", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+
+			tt.handler(rr, req, db)
+
+			assert.Equal(t, tt.status, rr.Code, rr.Body.String())
+			assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+			var response ValidationError
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+			assert.NotEmpty(t, response.Error)
+			assert.Equal(t, tt.fields, response.Fields)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+type WaitlistRequest struct {
+	UserUUID string `json:"user_uuid"`
+}
+
+func (req *WaitlistRequest) Validate() []FieldError {
+	return checkUUID(nil, "user_uuid", req.UserUUID)
+}
+
+// stationFromURL reads and checks the station of the URL. It writes the
+// error response if it is malformed or unknown.
+func stationFromURL(w http.ResponseWriter, r *http.Request, db *sql.DB) (string, bool) {
+	stationID := chi.URLParam(r, "id")
+	if !isUUID(stationID) {
+		writeValidationError(w, http.StatusBadRequest, "Invalid request", FieldError{Field: "id", Message: "must be a UUID"})
+		return "", false
+	}
+
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM stations WHERE id = $1)"
+	if err := db.QueryRowContext(r.Context(), query, stationID).Scan(&exists); err != nil {
+		http.Error(w, "Failed to fetch station", http.StatusInternalServerError)
+		return "", false
+	}
+	if !exists {
+		http.Error(w, "Station not found", http.StatusNotFound)
+		return "", false
+	}
+
+	return stationID, true
+}
+
+// joinWaitlist queues a user at a station with no available bike. The
+// first user in the queue gets the next bike available there held for
+// them, see cronjobs.OfferBikesToWaitlist.
+func (s *Server) joinWaitlist(w http.ResponseWriter, r *http.Request) {
+	stationID, ok := stationFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	// Parse and validate the JSON request body
+	var req WaitlistRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// Fetch the user and check they can rent a bike
+	now := s.Clock.Now()
+	user, ok := checkRenter(w, r, s.DB, req.UserUUID, now)
+	if !ok {
+		return
+	}
+
+	// The bike held for the user will be paid from the wallet
+	if !checkBalance(w, r, s.DB, user.ID, &s.Config.Wallet) {
+		return
+	}
+
+	// A bike is held for the user already, the waitlist would hold another one
+	var reservationID uint
+	query := "SELECT id FROM reservations WHERE user_id = $1 AND status = 'active'"
+	if err := s.DB.QueryRowContext(r.Context(), query, user.ID).Scan(&reservationID); err == nil {
+		http.Error(w, "User already has an active reservation", http.StatusBadRequest)
+		return
+	} else if err != sql.ErrNoRows {
+		http.Error(w, "Failed to check user reservations", http.StatusInternalServerError)
+		return
+	}
+
+	// Waiting only makes sense when no bike can be taken right away
+	if _, err := leastUsedAvailableBike(r.Context(), s.DB, now, stationID); err == nil {
+		http.Error(w, "Bikes are available at this station", http.StatusConflict)
+		return
+	} else if err != sql.ErrNoRows {
+		http.Error(w, "Failed to fetch bike", http.StatusInternalServerError)
+		return
+	}
+
+	entry := models.WaitlistEntry{StationID: stationID, UserID: user.ID, Status: models.WaitlistWaiting, JoinedAt: now}
+	query = `INSERT INTO waitlist_entries (station_id, user_id, status, joined_at)
+	          VALUES ($1, $2, $3, $4)
+	          RETURNING id`
+	if err := s.DB.QueryRowContext(r.Context(), query, entry.StationID, entry.UserID, entry.Status, entry.JoinedAt).Scan(&entry.ID); err != nil {
+		if isUniqueViolation(err) {
+			http.Error(w, "User is already on a waitlist", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to join waitlist", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// The queue is first in, first out
+	query = `SELECT COUNT(*) FROM waitlist_entries
+	         WHERE station_id = $1 AND status = 'waiting' AND (joined_at, id) <= ($2, $3)`
+	if err := s.DB.QueryRowContext(r.Context(), query, entry.StationID, entry.JoinedAt, entry.ID).Scan(&entry.Position); err != nil {
+		http.Error(w, "Failed to compute waitlist position", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the entry in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		http.Error(w, "Failed to encode waitlist entry to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// leaveWaitlist removes a user from the queue of a station. A bike already
+// held for them is released.
+func (s *Server) leaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	stationID, ok := stationFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	// Parse and validate the JSON request body
+	var req WaitlistRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// The entry and the bike held for it are released together
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Failed to leave waitlist", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var entry models.WaitlistEntry
+	query := `UPDATE waitlist_entries SET status = 'left', updated_at = $1
+	          WHERE station_id = $2 AND user_id = $3 AND status IN ('waiting', 'offered')
+	          RETURNING id, reservation_id`
+	if err := tx.QueryRowContext(r.Context(), query, s.Clock.Now(), stationID, req.UserUUID).Scan(&entry.ID, &entry.ReservationID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "User is not on the waitlist of this station", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to leave waitlist", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if entry.ReservationID.Valid {
+		query = "UPDATE reservations SET status = 'cancelled', updated_at = $1 WHERE id = $2 AND status = 'active'"
+		if _, err := tx.ExecContext(r.Context(), query, s.Clock.Now(), entry.ReservationID.Int64); err != nil {
+			http.Error(w, "Failed to release held bike", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to leave waitlist", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with success
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Left waitlist successfully"))
+}
+
+// getWaitlist lists the users waiting at a station, in queue order
+func (s *Server) getWaitlist(w http.ResponseWriter, r *http.Request) {
+	stationID, ok := stationFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	query := `SELECT id, station_id, user_id, status, joined_at, reservation_id
+	          FROM waitlist_entries
+	          WHERE station_id = $1 AND status IN ('waiting', 'offered')
+	          ORDER BY joined_at, id`
+	rows, err := s.DB.QueryContext(r.Context(), query, stationID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve waitlist", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.WaitlistEntry{}
+	position := 0
+	for rows.Next() {
+		var entry models.WaitlistEntry
+		if err := rows.Scan(&entry.ID, &entry.StationID, &entry.UserID, &entry.Status, &entry.JoinedAt, &entry.ReservationID); err != nil {
+			http.Error(w, "Failed to scan waitlist entry", http.StatusInternalServerError)
+			return
+		}
+		if entry.Status == models.WaitlistWaiting {
+			position++
+			entry.Position = position
+		}
+		entries = append(entries, entry)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the waitlist in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		http.Error(w, "Failed to encode waitlist to JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+const stationID = "7c1a3b5e-2f4d-4e6a-9b8c-0d1e2f3a4b5c"
+
+func withStation(req *http.Request) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", stationID)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestJoinWaitlist_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+		WithArgs(stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+
+	mock.ExpectQuery("SELECT id FROM reservations WHERE user_id = \\$1 AND status = 'active'").
+		WithArgs(userUUID).
+		WillReturnError(sql.ErrNoRows)
+
+	// No bike can be taken at the station
+	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id FROM bikes WHERE (.+) AND station_id = \\$2 ORDER BY usage_count, id LIMIT 1").
+		WithArgs(fixedTime, stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned", "available_from", "station_id"}))
+
+	mock.ExpectQuery("INSERT INTO waitlist_entries \\(station_id, user_id, status, joined_at\\) VALUES \\(\\$1, \\$2, \\$3, \\$4\\) RETURNING id").
+		WithArgs(stationID, userUUID, "waiting", fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM waitlist_entries WHERE station_id = \\$1 AND status = 'waiting' AND \\(joined_at, id\\) <= \\(\\$2, \\$3\\)").
+		WithArgs(stationID, fixedTime, 12).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	req := httptest.NewRequest(http.MethodPost, "/stations/"+stationID+"/waitlist", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).joinWaitlist(rr, withStation(req))
+
+	assert.Equal(t, http.StatusCreated, rr.Code, "Expected status Created but got %v", rr.Code)
+
+	var entry models.WaitlistEntry
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&entry))
+	assert.Equal(t, uint(12), entry.ID)
+	assert.Equal(t, 3, entry.Position)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJoinWaitlist_InsufficientBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+		WithArgs(stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	// The user could not pay for the bike held for them
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(100))
+
+	req := httptest.NewRequest(http.MethodPost, "/stations/"+stationID+"/waitlist", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{Wallet: WalletConfig{MinimumBalance: 500}}).joinWaitlist(rr, withStation(req))
+
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code, "Expected status Payment Required but got %v", rr.Code)
+	assert.Equal(t, "Insufficient wallet balance\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJoinWaitlist_ActiveReservation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+		WithArgs(stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+
+	// A bike is held for the user at another station
+	mock.ExpectQuery("SELECT id FROM reservations WHERE user_id = \\$1 AND status = 'active'").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	req := httptest.NewRequest(http.MethodPost, "/stations/"+stationID+"/waitlist", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).joinWaitlist(rr, withStation(req))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "User already has an active reservation\n", rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJoinWaitlist_BikesAvailable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+		WithArgs(stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	mock.ExpectQuery("SELECT id, role FROM users WHERE id = \\$1").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(userUUID, "Customer"))
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM assignments WHERE user_id = \\$1 AND unassigned_at IS NULL").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_entries").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+
+	mock.ExpectQuery("SELECT id FROM reservations WHERE user_id = \\$1 AND status = 'active'").
+		WithArgs(userUUID).
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectQuery("SELECT id, is_assigned, usage_count, last_unassigned, available_from, station_id FROM bikes WHERE (.+) AND station_id = \\$2 ORDER BY usage_count, id LIMIT 1").
+		WithArgs(fixedTime, stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_assigned", "usage_count", "last_unassigned", "available_from", "station_id"}).AddRow(bikeID, false, 3, nil, fixedTime.Add(-time.Hour), stationID))
+
+	req := httptest.NewRequest(http.MethodPost, "/stations/"+stationID+"/waitlist", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).joinWaitlist(rr, withStation(req))
+
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected status Conflict but got %v", rr.Code)
+	assert.Equal(t, "Bikes are available at this station\n", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLeaveWaitlist_ReleasesHeldBike(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM stations WHERE id = \\$1\\)").
+		WithArgs(stationID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE waitlist_entries SET status = 'left', updated_at = \\$1 WHERE station_id = \\$2 AND user_id = \\$3 AND status IN \\('waiting', 'offered'\\) RETURNING id, reservation_id").
+		WithArgs(fixedTime, stationID, userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id"}).AddRow(12, 7))
+
+	mock.ExpectExec("UPDATE reservations SET status = 'cancelled', updated_at = \\$1 WHERE id = \\$2 AND status = 'active'").
+		WithArgs(fixedTime, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	req := httptest.NewRequest(http.MethodPost, "/stations/"+stationID+"/waitlist/leave", strings.NewReader(`{"user_uuid":"`+userUUID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).leaveWaitlist(rr, withStation(req))
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status OK but got %v", rr.Code)
+	assert.Equal(t, "Left waitlist successfully", rr.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
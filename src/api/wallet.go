@@ -0,0 +1,296 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/wallet"
+)
+
+// maxTopUpCents caps a single top-up
+const maxTopUpCents = 100000
+
+// Statement lines returned when not given
+const (
+	defaultStatementLimit = 50
+	maxStatementLimit     = 500
+)
+
+// WalletConfig holds the [api.wallet] section of the configuration file
+type WalletConfig struct {
+	MinimumBalance int64 `toml:"minimum_balance"` // in cents, required to be assigned a bike
+}
+
+func (config *WalletConfig) minimumBalance() int64 {
+	if config == nil {
+		return 0
+	}
+	return config.MinimumBalance
+}
+
+// checkBalance checks the wallet of a user holds the minimum balance of
+// config to rent a bike. It writes the error response otherwise.
+func checkBalance(w http.ResponseWriter, r *http.Request, db *sql.DB, userID string, config *WalletConfig) bool {
+	balance, err := wallet.Balance(r.Context(), db, userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch wallet balance", http.StatusInternalServerError)
+		return false
+	}
+	if balance < config.minimumBalance() {
+		http.Error(w, "Insufficient wallet balance", http.StatusPaymentRequired)
+		return false
+	}
+	return true
+}
+
+// userFromURL reads and checks the user of the URL. It writes the error
+// response if it is malformed or unknown.
+func userFromURL(w http.ResponseWriter, r *http.Request, db *sql.DB) (string, bool) {
+	userID := chi.URLParam(r, "id")
+	if !isUUID(userID) {
+		writeValidationError(w, http.StatusBadRequest, "Invalid request", FieldError{Field: "id", Message: "must be a UUID"})
+		return "", false
+	}
+
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)"
+	if err := db.QueryRowContext(r.Context(), query, userID).Scan(&exists); err != nil {
+		http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		return "", false
+	}
+	if !exists {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return "", false
+	}
+
+	return userID, true
+}
+
+// WalletBalance is the balance of a user wallet
+type WalletBalance struct {
+	UserID       string `json:"user_id"`
+	BalanceCents int64  `json:"balance_cents"`
+}
+
+// getWalletBalance returns the balance of a user wallet
+func (s *Server) getWalletBalance(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	balance, err := wallet.Balance(r.Context(), s.DB, userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch wallet balance", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the balance in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(WalletBalance{UserID: userID, BalanceCents: balance}); err != nil {
+		http.Error(w, "Failed to encode balance to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getWalletStatement lists the movements of a user wallet, most recent
+// first, with the balance after each of them
+func (s *Server) getWalletStatement(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	limit := defaultStatementLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxStatementLimit {
+			writeValidationError(w, http.StatusBadRequest, "Invalid request", FieldError{Field: "limit", Message: fmt.Sprintf("must be an integer between 1 and %d", maxStatementLimit)})
+			return
+		}
+		limit = parsed
+	}
+
+	query := `SELECT transaction_id, kind, amount_cents, balance_cents, assignment_id, description, created_at
+	          FROM (
+	              SELECT e.id, e.transaction_id, t.kind, e.amount_cents,
+	                     SUM(e.amount_cents) OVER (ORDER BY e.id) AS balance_cents,
+	                     t.assignment_id, t.description, t.created_at
+	              FROM wallet_entries e
+	              INNER JOIN wallet_accounts a ON a.id = e.account_id
+	              INNER JOIN wallet_transactions t ON t.id = e.transaction_id
+	              WHERE a.user_id = $1
+	          ) statement
+	          ORDER BY id DESC
+	          LIMIT $2`
+	rows, err := s.DB.QueryContext(r.Context(), query, userID, limit)
+	if err != nil {
+		http.Error(w, "Failed to retrieve wallet statement", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	lines := []models.WalletStatementLine{}
+	for rows.Next() {
+		var line models.WalletStatementLine
+		if err := rows.Scan(&line.TransactionID, &line.Kind, &line.AmountCents, &line.BalanceCents, &line.AssignmentID, &line.Description, &line.CreatedAt); err != nil {
+			http.Error(w, "Failed to scan wallet statement", http.StatusInternalServerError)
+			return
+		}
+		lines = append(lines, line)
+	}
+
+	// Check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		http.Error(w, "Error encountered during row iteration", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the statement in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(lines); err != nil {
+		http.Error(w, "Failed to encode wallet statement to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+type TopUpRequest struct {
+	AmountCents int64 `json:"amount_cents"`
+}
+
+func (req *TopUpRequest) Validate() []FieldError {
+	if req.AmountCents < 1 || req.AmountCents > maxTopUpCents {
+		return []FieldError{{Field: "amount_cents", Message: fmt.Sprintf("must be between 1 and %d", maxTopUpCents)}}
+	}
+	return nil
+}
+
+// topUpWallet credits a user wallet with money paid in, as recorded by a
+// supervisor
+func (s *Server) topUpWallet(w http.ResponseWriter, r *http.Request) {
+	supervisor, ok := requireSupervisor(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := userFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	// Parse and validate the JSON request body
+	var req TopUpRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	transaction := models.WalletTransaction{
+		Kind:        models.WalletTopUp,
+		AmountCents: req.AmountCents,
+		CreatedBy:   sql.NullString{String: supervisor.ID, Valid: true},
+		CreatedAt:   s.Clock.Now(),
+	}
+	if err := wallet.Transfer(r.Context(), s.DB, wallet.System(wallet.AccountTopUps), wallet.User(userID), &transaction); err != nil {
+		http.Error(w, "Failed to top up wallet", http.StatusInternalServerError)
+		return
+	}
+
+	s.recordAudit(r, audit.Event{
+		Actor:      audit.ActorFromRequest(r, supervisor.ID),
+		Action:     audit.ActionWalletToppedUp,
+		EntityType: audit.EntityWallet,
+		EntityID:   fmt.Sprint(transaction.ID),
+		After:      map[string]interface{}{"user_id": userID, "amount_cents": transaction.AmountCents},
+	})
+
+	// Respond with the transaction in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(transaction); err != nil {
+		http.Error(w, "Failed to encode transaction to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+type RefundRequest struct {
+	AmountCents  int64  `json:"amount_cents"`
+	AssignmentID uint   `json:"assignment_id,omitempty"` // the ride refunded, if any
+	Reason       string `json:"reason"`
+}
+
+func (req *RefundRequest) Validate() []FieldError {
+	var errs []FieldError
+	if req.AmountCents < 1 {
+		errs = append(errs, FieldError{Field: "amount_cents", Message: "must be positive"})
+	}
+	switch {
+	case req.Reason == "":
+		errs = append(errs, FieldError{Field: "reason", Message: "is required"})
+	case len(req.Reason) > maxReasonLength:
+		errs = append(errs, FieldError{Field: "reason", Message: fmt.Sprintf("must not exceed %d characters", maxReasonLength)})
+	}
+	return errs
+}
+
+// refundWallet credits a user wallet back from the revenue. A refunded ride
+// cannot be refunded more than its fare.
+func (s *Server) refundWallet(w http.ResponseWriter, r *http.Request) {
+	supervisor, ok := requireSupervisor(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := userFromURL(w, r, s.DB)
+	if !ok {
+		return
+	}
+
+	// Parse and validate the JSON request body
+	var req RefundRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	transaction := models.WalletTransaction{
+		Kind:         models.WalletRefund,
+		AmountCents:  req.AmountCents,
+		AssignmentID: sql.NullInt64{Int64: int64(req.AssignmentID), Valid: req.AssignmentID != 0},
+		Description:  sql.NullString{String: req.Reason, Valid: true},
+		CreatedBy:    sql.NullString{String: supervisor.ID, Valid: true},
+		CreatedAt:    s.Clock.Now(),
+	}
+	if err := wallet.Refund(r.Context(), s.DB, userID, &transaction); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			http.Error(w, "Assignment not found", http.StatusNotFound)
+		case wallet.ErrRefundExceedsFare:
+			writeValidationError(w, http.StatusBadRequest, "Invalid request", FieldError{Field: "amount_cents", Message: "must not exceed the fare of the ride, less its earlier refunds"})
+		default:
+			http.Error(w, "Failed to refund wallet", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.recordAudit(r, audit.Event{
+		Actor:      audit.ActorFromRequest(r, supervisor.ID),
+		Action:     audit.ActionWalletRefunded,
+		EntityType: audit.EntityWallet,
+		EntityID:   fmt.Sprint(transaction.ID),
+		After:      map[string]interface{}{"user_id": userID, "amount_cents": transaction.AmountCents, "assignment_id": req.AssignmentID},
+		Reason:     req.Reason,
+	})
+
+	// Respond with the transaction in JSON format
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(transaction); err != nil {
+		http.Error(w, "Failed to encode transaction to JSON", http.StatusInternalServerError)
+		return
+	}
+}
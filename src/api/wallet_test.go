@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+// withUser sets the user of the URL, as the router does
+func withUser(req *http.Request, userID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", userID)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestTopUpWallet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM users WHERE id = \\$1\\)").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	// The money comes from the top-ups account
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM wallet_accounts WHERE name = \\$1").
+		WithArgs("topups").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO wallet_accounts \\(user_id\\)").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("INSERT INTO wallet_transactions").
+		WithArgs("top_up", nil, nil, supervisorUUID, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO wallet_entries").
+		WithArgs(int64(9), int64(1), int64(-2000), int64(3), int64(2000)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs("operator", supervisorUUID, "wallet.topped_up", "wallet_transaction", "9", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/users/"+userUUID+"/wallet/top-ups", strings.NewReader(`{"amount_cents":2000}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = asOperator(req, supervisorUUID)
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).topUpWallet(rr, withUser(req, userUUID))
+
+	assert.Equal(t, http.StatusCreated, rr.Code, "Expected status Created but got %v", rr.Code)
+
+	var transaction models.WalletTransaction
+	err = json.NewDecoder(rr.Body).Decode(&transaction)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(9), transaction.ID)
+	assert.Equal(t, models.WalletTopUp, transaction.Kind)
+	assert.Equal(t, int64(2000), transaction.AmountCents)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTopUpWallet_Anonymous(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	// Customers cannot credit their own wallet, nothing is written
+	req := httptest.NewRequest(http.MethodPost, "/users/"+userUUID+"/wallet/top-ups", strings.NewReader(`{"amount_cents":2000}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	testServer(db, clock.NewFake(time.Now()), &Config{}).topUpWallet(rr, withUser(req, userUUID))
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "Expected status Unauthorized but got %v", rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefundWallet_ExceedsFare(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC))
+
+	supervisorUUID := "da690323-5a78-4d46-a214-943b2ec9d49e"
+	userUUID := "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM users WHERE id = \\$1\\)").
+		WithArgs(userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	// The ride was charged 700 and refunded 300 already, less than the
+	// refund asked for is left
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT fare_cents FROM assignments WHERE id = \\$1 AND user_id = \\$2 FOR UPDATE").
+		WithArgs(int64(42), userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"fare_cents"}).AddRow(700))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(e.amount_cents\\), 0\\) FROM wallet_transactions t").
+		WithArgs(int64(42), "refund", userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(300))
+	mock.ExpectRollback()
+
+	reqBody := `{"amount_cents":500,"assignment_id":42,"reason":"Flat tyre"}`
+	req := httptest.NewRequest(http.MethodPost, "/users/"+userUUID+"/wallet/refunds", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req = asOperator(req, supervisorUUID)
+	rr := httptest.NewRecorder()
+
+	testServer(db, clk, &Config{}).refundWallet(rr, withUser(req, userUUID))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status Bad Request but got %v", rr.Code)
+	assert.Contains(t, rr.Body.String(), "must not exceed the fare of the ride")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Actor types
+const (
+	ActorUser     = "user"
+	ActorStation  = "station"
+	ActorOperator = "operator"
+	ActorSystem   = "system"
+)
+
+// Actions
+const (
+	ActionBikeAssigned        = "bike.assigned"
+	ActionBikeUnassigned      = "bike.unassigned"
+	ActionBikeForceUnassigned = "bike.force_unassigned" // recorded by the former auto-unassign job
+	ActionBikeCreated         = "bike.created"
+	ActionBikeRelocated       = "bike.relocated"
+	ActionBikeLostSuspected   = "bike.lost_suspected"
+	ActionBikeDeclaredLost    = "bike.declared_lost"
+	ActionBikeRecovered       = "bike.recovered"
+	ActionBikeReconciled      = "bike.reconciled" // repaired by the reconcile job
+	ActionUserCreated         = "user.created"
+
+	ActionReservationCreated   = "reservation.created"
+	ActionReservationConverted = "reservation.converted"
+	ActionReservationCancelled = "reservation.cancelled"
+	ActionReservationExpired   = "reservation.expired"
+
+	ActionWaitlistOffered = "waitlist.offered"
+
+	ActionWalletToppedUp = "wallet.topped_up"
+	ActionWalletRefunded = "wallet.refunded"
+
+	ActionSubscriptionCreated   = "subscription.created"
+	ActionSubscriptionRenewed   = "subscription.renewed"
+	ActionSubscriptionCancelled = "subscription.cancelled"
+	ActionSubscriptionExpired   = "subscription.expired"
+
+	ActionInvoiceIssued = "invoice.issued"
+
+	ActionTaskClosed = "task.closed"
+)
+
+// Entity types
+const (
+	EntityBike         = "bike"
+	EntityUser         = "user"
+	EntityReservation  = "reservation"
+	EntityWaitlist     = "waitlist_entry"
+	EntityWallet       = "wallet_transaction"
+	EntitySubscription = "subscription"
+	EntityInvoice      = "invoice"
+	EntityTask         = "supervisor_task"
+)
+
+// Headers identifying who is acting on behalf of a request
+const (
+	OperatorHeader = "X-Operator-UUID"
+	StationHeader  = "X-Station-ID"
+)
+
+// Actor is whoever caused a state change
+type Actor struct {
+	Type string
+	ID   string
+}
+
+// System returns the actor of a background job
+func System(job string) Actor {
+	return Actor{Type: ActorSystem, ID: job}
+}
+
+// operatorKey is the context key of the operator authenticated for a request
+type operatorKey struct{}
+
+// WithOperator returns a copy of ctx carrying the operator authenticated for
+// the request
+func WithOperator(ctx context.Context, operatorID string) context.Context {
+	return context.WithValue(ctx, operatorKey{}, operatorID)
+}
+
+// OperatorFromContext returns the operator authenticated for the request, if
+// any
+func OperatorFromContext(ctx context.Context) (string, bool) {
+	operatorID, ok := ctx.Value(operatorKey{}).(string)
+	return operatorID, ok
+}
+
+// ActorFromRequest returns the operator authenticated for the request or the
+// docking station acting on its behalf, if any, and the given user
+// otherwise. The operator header alone is not trusted: it is the actor only
+// once the authentication middleware set it in the context.
+func ActorFromRequest(r *http.Request, userID string) Actor {
+	if operator, ok := OperatorFromContext(r.Context()); ok {
+		return Actor{Type: ActorOperator, ID: operator}
+	}
+	if station := r.Header.Get(StationHeader); station != "" {
+		return Actor{Type: ActorStation, ID: station}
+	}
+	return Actor{Type: ActorUser, ID: userID}
+}
+
+// Event is a state change to record. Before and After are snapshots of the
+// entity, marshalled to JSON.
+type Event struct {
+	OccurredAt time.Time // read from the clock of the caller
+	Actor      Actor
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     interface{}
+	After      interface{}
+	Reason     string
+}
+
+// Execer is satisfied by both *sql.DB and *sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Record appends an event to the audit log. The request ID, if any, is
+// taken from the context.
+func Record(ctx context.Context, db Execer, event Event) error {
+	if event.OccurredAt.IsZero() {
+		return errors.New("audit: event without time")
+	}
+	before, err := snapshot(event.Before)
+	if err != nil {
+		return err
+	}
+	after, err := snapshot(event.After)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO audit_events (actor_type, actor_id, action, entity_type, entity_id, before, after, reason, request_id, occurred_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = db.ExecContext(ctx, query,
+		event.Actor.Type, nullString(event.Actor.ID), event.Action, event.EntityType, event.EntityID,
+		before, after, nullString(event.Reason), nullString(middleware.GetReqID(ctx)), event.OccurredAt)
+	return err
+}
+
+func snapshot(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -0,0 +1,69 @@
+// Package clock tells the time to the handlers and cron jobs, so tests can
+// set it instead of reading the wall clock
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock returns the current time, and waits for it to pass
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// Real is the wall clock
+type Real struct{}
+
+// Now returns time.Now()
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// After returns time.After(d)
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Fake is a clock standing still until it is set or advanced. It is safe
+// for concurrent use.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a fake clock set to now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the time the clock is set to
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set moves the clock to now
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// After advances the clock by d and returns a channel the new time was sent
+// on already, the wait being over at once
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	c := make(chan time.Time, 1)
+	c <- f.Advance(d)
+	return c
+}
+
+// Advance moves the clock forward by d and returns the new time
+func (f *Fake) Advance(d time.Duration) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	return f.now
+}
@@ -0,0 +1,33 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := NewFake(start)
+
+	// The clock stands still until it is moved
+	assert.Equal(t, start, clk.Now())
+	assert.Equal(t, start, clk.Now())
+
+	assert.Equal(t, start.Add(5*time.Minute), clk.Advance(5*time.Minute))
+	assert.Equal(t, start.Add(5*time.Minute), clk.Now())
+
+	clk.Set(start)
+	assert.Equal(t, start, clk.Now())
+
+	// Waiting moves the clock instead of blocking
+	assert.Equal(t, start.Add(time.Second), <-clk.After(time.Second))
+	assert.Equal(t, start.Add(time.Second), clk.Now())
+}
+
+func TestReal(t *testing.T) {
+	before := time.Now()
+	now := Real{}.Now()
+	assert.False(t, now.Before(before))
+}
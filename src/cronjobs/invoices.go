@@ -0,0 +1,84 @@
+package cronjobs
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/invoices"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/tracing"
+)
+
+// IssueMonthlyInvoices invoices the previous month to every user who rode,
+// paid a subscription, a penalty or a fee, or was refunded during it and was
+// not invoiced yet. Running daily catches up on the users a failed run
+// missed.
+func IssueMonthlyInvoices(db *sql.DB, clk clock.Clock) {
+	ctx, span := tracing.Tracer("cronjobs").Start(context.Background(), "cron IssueMonthlyInvoices")
+	defer span.End()
+
+	now := clk.Now()
+	periodEnd := invoices.MonthStart(now)
+	periodStart := periodEnd.AddDate(0, -1, 0)
+
+	query := `SELECT user_id FROM receipts WHERE ended_at >= $1 AND ended_at < $2
+	          UNION
+	          SELECT a.user_id
+	          FROM wallet_transactions t
+	          INNER JOIN wallet_entries e ON e.transaction_id = t.id
+	          INNER JOIN wallet_accounts a ON a.id = e.account_id
+	          WHERE a.user_id IS NOT NULL AND ` + invoices.TransactionCondition + ` AND t.created_at >= $1 AND t.created_at < $2
+	          EXCEPT
+	          SELECT user_id FROM invoices WHERE period_start = $1`
+	rows, err := db.QueryContext(ctx, query, periodStart, periodEnd)
+	if err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to fetch users to invoice")
+		return
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to scan user to invoice")
+			return
+		}
+		users = append(users, userID)
+	}
+	if err := rows.Err(); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to fetch users to invoice")
+		return
+	}
+
+	issued := 0
+	for _, userID := range users {
+		invoice, err := invoices.Issue(ctx, db, userID, periodStart, now)
+		if err == invoices.ErrNothingToInvoice {
+			continue
+		}
+		if err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Str("user", userID).Msg("Failed to issue invoice")
+			continue
+		}
+		issued++
+
+		event := audit.Event{
+			OccurredAt: now,
+			Actor:      audit.System("invoices"),
+			Action:     audit.ActionInvoiceIssued,
+			EntityType: audit.EntityInvoice,
+			EntityID:   invoice.Number,
+			After:      map[string]interface{}{"user_id": userID, "period_start": invoice.PeriodStart, "total_cents": invoice.TotalCents},
+		}
+		if err := audit.Record(ctx, db, event); err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Str("invoice", invoice.Number).Msg("Failed to record audit event")
+		}
+	}
+
+	if issued > 0 {
+		logger.For("cronjobs").Info().Ctx(ctx).Int("issued", issued).Str("period", periodStart.Format("2006-01")).Msg("Issued monthly invoices")
+	}
+}
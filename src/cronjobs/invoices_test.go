@@ -0,0 +1,69 @@
+package cronjobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/yourusername/bike-rental/src/clock"
+)
+
+func TestIssueMonthlyInvoices(t *testing.T) {
+	// Create a new mock database
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 1, 2, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	periodStart := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT user_id FROM receipts WHERE ended_at >= \$1 AND ended_at < \$2 UNION (.+) EXCEPT SELECT user_id FROM invoices WHERE period_start = \$1`).
+		WithArgs(periodStart, periodEnd).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user-1").AddRow("user-2"))
+
+	// The first user rode once in July
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM receipts`).
+		WithArgs("user-1", periodStart, periodEnd).
+		WillReturnRows(sqlmock.NewRows([]string{"assignment_id", "ended_at", "minutes", "unlock_cents", "time_cents", "penalty_cents", "total_cents", "currency"}).
+			AddRow(12, time.Date(2024, 7, 3, 8, 30, 0, 0, time.UTC), 30, 100, 450, 0, 550, "EUR"))
+	mock.ExpectQuery(`FROM wallet_transactions t`).
+		WithArgs("user-1", periodStart, periodEnd).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "assignment_id", "created_at", "description", "amount_cents"}))
+	mock.ExpectQuery(`INSERT INTO invoice_counters`).
+		WithArgs(2024).
+		WillReturnRows(sqlmock.NewRows([]string{"last_number"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO invoices`).
+		WithArgs("INV-2024-000001", "user-1", periodStart, periodEnd, "EUR", 1, int64(100), int64(450), int64(0), int64(0), int64(0), int64(0), int64(550), fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(`INSERT INTO invoice_lines`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectExec(`INSERT INTO audit_events`).
+		WithArgs("system", "invoices", "invoice.issued", "invoice", "INV-2024-000001", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// The second one has nothing left to invoice, no invoice is issued
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM receipts`).
+		WithArgs("user-2", periodStart, periodEnd).
+		WillReturnRows(sqlmock.NewRows([]string{"assignment_id", "ended_at", "minutes", "unlock_cents", "time_cents", "penalty_cents", "total_cents", "currency"}))
+	mock.ExpectQuery(`FROM wallet_transactions t`).
+		WithArgs("user-2", periodStart, periodEnd).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "assignment_id", "created_at", "description", "amount_cents"}))
+	mock.ExpectRollback()
+
+	// Call the function to test
+	IssueMonthlyInvoices(db, clk)
+
+	// Assert that all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %v", err)
+	}
+}
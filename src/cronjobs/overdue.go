@@ -1,27 +1,46 @@
 package cronjobs
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/clock"
 	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/notifications"
+	"github.com/yourusername/bike-rental/src/pricing"
+	"github.com/yourusername/bike-rental/src/tracing"
+	"github.com/yourusername/bike-rental/src/wallet"
 )
 
-// timeNow is a variable that returns the current time. It can be overridden in tests.
-var timeNow = time.Now
+// Steps of the overdue escalation, from the assignment of the bike
+const (
+	// OverdueAfter is how long a ride lasts before it is escalated
+	OverdueAfter = 24 * time.Hour
+	warnBefore   = time.Hour
+)
+
+// EscalateOverdueAssignments warns the users whose ride is due within the
+// hour, then flags the rides of more than 24 hours as overdue: the bike is
+// suspected lost, the overdue penalty of tariff is charged and a supervisor
+// task is opened. The ride itself goes on until the bike is docked.
+func EscalateOverdueAssignments(db *sql.DB, clk clock.Clock, tariff *pricing.Tariff) {
+	// Every run is traced, the SQL statements being its children
+	ctx, span := tracing.Tracer("cronjobs").Start(context.Background(), "cron EscalateOverdueAssignments")
+	defer span.End()
 
-func AutoUnassignOverdueBikes(db *sql.DB) {
-	// Calculate the cutoff time for 24 hours ago
-	cutoff := timeNow().Add(-24 * time.Hour)
+	now := clk.Now()
+	warnDueAssignments(ctx, db, now)
 
-	log.Debug().Msg("Scanning for overdue bike assignments...")
+	logger.For("cronjobs").Debug().Ctx(ctx).Msg("Scanning for overdue bike assignments...")
 
-	// Find all assignments older than 24 hours and still active (unassigned_at is NULL)
-	query := `SELECT id, user_id, bike_id FROM assignments WHERE assigned_at < $1 AND unassigned_at IS NULL`
-	rows, err := db.Query(query, cutoff)
+	// Find the rides started more than 24 hours ago and not escalated yet
+	query := `SELECT id, user_id, bike_id, assigned_at FROM assignments WHERE assigned_at < $1 AND unassigned_at IS NULL AND overdue_at IS NULL`
+	rows, err := db.QueryContext(ctx, query, now.Add(-OverdueAfter))
 	if err != nil {
-		log.Err(err).Msg("Failed to retrieve overdue assignments")
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to retrieve overdue assignments")
 		return
 	}
 	defer rows.Close()
@@ -29,49 +48,134 @@ func AutoUnassignOverdueBikes(db *sql.DB) {
 	var overdueAssignments []models.Assignment
 	for rows.Next() {
 		var assignment models.Assignment
-		if err := rows.Scan(&assignment.ID, &assignment.UserID, &assignment.BikeID); err != nil {
-			log.Err(err).Msg("Failed to scan overdue assignment")
+		if err := rows.Scan(&assignment.ID, &assignment.UserID, &assignment.BikeID, &assignment.AssignedAt); err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to scan overdue assignment")
 			return
 		}
 		overdueAssignments = append(overdueAssignments, assignment)
 	}
+	if err := rows.Err(); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to retrieve overdue assignments")
+		return
+	}
 
-	// Process each overdue assignment
 	for _, assignment := range overdueAssignments {
-		// Unassign the bike and update the assignment
-		if err := unassignBikeByUserID(db, assignment.UserID); err != nil {
-			log.Err(err).Str("user", assignment.UserID).Msg("Failed to unassign bike")
+		if err := escalateAssignment(ctx, db, assignment, tariff, now); err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Uint("assignment", assignment.ID).Msg("Failed to escalate overdue assignment")
 		}
 	}
 }
 
-func unassignBikeByUserID(db *sql.DB, userID string) error {
-	// Find the active assignment for the user
-	query := `SELECT id, bike_id FROM assignments WHERE user_id = $1 AND unassigned_at IS NULL LIMIT 1`
-	var assignment models.Assignment
-	if err := db.QueryRow(query, userID).Scan(&assignment.ID, &assignment.BikeID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil // No active assignment found, nothing to do
+// warnDueAssignments lets the users know their ride becomes overdue within
+// the hour, once per ride
+func warnDueAssignments(ctx context.Context, db *sql.DB, now time.Time) {
+	query := `UPDATE assignments SET warned_at = $1
+	          WHERE unassigned_at IS NULL AND warned_at IS NULL AND assigned_at < $2 AND assigned_at >= $3
+	          RETURNING id, user_id, bike_id, assigned_at`
+	rows, err := db.QueryContext(ctx, query, now, now.Add(warnBefore-OverdueAfter), now.Add(-OverdueAfter))
+	if err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to flag assignments due soon")
+		return
+	}
+	defer rows.Close()
+
+	var due []models.Assignment
+	for rows.Next() {
+		var assignment models.Assignment
+		if err := rows.Scan(&assignment.ID, &assignment.UserID, &assignment.BikeID, &assignment.AssignedAt); err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to scan assignment due soon")
+			return
+		}
+		due = append(due, assignment)
+	}
+	if err := rows.Err(); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to flag assignments due soon")
+		return
+	}
+
+	for _, assignment := range due {
+		payload := map[string]interface{}{
+			"assignment_id": assignment.ID,
+			"bike_id":       assignment.BikeID,
+			"due_at":        assignment.AssignedAt.Time.Add(OverdueAfter),
+		}
+		if err := notifications.Enqueue(ctx, db, assignment.UserID, notifications.KindAssignmentDueSoon, payload); err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Str("user", assignment.UserID).Msg("Failed to enqueue notification")
 		}
+	}
+}
+
+// escalateAssignment flags an overdue ride, suspects its bike lost, opens a
+// task for the supervisors to recover it and charges the penalty, all in one
+// transaction
+func escalateAssignment(ctx context.Context, db *sql.DB, assignment models.Assignment, tariff *pricing.Tariff, now time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	log.Info().Uint("assignment", uint(assignment.ID)).Msg("Found overdue bike assignment...")
+	// Docked since it was selected, or escalated by another run
+	query := "UPDATE assignments SET overdue_at = $1 WHERE id = $2 AND unassigned_at IS NULL AND overdue_at IS NULL"
+	result, err := tx.ExecContext(ctx, query, now, assignment.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return err
+	}
 
-	// Mark the bike as unassigned
-	query = `UPDATE bikes SET is_assigned = false, last_unassigned = $1 WHERE id = $2`
-	now := time.Now()
-	if _, err := db.Exec(query, now, assignment.BikeID); err != nil {
+	query = "UPDATE bikes SET status = 'lost_suspected' WHERE id = $1"
+	if _, err := tx.ExecContext(ctx, query, assignment.BikeID); err != nil {
 		return err
 	}
 
-	// Update the assignment to mark it as unassigned
-	query = `UPDATE assignments SET unassigned_at = $1 WHERE id = $2`
-	if _, err := db.Exec(query, now, assignment.ID); err != nil {
+	var taskID uint
+	query = `INSERT INTO supervisor_tasks (kind, bike_id, assignment_id, user_id, note, created_at)
+	         VALUES ($1, $2, $3, $4, $5, $6)
+	         RETURNING id`
+	if err := tx.QueryRowContext(ctx, query, models.TaskOverdueBike, assignment.BikeID, assignment.ID, assignment.UserID,
+		"Bike not docked 24 hours after its assignment", now).Scan(&taskID); err != nil {
 		return err
 	}
 
-	log.Info().Uint("assignment", uint(assignment.ID)).Msg("Successfully unassigned overdue bike")
+	// The penalty is collected now, the rest of the fare when the bike is docked
+	var penalty int64
+	if tariff != nil {
+		penalty = tariff.OverduePenalty
+	}
+	if err := wallet.ChargePenalty(ctx, tx, assignment.UserID, assignment.ID, penalty, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"assignment_id": assignment.ID,
+		"bike_id":       assignment.BikeID,
+		"penalty_cents": penalty,
+	}
+	if err := notifications.Enqueue(ctx, db, assignment.UserID, notifications.KindAssignmentOverdue, payload); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Str("user", assignment.UserID).Msg("Failed to enqueue notification")
+	}
+
+	// Leave a trace of who flagged the bike and why
+	event := audit.Event{
+		OccurredAt: now,
+		Actor:      audit.System("overdue"),
+		Action:     audit.ActionBikeLostSuspected,
+		EntityType: audit.EntityBike,
+		EntityID:   assignment.BikeID,
+		Before:     map[string]interface{}{"status": models.BikeInService, "user_id": assignment.UserID, "assignment_id": assignment.ID},
+		After:      map[string]interface{}{"status": models.BikeLostSuspected, "penalty_cents": penalty, "task_id": taskID},
+		Reason:     "assigned for more than 24 hours",
+	}
+	if err := audit.Record(ctx, db, event); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Uint("assignment", assignment.ID).Msg("Failed to record audit event")
+	}
 
+	logger.For("cronjobs").Info().Ctx(ctx).Uint("assignment", assignment.ID).Uint("task", taskID).Msg("Escalated overdue bike assignment")
 	return nil
 }
@@ -1,6 +1,9 @@
 package cronjobs
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"testing"
 	"time"
 
@@ -8,6 +11,9 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/pricing"
 )
 
 func init() {
@@ -15,7 +21,7 @@ func init() {
 	log.Logger = zerolog.New(nil)
 }
 
-func TestAutoUnassignOverdueBikes(t *testing.T) {
+func TestEscalateOverdueAssignments(t *testing.T) {
 	// Create a new mock database
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -25,52 +31,93 @@ func TestAutoUnassignOverdueBikes(t *testing.T) {
 
 	// Use a fixed time for testing
 	fixedTime := time.Date(2024, 8, 20, 7, 19, 48, 208958572, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	// The rides started between 24 and 23 hours ago are warned once
+	startedAt := fixedTime.Add(-23*time.Hour - 30*time.Minute)
+	mock.ExpectQuery(`UPDATE assignments SET warned_at = \$1 WHERE unassigned_at IS NULL AND warned_at IS NULL AND assigned_at < \$2 AND assigned_at >= \$3 RETURNING id, user_id, bike_id, assigned_at`).
+		WithArgs(fixedTime, fixedTime.Add(-23*time.Hour), fixedTime.Add(-24*time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bike_id", "assigned_at"}).AddRow(3, "user-3", "bike-3", startedAt))
+	mock.ExpectExec(`INSERT INTO notifications`).
+		WithArgs("user-3", "assignment.due_soon", `{"assignment_id":3,"bike_id":"bike-3","due_at":"2024-08-20T07:49:48.208958572Z"}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	// Prepare mock data for overdue assignments
-	mockRows := sqlmock.NewRows([]string{"id", "user_id", "bike_id"}).
-		AddRow(1, "user-1", "bike-1").
-		AddRow(2, "user-2", "bike-2")
-
-	// Set up the expectations for the SELECT query
-	mock.ExpectQuery(`SELECT id, user_id, bike_id FROM assignments WHERE assigned_at < .* AND unassigned_at IS NULL`).
+	// The rides of more than 24 hours are escalated
+	mock.ExpectQuery(`SELECT id, user_id, bike_id, assigned_at FROM assignments WHERE assigned_at < \$1 AND unassigned_at IS NULL AND overdue_at IS NULL`).
 		WithArgs(fixedTime.Add(-24 * time.Hour)).
-		WillReturnRows(mockRows)
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bike_id", "assigned_at"}).
+			AddRow(1, "user-1", "bike-1", fixedTime.Add(-25*time.Hour)))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE assignments SET overdue_at = \$1 WHERE id = \$2 AND unassigned_at IS NULL AND overdue_at IS NULL`).
+		WithArgs(fixedTime, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE bikes SET status = 'lost_suspected' WHERE id = \$1`).
+		WithArgs("bike-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO supervisor_tasks \(kind, bike_id, assignment_id, user_id, note, created_at\)`).
+		WithArgs(models.TaskOverdueBike, "bike-1", 1, "user-1", sqlmock.AnyArg(), fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	// The penalty is debited right away, in the same transaction
+	mock.ExpectQuery(`INSERT INTO wallet_accounts \(user_id\)`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`SELECT id FROM wallet_accounts WHERE name = .*`).
+		WithArgs("revenue").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`INSERT INTO wallet_transactions`).
+		WithArgs("overdue_penalty", int64(1), nil, nil, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec(`INSERT INTO wallet_entries`).
+		WithArgs(int64(9), int64(3), int64(-5000), int64(2), int64(5000)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	mock.ExpectExec(`INSERT INTO notifications`).
+		WithArgs("user-1", "assignment.overdue", `{"assignment_id":1,"bike_id":"bike-1","penalty_cents":5000}`).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec(`INSERT INTO audit_events`).
+		WithArgs("system", "overdue", "bike.lost_suspected", "bike", "bike-1", sqlmock.AnyArg(), sqlmock.AnyArg(), "assigned for more than 24 hours", sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	// Set up expectations for the UPDATE queries
-	// mock.ExpectExec(`UPDATE bikes SET is_assigned = false, last_unassigned = ? WHERE id = ?`).
-	// 	WithArgs(sqlmock.AnyArg(), "bike-1").
-	// 	WillReturnResult(sqlmock.NewResult(1, 1))
+	// Call the function to test
+	EscalateOverdueAssignments(db, clk, &pricing.Tariff{UnlockFee: 100, OverduePenalty: 5000})
 
-	// mock.ExpectExec(`UPDATE assignments SET unassigned_at = ? WHERE id = ?`).
-	// 	WithArgs(sqlmock.AnyArg(), 1).
-	// 	WillReturnResult(sqlmock.NewResult(1, 1))
+	// Assert that all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %v", err)
+	}
+}
 
-	// mock.ExpectExec(`UPDATE bikes SET is_assigned = false, last_unassigned = ? WHERE id = ?`).
-	// 	WithArgs(sqlmock.AnyArg(), "bike-2").
-	// 	WillReturnResult(sqlmock.NewResult(1, 1))
+func TestEscalateAssignment_Docked(t *testing.T) {
+	// Create a new mock database
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
 
-	// mock.ExpectExec(`UPDATE assignments SET unassigned_at = ? WHERE id = ?`).
-	// 	WithArgs(sqlmock.AnyArg(), 2).
-	// 	WillReturnResult(sqlmock.NewResult(1, 1))
+	now := time.Date(2024, 8, 20, 7, 0, 0, 0, time.UTC)
 
-	// Override the timeNow function to return the fixed time
-	timeNow = func() time.Time {
-		return fixedTime
-	}
-	defer func() {
-		timeNow = time.Now
-	}()
+	// The bike was docked since the assignment was selected
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE assignments SET overdue_at = \$1 WHERE id = \$2`).
+		WithArgs(now, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
-	// Call the function to test
-	AutoUnassignOverdueBikes(db)
+	assignment := models.Assignment{ID: 1, UserID: "user-1", BikeID: "bike-1", AssignedAt: sql.NullTime{Time: now.Add(-25 * time.Hour), Valid: true}}
+	err = escalateAssignment(context.Background(), db, assignment, &pricing.Tariff{OverduePenalty: 5000}, now)
 
-	// Assert that all expectations were met
+	// Nothing is flagged nor charged
+	assert.NoError(t, err)
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("There were unfulfilled expectations: %v", err)
 	}
 }
 
-func TestUnassignBikeByUserID(t *testing.T) {
+func TestEscalateAssignment_ChargeFailed(t *testing.T) {
 	// Create a new mock database
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -78,27 +125,92 @@ func TestUnassignBikeByUserID(t *testing.T) {
 	}
 	defer db.Close()
 
-	// Prepare mock data
-	mockRows := sqlmock.NewRows([]string{"id", "bike_id"}).
-		AddRow(1, "bike-1")
-
-	// Set up the expectations
-	mock.ExpectQuery(`SELECT id, bike_id FROM assignments WHERE user_id = .* AND unassigned_at IS NULL LIMIT 1`).
+	now := time.Date(2024, 8, 20, 7, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE assignments SET overdue_at = \$1 WHERE id = \$2`).
+		WithArgs(now, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE bikes SET status = 'lost_suspected' WHERE id = \$1`).
+		WithArgs("bike-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO supervisor_tasks`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(`INSERT INTO wallet_accounts \(user_id\)`).
 		WithArgs("user-1").
-		WillReturnRows(mockRows)
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
 
-	mock.ExpectExec(`UPDATE bikes SET is_assigned = false, last_unassigned = .* WHERE id = .*`).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	assignment := models.Assignment{ID: 1, UserID: "user-1", BikeID: "bike-1", AssignedAt: sql.NullTime{Time: now.Add(-25 * time.Hour), Valid: true}}
+	err = escalateAssignment(context.Background(), db, assignment, &pricing.Tariff{OverduePenalty: 5000}, now)
 
-	mock.ExpectExec(`UPDATE assignments SET unassigned_at = .* WHERE id = .*`).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	// Call the function
-	err = unassignBikeByUserID(db, "user-1")
-
-	// Assert no errors and all expectations were met
-	assert.NoError(t, err)
+	// The ride is not flagged without its penalty, the next run trying again
+	assert.Equal(t, sql.ErrConnDone, err)
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("There were unfulfilled expectations: %v", err)
 	}
 }
+
+// startedBefore matches the bound of `assigned_at < $n` when the condition
+// holds, or not, for a ride started at at
+type startedBefore struct {
+	at    time.Time
+	holds bool
+}
+
+func (m startedBefore) Match(v driver.Value) bool {
+	bound, ok := v.(time.Time)
+	return ok && m.at.Before(bound) == m.holds
+}
+
+// startedFrom matches the bound of `assigned_at >= $n` the same way
+type startedFrom struct {
+	at    time.Time
+	holds bool
+}
+
+func (m startedFrom) Match(v driver.Value) bool {
+	bound, ok := v.(time.Time)
+	return ok && !m.at.Before(bound) == m.holds
+}
+
+func TestEscalateOverdueAssignments_Boundaries(t *testing.T) {
+	// Create a new mock database
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// The ride starts when the clock does
+	assignedAt := time.Date(2024, 8, 20, 7, 19, 48, 0, time.UTC)
+	clk := clock.NewFake(assignedAt)
+
+	columns := []string{"id", "user_id", "bike_id", "assigned_at"}
+	run := func(warned, overdue bool) {
+		// The warning window is [24 hours ago, 23 hours ago)
+		mock.ExpectQuery(`UPDATE assignments SET warned_at = \$1 WHERE (.+) RETURNING id, user_id, bike_id, assigned_at`).
+			WithArgs(clk.Now(), startedBefore{assignedAt, warned || overdue}, startedFrom{assignedAt, !overdue}).
+			WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectQuery(`SELECT id, user_id, bike_id, assigned_at FROM assignments WHERE assigned_at < \$1`).
+			WithArgs(startedBefore{assignedAt, overdue}).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		EscalateOverdueAssignments(db, clk, &pricing.Tariff{OverduePenalty: 5000})
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations at %v: %v", clk.Now().Sub(assignedAt), err)
+		}
+	}
+
+	// Warned once the ride is 23 hours old, not before
+	clk.Advance(23 * time.Hour)
+	run(false, false)
+	clk.Advance(time.Nanosecond)
+	run(true, false)
+
+	// Overdue once it is more than 24 hours old
+	clk.Set(assignedAt.Add(24 * time.Hour))
+	run(true, false)
+	clk.Advance(time.Nanosecond)
+	run(false, true)
+}
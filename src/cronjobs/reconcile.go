@@ -0,0 +1,32 @@
+package cronjobs
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/reconcile"
+	"github.com/yourusername/bike-rental/src/tracing"
+)
+
+// Reconcile reports the bikes and assignments left disagreeing by a failed
+// request, and repairs them when config allows it
+func Reconcile(db *sql.DB, clk clock.Clock, config *reconcile.Config) {
+	ctx, span := tracing.Tracer("cronjobs").Start(context.Background(), "cron Reconcile")
+	defer span.End()
+
+	found, err := reconcile.Run(ctx, db, clk, config)
+	if err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to reconcile bikes and assignments")
+		return
+	}
+
+	for _, inconsistency := range found {
+		if inconsistency.Repaired {
+			logger.For("cronjobs").Info().Ctx(ctx).Str("kind", inconsistency.Kind).Str("detail", inconsistency.String()).Msg("Repaired inconsistency")
+		} else {
+			logger.For("cronjobs").Warn().Ctx(ctx).Str("kind", inconsistency.Kind).Str("detail", inconsistency.String()).Msg("Found inconsistency")
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package cronjobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/reconcile"
+)
+
+func TestReconcile(t *testing.T) {
+	// Create a new mock database
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	// The assignment of a bike was never created
+	mock.ExpectQuery(`SELECT b.id FROM bikes b WHERE b.is_assigned AND NOT EXISTS`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("bike-1"))
+	mock.ExpectQuery(`SELECT a.id, a.user_id, a.bike_id FROM assignments a`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bike_id"}))
+	mock.ExpectQuery(`SELECT b.id, b.usage_count, COUNT\(a.id\) FROM bikes b`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "usage_count", "count"}))
+	mock.ExpectQuery(`SELECT user_id, COUNT\(\*\) FROM assignments`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "count"}))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE bikes SET is_assigned = false`).
+		WithArgs("bike-1").
+		WillReturnRows(sqlmock.NewRows([]string{"station_id", "slot"}).AddRow("station-1", 4))
+	mock.ExpectExec(`INSERT INTO audit_events`).
+		WithArgs("system", "reconcile", "bike.reconciled", "bike", "bike-1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// Call the function to test
+	Reconcile(db, clk, &reconcile.Config{Repair: true})
+
+	// Assert that all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package cronjobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/tracing"
+)
+
+// ExpireReservations releases the bikes held by reservations that were not
+// converted into an assignment in time
+func ExpireReservations(db *sql.DB, clk clock.Clock) {
+	ctx, span := tracing.Tracer("cronjobs").Start(context.Background(), "cron ExpireReservations")
+	defer span.End()
+
+	now := clk.Now()
+	query := `UPDATE reservations SET status = 'expired', updated_at = $1
+	          WHERE status = 'active' AND expires_at <= $1
+	          RETURNING id, user_id, bike_id`
+	rows, err := db.QueryContext(ctx, query, now)
+	if err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to expire reservations")
+		return
+	}
+	defer rows.Close()
+
+	var expired []models.Reservation
+	for rows.Next() {
+		var reservation models.Reservation
+		if err := rows.Scan(&reservation.ID, &reservation.UserID, &reservation.BikeID); err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to scan expired reservation")
+			return
+		}
+		expired = append(expired, reservation)
+	}
+	if err := rows.Err(); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to expire reservations")
+		return
+	}
+
+	for _, reservation := range expired {
+		event := audit.Event{
+			OccurredAt: now,
+			Actor:      audit.System("reservations"),
+			Action:     audit.ActionReservationExpired,
+			EntityType: audit.EntityReservation,
+			EntityID:   fmt.Sprint(reservation.ID),
+			Before:     map[string]interface{}{"status": models.ReservationActive, "user_id": reservation.UserID, "bike_id": reservation.BikeID},
+			After:      map[string]interface{}{"status": models.ReservationExpired},
+		}
+		if err := audit.Record(ctx, db, event); err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Uint("reservation", reservation.ID).Msg("Failed to record audit event")
+		}
+	}
+
+	if len(expired) > 0 {
+		logger.For("cronjobs").Info().Ctx(ctx).Int("count", len(expired)).Msg("Expired stale reservations")
+	}
+
+	// Waiting users who did not claim the bike held for them lose their turn
+	query = `UPDATE waitlist_entries SET status = 'expired', updated_at = $1
+	         WHERE status = 'offered' AND reservation_id IN (SELECT id FROM reservations WHERE status = 'expired')`
+	if _, err := db.ExecContext(ctx, query, now); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to expire waitlist offers")
+	}
+}
@@ -0,0 +1,42 @@
+package cronjobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/yourusername/bike-rental/src/clock"
+)
+
+func TestExpireReservations(t *testing.T) {
+	// Create a new mock database
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 20, 7, 19, 48, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	mock.ExpectQuery(`UPDATE reservations SET status = 'expired', updated_at = \$1 WHERE status = 'active' AND expires_at <= \$1 RETURNING id, user_id, bike_id`).
+		WithArgs(fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bike_id"}).AddRow(3, "user-1", "bike-1"))
+
+	mock.ExpectExec(`INSERT INTO audit_events`).
+		WithArgs("system", "reservations", "reservation.expired", "reservation", "3", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(`UPDATE waitlist_entries SET status = 'expired', updated_at = \$1 WHERE status = 'offered' AND reservation_id IN \(SELECT id FROM reservations WHERE status = 'expired'\)`).
+		WithArgs(fixedTime).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Call the function to test
+	ExpireReservations(db, clk)
+
+	// Assert that all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %v", err)
+	}
+}
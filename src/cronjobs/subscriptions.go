@@ -0,0 +1,127 @@
+package cronjobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/notifications"
+	"github.com/yourusername/bike-rental/src/subscriptions"
+	"github.com/yourusername/bike-rental/src/tracing"
+)
+
+// RenewSubscriptions renews the subscriptions whose period ended, paid from
+// the wallet of their user, and expires the ones cancelled or that cannot be
+// paid for
+func RenewSubscriptions(db *sql.DB, clk clock.Clock) {
+	ctx, span := tracing.Tracer("cronjobs").Start(context.Background(), "cron RenewSubscriptions")
+	defer span.End()
+
+	now := clk.Now()
+	query := `SELECT s.id, s.user_id, s.auto_renew, s.started_at, s.expires_at,
+	                 p.id, p.name, p.period, p.price_cents, p.included_minutes, p.max_rentals
+	          FROM subscriptions s
+	          INNER JOIN plans p ON p.id = s.plan_id
+	          WHERE s.status = 'active' AND s.expires_at <= $1
+	          ORDER BY s.expires_at`
+	rows, err := db.QueryContext(ctx, query, now)
+	if err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to fetch ended subscriptions")
+		return
+	}
+	defer rows.Close()
+
+	var ended []models.Subscription
+	var plans []models.Plan
+	for rows.Next() {
+		var subscription models.Subscription
+		var plan models.Plan
+		if err := rows.Scan(&subscription.ID, &subscription.UserID, &subscription.AutoRenew, &subscription.StartedAt, &subscription.ExpiresAt,
+			&plan.ID, &plan.Name, &plan.Period, &plan.PriceCents, &plan.IncludedMinutes, &plan.MaxRentals); err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to scan ended subscription")
+			return
+		}
+		subscription.PlanID = plan.ID
+		subscription.Status = models.SubscriptionActive
+		ended = append(ended, subscription)
+		plans = append(plans, plan)
+	}
+	if err := rows.Err(); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to fetch ended subscriptions")
+		return
+	}
+
+	renewed, expired := 0, 0
+	for i := range ended {
+		subscription, plan := &ended[i], plans[i]
+
+		reason := "cancelled"
+		if subscription.AutoRenew {
+			previous := subscription.ExpiresAt
+			err := subscriptions.Renew(ctx, db, subscription, plan, now)
+			if err == nil {
+				renewed++
+				recordSubscriptionEvent(ctx, db, audit.ActionSubscriptionRenewed, subscription, previous, "", now)
+				continue
+			}
+			if err != subscriptions.ErrInsufficientBalance {
+				logger.For("cronjobs").Err(err).Ctx(ctx).Uint("subscription", subscription.ID).Msg("Failed to renew subscription")
+				continue
+			}
+			reason = "insufficient wallet balance"
+		}
+
+		if err := expireSubscription(ctx, db, subscription, reason, now); err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Uint("subscription", subscription.ID).Msg("Failed to expire subscription")
+			continue
+		}
+		expired++
+	}
+
+	if renewed > 0 || expired > 0 {
+		logger.For("cronjobs").Info().Ctx(ctx).Int("renewed", renewed).Int("expired", expired).Msg("Processed ended subscriptions")
+	}
+}
+
+// expireSubscription ends a subscription at time now and lets its user know why
+func expireSubscription(ctx context.Context, db *sql.DB, subscription *models.Subscription, reason string, now time.Time) error {
+	query := "UPDATE subscriptions SET status = 'expired', updated_at = $1 WHERE id = $2 AND status = 'active'"
+	if _, err := db.ExecContext(ctx, query, now, subscription.ID); err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"subscription_id": subscription.ID,
+		"plan_id":         subscription.PlanID,
+		"expired_at":      subscription.ExpiresAt,
+		"reason":          reason,
+	}
+	if err := notifications.Enqueue(ctx, db, subscription.UserID, notifications.KindSubscriptionExpired, payload); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Str("user", subscription.UserID).Msg("Failed to enqueue notification")
+	}
+
+	subscription.Status = models.SubscriptionExpired
+	recordSubscriptionEvent(ctx, db, audit.ActionSubscriptionExpired, subscription, subscription.ExpiresAt, reason, now)
+	return nil
+}
+
+func recordSubscriptionEvent(ctx context.Context, db *sql.DB, action string, subscription *models.Subscription, previousExpiry time.Time, reason string, now time.Time) {
+	event := audit.Event{
+		OccurredAt: now,
+		Actor:      audit.System("subscriptions"),
+		Action:     action,
+		EntityType: audit.EntitySubscription,
+		EntityID:   fmt.Sprint(subscription.ID),
+		Before:     map[string]interface{}{"status": models.SubscriptionActive, "expires_at": previousExpiry},
+		After:      map[string]interface{}{"status": subscription.Status, "expires_at": subscription.ExpiresAt},
+		Reason:     reason,
+	}
+	if err := audit.Record(ctx, db, event); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Uint("subscription", subscription.ID).Msg("Failed to record audit event")
+	}
+}
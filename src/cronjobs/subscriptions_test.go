@@ -0,0 +1,81 @@
+package cronjobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/yourusername/bike-rental/src/clock"
+)
+
+func TestRenewSubscriptions(t *testing.T) {
+	// Create a new mock database
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 21, 8, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	expiresAt := time.Date(2024, 8, 21, 7, 0, 0, 0, time.UTC)
+	columns := []string{"id", "user_id", "auto_renew", "started_at", "expires_at", "id", "name", "period", "price_cents", "included_minutes", "max_rentals"}
+	mock.ExpectQuery(`SELECT s.id, s.user_id, s.auto_renew, s.started_at, s.expires_at, p.id, (.+) WHERE s.status = 'active' AND s.expires_at <= \$1`).
+		WithArgs(fixedTime).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "user-1", true, expiresAt.AddDate(0, -1, 0), expiresAt, "monthly", "Monthly pass", "month", 1990, 45, 1).
+			AddRow(2, "user-2", true, expiresAt.AddDate(0, -1, 0), expiresAt, "monthly", "Monthly pass", "month", 1990, 45, 1))
+
+	// The first user pays the next month
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(e.amount_cents\), 0\) FROM wallet_entries`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(5000))
+	mock.ExpectQuery(`INSERT INTO wallet_accounts \(user_id\)`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`SELECT id FROM wallet_accounts WHERE name = \$1`).
+		WithArgs("revenue").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`INSERT INTO wallet_transactions`).
+		WithArgs("subscription", nil, "Monthly pass from 2024-08-21 to 2024-09-21", nil, fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec(`INSERT INTO wallet_entries`).
+		WithArgs(int64(9), int64(3), int64(-1990), int64(2), int64(1990)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE subscriptions SET expires_at = \$1, updated_at = \$2 WHERE id = \$3 AND status = 'active'`).
+		WithArgs(expiresAt.AddDate(0, 1, 0), fixedTime, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectExec(`INSERT INTO audit_events`).
+		WithArgs("system", "subscriptions", "subscription.renewed", "subscription", "1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// The second one cannot, and loses the subscription
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(e.amount_cents\), 0\) FROM wallet_entries`).
+		WithArgs("user-2").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(100))
+	mock.ExpectRollback()
+
+	mock.ExpectExec(`UPDATE subscriptions SET status = 'expired', updated_at = \$1 WHERE id = \$2 AND status = 'active'`).
+		WithArgs(fixedTime, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO notifications \(user_id, kind, payload\)`).
+		WithArgs("user-2", "subscription.expired", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO audit_events`).
+		WithArgs("system", "subscriptions", "subscription.expired", "subscription", "2", sqlmock.AnyArg(), sqlmock.AnyArg(), "insufficient wallet balance", sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Call the function to test
+	RenewSubscriptions(db, clk)
+
+	// Assert that all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %v", err)
+	}
+}
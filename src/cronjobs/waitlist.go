@@ -0,0 +1,155 @@
+package cronjobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/notifications"
+	"github.com/yourusername/bike-rental/src/tracing"
+)
+
+// defaultWaitlistHold is how long a bike is held for a waiting user when
+// not configured
+const defaultWaitlistHold = 15 * time.Minute
+
+// OfferBikesToWaitlist holds the bikes available again at a station, once
+// their cooldown ended, for the first user waiting there whose wallet holds
+// minimumBalance. The user is notified and has hold to claim the bike, after
+// which ExpireReservations releases it.
+func OfferBikesToWaitlist(db *sql.DB, clk clock.Clock, hold time.Duration, minimumBalance int64) {
+	ctx, span := tracing.Tracer("cronjobs").Start(context.Background(), "cron OfferBikesToWaitlist")
+	defer span.End()
+
+	if hold <= 0 {
+		hold = defaultWaitlistHold
+	}
+
+	// The head of the queue of each station, skipping users who got a bike
+	// in the meantime or hold one already, a user having one reservation at
+	// most, and users who could not pay for the ride
+	query := `SELECT DISTINCT ON (w.station_id) w.id, w.station_id, w.user_id
+	          FROM waitlist_entries w
+	          WHERE w.status = 'waiting'
+	            AND NOT EXISTS (SELECT 1 FROM assignments a WHERE a.user_id = w.user_id AND a.unassigned_at IS NULL)
+	            AND NOT EXISTS (SELECT 1 FROM reservations r WHERE r.user_id = w.user_id AND r.status = 'active')
+	            AND (SELECT COALESCE(SUM(e.amount_cents), 0)
+	                 FROM wallet_entries e
+	                 INNER JOIN wallet_accounts wa ON wa.id = e.account_id
+	                 WHERE wa.user_id = w.user_id) >= $1
+	          ORDER BY w.station_id, w.joined_at, w.id`
+	rows, err := db.QueryContext(ctx, query, minimumBalance)
+	if err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to fetch waitlists")
+		return
+	}
+	defer rows.Close()
+
+	var entries []models.WaitlistEntry
+	for rows.Next() {
+		var entry models.WaitlistEntry
+		if err := rows.Scan(&entry.ID, &entry.StationID, &entry.UserID); err != nil {
+			logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to scan waitlist entry")
+			return
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Msg("Failed to fetch waitlists")
+		return
+	}
+
+	offered := 0
+	for _, entry := range entries {
+		if err := offerBike(ctx, db, entry, hold, clk.Now()); err != nil {
+			if err != sql.ErrNoRows {
+				logger.For("cronjobs").Err(err).Ctx(ctx).Str("station", entry.StationID).Str("user", entry.UserID).Msg("Failed to offer bike to waiting user")
+			}
+			continue
+		}
+		offered++
+	}
+
+	if offered > 0 {
+		logger.For("cronjobs").Info().Ctx(ctx).Int("count", offered).Msg("Offered bikes to waiting users")
+	}
+}
+
+// offerBike holds the least used available bike of the station for the
+// user of entry at time now, ties going to the lowest ID as in the API. It
+// returns sql.ErrNoRows if there is none, or if the user left meanwhile.
+func offerBike(ctx context.Context, db *sql.DB, entry models.WaitlistEntry, hold time.Duration, now time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var bikeID string
+	query := `SELECT id FROM bikes
+	          WHERE station_id = $1 AND is_assigned = false AND status = 'in_service' AND available_from <= $2
+	            AND NOT EXISTS (SELECT 1 FROM reservations WHERE reservations.bike_id = bikes.id AND reservations.status = 'active')
+	          ORDER BY usage_count, id LIMIT 1`
+	if err := tx.QueryRowContext(ctx, query, entry.StationID, now).Scan(&bikeID); err != nil {
+		return err
+	}
+
+	reservation := models.Reservation{
+		UserID:     entry.UserID,
+		BikeID:     bikeID,
+		Status:     models.ReservationActive,
+		ReservedAt: now,
+		ExpiresAt:  now.Add(hold),
+	}
+	query = `INSERT INTO reservations (user_id, bike_id, status, reserved_at, expires_at, created_at)
+	         VALUES ($1, $2, $3, $4, $5, $4)
+	         RETURNING id`
+	if err := tx.QueryRowContext(ctx, query, reservation.UserID, reservation.BikeID, reservation.Status, reservation.ReservedAt, reservation.ExpiresAt).Scan(&reservation.ID); err != nil {
+		return err
+	}
+
+	query = "UPDATE waitlist_entries SET status = 'offered', reservation_id = $1, updated_at = $2 WHERE id = $3 AND status = 'waiting'"
+	result, err := tx.ExecContext(ctx, query, reservation.ID, now, entry.ID)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"station_id":     entry.StationID,
+		"bike_id":        reservation.BikeID,
+		"reservation_id": reservation.ID,
+		"expires_at":     reservation.ExpiresAt,
+	}
+	if err := notifications.Enqueue(ctx, db, entry.UserID, notifications.KindWaitlistBikeHeld, payload); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Str("user", entry.UserID).Msg("Failed to enqueue notification")
+	}
+
+	event := audit.Event{
+		OccurredAt: now,
+		Actor:      audit.System("waitlist"),
+		Action:     audit.ActionWaitlistOffered,
+		EntityType: audit.EntityWaitlist,
+		EntityID:   fmt.Sprint(entry.ID),
+		Before:     map[string]interface{}{"status": models.WaitlistWaiting},
+		After:      map[string]interface{}{"status": models.WaitlistOffered, "reservation": reservation},
+	}
+	if err := audit.Record(ctx, db, event); err != nil {
+		logger.For("cronjobs").Err(err).Ctx(ctx).Uint("entry", entry.ID).Msg("Failed to record audit event")
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package cronjobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/yourusername/bike-rental/src/clock"
+)
+
+func TestOfferBikesToWaitlist(t *testing.T) {
+	// Create a new mock database
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Use a fixed time for testing
+	fixedTime := time.Date(2024, 8, 20, 7, 19, 48, 0, time.UTC)
+	clk := clock.NewFake(fixedTime)
+
+	// Two stations have users waiting, a bike is available again at the first
+	// one only
+	mock.ExpectQuery(`SELECT DISTINCT ON \(w.station_id\) w.id, w.station_id, w.user_id FROM waitlist_entries w WHERE w.status = 'waiting' (.+) AND NOT EXISTS \(SELECT 1 FROM reservations r WHERE r.user_id = w.user_id AND r.status = 'active'\) AND \(SELECT COALESCE\(SUM\(e.amount_cents\), 0\) (.+)\) >= \$1`).
+		WithArgs(500).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "station_id", "user_id"}).
+			AddRow(4, "station-1", "user-1").
+			AddRow(9, "station-2", "user-2"))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM bikes WHERE station_id = \$1 AND is_assigned = false AND status = 'in_service' AND available_from <= \$2 (.+) ORDER BY usage_count, id LIMIT 1`).
+		WithArgs("station-1", fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("bike-1"))
+
+	mock.ExpectQuery(`INSERT INTO reservations \(user_id, bike_id, status, reserved_at, expires_at, created_at\)`).
+		WithArgs("user-1", "bike-1", "active", fixedTime, fixedTime.Add(10*time.Minute)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	mock.ExpectExec(`UPDATE waitlist_entries SET status = 'offered', reservation_id = \$1, updated_at = \$2 WHERE id = \$3 AND status = 'waiting'`).
+		WithArgs(7, fixedTime, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectExec(`INSERT INTO notifications \(user_id, kind, payload\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs("user-1", "waitlist.bike_held", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(`INSERT INTO audit_events`).
+		WithArgs("system", "waitlist", "waitlist.offered", "waitlist_entry", "4", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), fixedTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM bikes WHERE station_id = \$1`).
+		WithArgs("station-2", fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	// Call the function to test
+	OfferBikesToWaitlist(db, clk, 10*time.Minute, 500)
+
+	// Assert that all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %v", err)
+	}
+}
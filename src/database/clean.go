@@ -7,20 +7,37 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// CleanDatabase deletes all records from the database tables
-func CleanDatabase(db *sql.DB) {
+// CleanDatabase deletes the users, the fleet and the rides, the audit log
+// being append-only
+func CleanDatabase(db *sql.DB) error {
 	tables := []string{
+		"invoice_lines",
+		"invoices",
+		"invoice_counters",
+		"receipts",
+		"supervisor_tasks",
+		"bike_reports",
+		"wallet_entries",
+		"wallet_transactions",
+		"subscriptions",
+		"notifications",
+		"waitlist_entries",
+		"reservations",
+		"rebalancing_moves",
 		"assignments",
+		"docks",
 		"bikes",
+		"stations",
 		"users",
 	}
 
 	for _, table := range tables {
 		query := fmt.Sprintf("DELETE FROM %s", table)
 		if _, err := db.Exec(query); err != nil {
-			log.Err(err).Msg("Failed to clean table")
+			return fmt.Errorf("failed to clean table %s: %w", table, err)
 		}
 	}
 
 	log.Info().Msg("All records deleted successfully")
+	return nil
 }
@@ -3,18 +3,14 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"os"
 
-	"github.com/BurntSushi/toml"
 	_ "github.com/golang-migrate/migrate/v4/source/file" // This import is critical for using the "file" source driver
 	_ "github.com/lib/pq"                                // Import the PostgreSQL driver
+	"github.com/yourusername/bike-rental/src/tracing"
 )
 
+// Config holds the [database] section of the configuration file
 type Config struct {
-	Database DatabaseConfig `toml:"database"`
-}
-
-type DatabaseConfig struct {
 	User     string `toml:"user"`
 	Password string `toml:"password"`
 	DBName   string `toml:"dbname"`
@@ -23,35 +19,16 @@ type DatabaseConfig struct {
 	SSLMode  string `toml:"sslmode"`
 }
 
-func LoadConfig(path string) (*Config, error) {
-	config := &Config{}
-
-	// Open the config file
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	// Parse the config file
-	_, err = toml.NewDecoder(file).Decode(config)
-	if err != nil {
-		return nil, err
-	}
-
-	return config, nil
-}
-
 // InitDB initializes and returns a database connection using database/sql
-func InitDB(config *DatabaseConfig) (*sql.DB, error) {
+func InitDB(config *Config) (*sql.DB, error) {
 	// Build the DSN (Data Source Name)
 	dsn := fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
 		config.Host, config.User, config.Password, config.DBName, config.Port, config.SSLMode,
 	)
 
-	// Connect to the database
-	db, err := sql.Open("postgres", dsn)
+	// Connect to the database, every statement being traced
+	db, err := tracing.OpenDB("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to the database: %w", err)
 	}
@@ -2,55 +2,107 @@ package database
 
 import (
 	"database/sql"
-	"fmt"
+	"errors"
+	"io/fs"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
-	"github.com/rs/zerolog/log"
+	"github.com/golang-migrate/migrate/v4/source"
 )
 
-func Migrate(config *DatabaseConfig) {
-	// Build the DSN (Data Source Name)
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
-		config.Host, config.User, config.Password, config.DBName, config.Port, config.SSLMode,
-	)
+// MigrationsPath is the source of the migrations, relative to the root of
+// the repository
+const MigrationsPath = "file://src/database/migrations"
 
-	// Connect to the database
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		log.Err(err).Msg("Failed to connect to the database")
-	}
-	defer db.Close()
-
-	// Run migrations
-	if err := runMigrations(db, "file://src/database/migrations"); err != nil {
-		log.Err(err).Msg("Failed to run migrations")
-	}
+// Migrator applies the migrations of a source to the database
+type Migrator struct {
+	m              *migrate.Migrate
+	migrationsPath string
+}
 
-	log.Info().Msg("Migrations applied successfully")
+// MigrationStatus is the version of the schema
+type MigrationStatus struct {
+	Version uint // 0 when no migration was applied
+	Dirty   bool // the last migration failed halfway and must be fixed by hand
+	Latest  uint // the last migration of the source
+	Pending int  // migrations of the source newer than Version
 }
 
-func runMigrations(db *sql.DB, migrationsPath string) error {
+// NewMigrator returns a migrator of db to the migrations found at
+// migrationsPath, a golang-migrate source URL such as MigrationsPath
+func NewMigrator(db *sql.DB, migrationsPath string) (*Migrator, error) {
 	// Create an instance of the Postgres driver
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Create a new migrate instance
-	m, err := migrate.NewWithDatabaseInstance(
-		migrationsPath,
-		"postgres", driver)
+	m, err := migrate.NewWithDatabaseInstance(migrationsPath, "postgres", driver)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return &Migrator{m: m, migrationsPath: migrationsPath}, nil
+}
 
-	// Run the migrations
-	err = m.Up()
-	if err != nil && err != migrate.ErrNoChange {
-		return err
+// Up applies every pending migration
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Down reverts the last steps migrations
+func (m *Migrator) Down(steps int) error {
+	return ignoreNoChange(m.m.Steps(-steps))
+}
+
+// To migrates up or down to version
+func (m *Migrator) To(version uint) error {
+	return ignoreNoChange(m.m.Migrate(version))
+}
+
+// Status returns the version of the schema and the migrations left to apply
+func (m *Migrator) Status() (MigrationStatus, error) {
+	var status MigrationStatus
+	version, dirty, err := m.m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return status, err
+	}
+	status.Version, status.Dirty = version, dirty
+
+	// Walk the migrations of the source
+	src, err := source.Open(m.migrationsPath)
+	if err != nil {
+		return status, err
+	}
+	defer src.Close()
+
+	next, err := src.First()
+	for err == nil {
+		status.Latest = next
+		if next > status.Version {
+			status.Pending++
+		}
+		next, err = src.Next(next)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return status, err
 	}
+	return status, nil
+}
+
+func ignoreNoChange(err error) error {
+	if err == migrate.ErrNoChange {
+		return nil
+	}
+	return err
+}
 
-	return nil
+// RunMigrations applies the migrations found at migrationsPath, a
+// golang-migrate source URL such as MigrationsPath
+func RunMigrations(db *sql.DB, migrationsPath string) error {
+	migrator, err := NewMigrator(db, migrationsPath)
+	if err != nil {
+		return err
+	}
+	return migrator.Up()
 }
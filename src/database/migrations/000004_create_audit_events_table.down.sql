@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.audit_events CASCADE;
+DROP FUNCTION IF EXISTS public.audit_events_append_only() CASCADE;
+DROP SEQUENCE IF EXISTS public.audit_events_id_seq CASCADE;
@@ -0,0 +1,36 @@
+CREATE SEQUENCE public.audit_events_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+CREATE TABLE public.audit_events (
+    id bigint NOT NULL DEFAULT nextval('public.audit_events_id_seq'::regclass),
+    occurred_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actor_type character varying(50) NOT NULL,
+    actor_id character varying(255),
+    action character varying(100) NOT NULL,
+    entity_type character varying(50) NOT NULL,
+    entity_id character varying(255) NOT NULL,
+    before jsonb,
+    after jsonb,
+    reason text,
+    request_id character varying(255),
+    CONSTRAINT audit_events_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_audit_events_entity ON public.audit_events USING btree (entity_type, entity_id);
+CREATE INDEX idx_audit_events_actor ON public.audit_events USING btree (actor_type, actor_id);
+CREATE INDEX idx_audit_events_occurred_at ON public.audit_events USING btree (occurred_at);
+
+-- The audit log is append-only
+CREATE FUNCTION public.audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON public.audit_events
+    FOR EACH ROW EXECUTE PROCEDURE public.audit_events_append_only();
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.reservations CASCADE;
+DROP SEQUENCE IF EXISTS public.reservations_id_seq CASCADE;
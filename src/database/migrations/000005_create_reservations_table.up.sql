@@ -0,0 +1,25 @@
+CREATE SEQUENCE public.reservations_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+CREATE TABLE public.reservations (
+    id bigint NOT NULL DEFAULT nextval('public.reservations_id_seq'::regclass),
+    user_id uuid NOT NULL,
+    bike_id uuid NOT NULL,
+    status character varying(20) NOT NULL DEFAULT 'active',
+    reserved_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT reservations_pkey PRIMARY KEY (id)
+);
+
+-- A bike is held for a single user, and a user holds a single bike
+CREATE UNIQUE INDEX idx_reservations_active_bike ON public.reservations USING btree (bike_id) WHERE status = 'active';
+CREATE UNIQUE INDEX idx_reservations_active_user ON public.reservations USING btree (user_id) WHERE status = 'active';
+CREATE INDEX idx_reservations_expires_at ON public.reservations USING btree (expires_at) WHERE status = 'active';
+CREATE INDEX idx_reservations_deleted_at ON public.reservations USING btree (deleted_at);
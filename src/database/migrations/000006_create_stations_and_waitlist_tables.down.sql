@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS public.notifications CASCADE;
+DROP SEQUENCE IF EXISTS public.notifications_id_seq CASCADE;
+DROP TABLE IF EXISTS public.waitlist_entries CASCADE;
+DROP SEQUENCE IF EXISTS public.waitlist_entries_id_seq CASCADE;
+ALTER TABLE IF EXISTS public.bikes DROP COLUMN IF EXISTS station_id;
+DROP TABLE IF EXISTS public.stations CASCADE;
@@ -0,0 +1,57 @@
+CREATE TABLE public.stations (
+    id uuid NOT NULL,
+    name character varying(255) NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT uni_stations_id PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_stations_deleted_at ON public.stations USING btree (deleted_at);
+
+-- The docking station a bike is parked at
+ALTER TABLE public.bikes ADD COLUMN station_id uuid REFERENCES public.stations (id);
+
+CREATE INDEX idx_bikes_station_id ON public.bikes USING btree (station_id);
+
+CREATE SEQUENCE public.waitlist_entries_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+CREATE TABLE public.waitlist_entries (
+    id bigint NOT NULL DEFAULT nextval('public.waitlist_entries_id_seq'::regclass),
+    station_id uuid NOT NULL REFERENCES public.stations (id),
+    user_id uuid NOT NULL,
+    status character varying(20) NOT NULL DEFAULT 'waiting',
+    joined_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reservation_id bigint REFERENCES public.reservations (id),
+    updated_at timestamp with time zone,
+    CONSTRAINT waitlist_entries_pkey PRIMARY KEY (id)
+);
+
+-- A user waits in a single queue at a time
+CREATE UNIQUE INDEX idx_waitlist_entries_pending_user ON public.waitlist_entries USING btree (user_id) WHERE status IN ('waiting', 'offered');
+CREATE INDEX idx_waitlist_entries_queue ON public.waitlist_entries USING btree (station_id, joined_at, id) WHERE status = 'waiting';
+
+CREATE SEQUENCE public.notifications_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+-- Outbox of the notifications to deliver to users
+CREATE TABLE public.notifications (
+    id bigint NOT NULL DEFAULT nextval('public.notifications_id_seq'::regclass),
+    user_id uuid NOT NULL,
+    kind character varying(100) NOT NULL,
+    payload jsonb,
+    created_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at timestamp with time zone,
+    CONSTRAINT notifications_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_notifications_unsent ON public.notifications USING btree (created_at) WHERE sent_at IS NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS public.assignments DROP COLUMN IF EXISTS end_station_id;
+ALTER TABLE IF EXISTS public.assignments DROP COLUMN IF EXISTS start_station_id;
+ALTER TABLE IF EXISTS public.stations DROP COLUMN IF EXISTS capacity;
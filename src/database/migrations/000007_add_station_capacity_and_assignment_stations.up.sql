@@ -0,0 +1,6 @@
+-- Number of dock slots of a station
+ALTER TABLE public.stations ADD COLUMN capacity integer NOT NULL DEFAULT 20;
+
+-- The stations a bike was taken from and returned to
+ALTER TABLE public.assignments ADD COLUMN start_station_id uuid REFERENCES public.stations (id);
+ALTER TABLE public.assignments ADD COLUMN end_station_id uuid REFERENCES public.stations (id);
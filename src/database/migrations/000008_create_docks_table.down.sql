@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.docks CASCADE;
+DROP SEQUENCE IF EXISTS public.docks_id_seq CASCADE;
@@ -0,0 +1,36 @@
+CREATE SEQUENCE public.docks_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+-- The dock slots of the stations, and the bike locked in each
+CREATE TABLE public.docks (
+    id bigint NOT NULL DEFAULT nextval('public.docks_id_seq'::regclass),
+    station_id uuid NOT NULL REFERENCES public.stations (id),
+    slot integer NOT NULL,
+    state character varying(20) NOT NULL DEFAULT 'free',
+    bike_id uuid REFERENCES public.bikes (id),
+    updated_at timestamp with time zone,
+    CONSTRAINT docks_pkey PRIMARY KEY (id),
+    CONSTRAINT uni_docks_station_slot UNIQUE (station_id, slot),
+    CONSTRAINT uni_docks_bike_id UNIQUE (bike_id),
+    CONSTRAINT chk_docks_occupied CHECK ((state = 'occupied') = (bike_id IS NOT NULL))
+);
+
+CREATE INDEX idx_docks_free ON public.docks USING btree (station_id, slot) WHERE state = 'free';
+
+-- One dock per slot of the existing stations
+INSERT INTO public.docks (station_id, slot)
+SELECT s.id, generate_series(1, s.capacity) FROM public.stations s;
+
+-- Bikes parked at a station are locked in its first slots
+WITH parked AS (
+    SELECT id, station_id, row_number() OVER (PARTITION BY station_id ORDER BY id) AS slot
+    FROM public.bikes
+    WHERE station_id IS NOT NULL AND is_assigned = false
+)
+UPDATE public.docks SET state = 'occupied', bike_id = parked.id
+FROM parked
+WHERE docks.station_id = parked.station_id AND docks.slot = parked.slot;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.rebalancing_moves CASCADE;
+DROP SEQUENCE IF EXISTS public.rebalancing_moves_id_seq CASCADE;
@@ -0,0 +1,19 @@
+CREATE SEQUENCE public.rebalancing_moves_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+-- Bikes relocated between stations by a van crew
+CREATE TABLE public.rebalancing_moves (
+    id bigint NOT NULL DEFAULT nextval('public.rebalancing_moves_id_seq'::regclass),
+    bike_id uuid NOT NULL REFERENCES public.bikes (id),
+    from_station_id uuid NOT NULL REFERENCES public.stations (id),
+    to_station_id uuid NOT NULL REFERENCES public.stations (id),
+    operator_id uuid NOT NULL,
+    moved_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT rebalancing_moves_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_rebalancing_moves_moved_at ON public.rebalancing_moves USING btree (moved_at);
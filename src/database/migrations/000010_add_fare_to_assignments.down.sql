@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS public.assignments DROP COLUMN IF EXISTS fare_currency;
+ALTER TABLE IF EXISTS public.assignments DROP COLUMN IF EXISTS fare_cents;
@@ -0,0 +1,3 @@
+-- Price of the ride, in cents of the currency, set on unassignment
+ALTER TABLE public.assignments ADD COLUMN fare_cents bigint;
+ALTER TABLE public.assignments ADD COLUMN fare_currency character varying(3);
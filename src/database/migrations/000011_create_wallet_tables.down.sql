@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS public.wallet_entries CASCADE;
+DROP SEQUENCE IF EXISTS public.wallet_entries_id_seq CASCADE;
+DROP FUNCTION IF EXISTS public.wallet_entries_balanced();
+DROP TABLE IF EXISTS public.wallet_transactions CASCADE;
+DROP SEQUENCE IF EXISTS public.wallet_transactions_id_seq CASCADE;
+DROP TABLE IF EXISTS public.wallet_accounts CASCADE;
+DROP SEQUENCE IF EXISTS public.wallet_accounts_id_seq CASCADE;
@@ -0,0 +1,73 @@
+CREATE SEQUENCE public.wallet_accounts_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+-- Accounts of the ledger: one per user, and the system accounts money
+-- comes from or goes to
+CREATE TABLE public.wallet_accounts (
+    id bigint NOT NULL DEFAULT nextval('public.wallet_accounts_id_seq'::regclass),
+    user_id uuid,
+    name character varying(50),
+    created_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT wallet_accounts_pkey PRIMARY KEY (id),
+    CONSTRAINT uni_wallet_accounts_user_id UNIQUE (user_id),
+    CONSTRAINT uni_wallet_accounts_name UNIQUE (name),
+    CONSTRAINT chk_wallet_accounts_owner CHECK ((user_id IS NULL) <> (name IS NULL))
+);
+
+INSERT INTO public.wallet_accounts (name) VALUES ('topups'), ('revenue');
+
+CREATE SEQUENCE public.wallet_transactions_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+CREATE TABLE public.wallet_transactions (
+    id bigint NOT NULL DEFAULT nextval('public.wallet_transactions_id_seq'::regclass),
+    kind character varying(20) NOT NULL,
+    assignment_id bigint REFERENCES public.assignments (id),
+    description text,
+    created_by character varying(255),
+    created_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT wallet_transactions_pkey PRIMARY KEY (id)
+);
+
+CREATE SEQUENCE public.wallet_entries_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+-- Amounts in cents, credited to the account when positive and debited when
+-- negative. The entries of a transaction sum to zero.
+CREATE TABLE public.wallet_entries (
+    id bigint NOT NULL DEFAULT nextval('public.wallet_entries_id_seq'::regclass),
+    transaction_id bigint NOT NULL REFERENCES public.wallet_transactions (id),
+    account_id bigint NOT NULL REFERENCES public.wallet_accounts (id),
+    amount_cents bigint NOT NULL,
+    CONSTRAINT wallet_entries_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_wallet_entries_account ON public.wallet_entries USING btree (account_id, id);
+CREATE INDEX idx_wallet_entries_transaction ON public.wallet_entries USING btree (transaction_id);
+
+-- Checked at commit, once every entry of the transaction is inserted
+CREATE FUNCTION public.wallet_entries_balanced() RETURNS trigger AS $$
+BEGIN
+    IF (SELECT SUM(amount_cents) FROM public.wallet_entries WHERE transaction_id = NEW.transaction_id) <> 0 THEN
+        RAISE EXCEPTION 'wallet transaction % is not balanced', NEW.transaction_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER wallet_entries_balanced
+    AFTER INSERT OR UPDATE ON public.wallet_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE PROCEDURE public.wallet_entries_balanced();
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.subscriptions CASCADE;
+DROP SEQUENCE IF EXISTS public.subscriptions_id_seq CASCADE;
+DROP TABLE IF EXISTS public.plans CASCADE;
@@ -0,0 +1,45 @@
+-- Subscription plans and their entitlements: rides up to included_minutes
+-- long are free of charge, and max_rentals bikes can be ridden at once
+CREATE TABLE public.plans (
+    id character varying(50) NOT NULL,
+    name character varying(255) NOT NULL,
+    period character varying(10) NOT NULL,
+    price_cents bigint NOT NULL,
+    included_minutes integer NOT NULL DEFAULT 0,
+    max_rentals integer NOT NULL DEFAULT 1,
+    CONSTRAINT plans_pkey PRIMARY KEY (id),
+    CONSTRAINT chk_plans_period CHECK (period IN ('month', 'year')),
+    CONSTRAINT chk_plans_max_rentals CHECK (max_rentals >= 1)
+);
+
+INSERT INTO public.plans (id, name, period, price_cents, included_minutes, max_rentals) VALUES
+    ('monthly', 'Monthly pass', 'month', 1990, 45, 1),
+    ('annual', 'Annual pass', 'year', 19900, 45, 2),
+    ('student', 'Student pass', 'month', 990, 45, 1);
+
+CREATE SEQUENCE public.subscriptions_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+-- A subscription covers from started_at to expires_at, pushed back by each
+-- renewal
+CREATE TABLE public.subscriptions (
+    id bigint NOT NULL DEFAULT nextval('public.subscriptions_id_seq'::regclass),
+    user_id uuid NOT NULL REFERENCES public.users (id),
+    plan_id character varying(50) NOT NULL REFERENCES public.plans (id),
+    status character varying(20) NOT NULL DEFAULT 'active',
+    auto_renew boolean NOT NULL DEFAULT true,
+    started_at timestamp with time zone NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp with time zone,
+    CONSTRAINT subscriptions_pkey PRIMARY KEY (id),
+    CONSTRAINT chk_subscriptions_status CHECK (status IN ('active', 'expired'))
+);
+
+-- A user has one active subscription at most
+CREATE UNIQUE INDEX uni_subscriptions_active_user ON public.subscriptions USING btree (user_id) WHERE status = 'active';
+CREATE INDEX idx_subscriptions_expires_at ON public.subscriptions USING btree (expires_at) WHERE status = 'active';
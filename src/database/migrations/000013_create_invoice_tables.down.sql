@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS public.invoice_lines CASCADE;
+DROP SEQUENCE IF EXISTS public.invoice_lines_id_seq CASCADE;
+DROP TABLE IF EXISTS public.invoices CASCADE;
+DROP SEQUENCE IF EXISTS public.invoices_id_seq CASCADE;
+DROP TABLE IF EXISTS public.invoice_counters CASCADE;
+DROP TABLE IF EXISTS public.receipts CASCADE;
+DROP SEQUENCE IF EXISTS public.receipts_id_seq CASCADE;
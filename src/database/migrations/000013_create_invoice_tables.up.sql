@@ -0,0 +1,85 @@
+CREATE SEQUENCE public.receipts_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+-- The priced summary of a closed assignment, kept as charged
+CREATE TABLE public.receipts (
+    id bigint NOT NULL DEFAULT nextval('public.receipts_id_seq'::regclass),
+    assignment_id bigint NOT NULL REFERENCES public.assignments (id),
+    user_id uuid NOT NULL,
+    bike_id uuid NOT NULL,
+    start_station_id uuid,
+    end_station_id uuid,
+    started_at timestamp with time zone NOT NULL,
+    ended_at timestamp with time zone NOT NULL,
+    plan_id character varying(50),
+    minutes bigint NOT NULL DEFAULT 0,
+    unlock_cents bigint NOT NULL DEFAULT 0,
+    time_cents bigint NOT NULL DEFAULT 0,
+    penalty_cents bigint NOT NULL DEFAULT 0,
+    total_cents bigint NOT NULL DEFAULT 0,
+    currency character(3) NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT receipts_pkey PRIMARY KEY (id),
+    CONSTRAINT uni_receipts_assignment_id UNIQUE (assignment_id)
+);
+
+CREATE INDEX idx_receipts_user_ended_at ON public.receipts USING btree (user_id, ended_at);
+
+-- Last invoice number issued per year, incremented in the transaction
+-- issuing the invoice so that numbers have no gaps
+CREATE TABLE public.invoice_counters (
+    year integer NOT NULL,
+    last_number integer NOT NULL,
+    CONSTRAINT invoice_counters_pkey PRIMARY KEY (year)
+);
+
+CREATE SEQUENCE public.invoices_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+-- Monthly invoice of a user, from period_start to period_end excluded
+CREATE TABLE public.invoices (
+    id bigint NOT NULL DEFAULT nextval('public.invoices_id_seq'::regclass),
+    number character varying(20) NOT NULL,
+    user_id uuid NOT NULL,
+    period_start timestamp with time zone NOT NULL,
+    period_end timestamp with time zone NOT NULL,
+    currency character(3) NOT NULL,
+    rides integer NOT NULL DEFAULT 0,
+    unlock_cents bigint NOT NULL DEFAULT 0,
+    time_cents bigint NOT NULL DEFAULT 0,
+    penalty_cents bigint NOT NULL DEFAULT 0,
+    subscription_cents bigint NOT NULL DEFAULT 0,
+    total_cents bigint NOT NULL DEFAULT 0,
+    issued_at timestamp with time zone NOT NULL,
+    CONSTRAINT invoices_pkey PRIMARY KEY (id),
+    CONSTRAINT uni_invoices_number UNIQUE (number),
+    CONSTRAINT uni_invoices_user_period UNIQUE (user_id, period_start)
+);
+
+CREATE SEQUENCE public.invoice_lines_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+CREATE TABLE public.invoice_lines (
+    id bigint NOT NULL DEFAULT nextval('public.invoice_lines_id_seq'::regclass),
+    invoice_id bigint NOT NULL REFERENCES public.invoices (id),
+    kind character varying(20) NOT NULL,
+    description text NOT NULL,
+    assignment_id bigint REFERENCES public.assignments (id),
+    occurred_at timestamp with time zone NOT NULL,
+    amount_cents bigint NOT NULL,
+    CONSTRAINT invoice_lines_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_invoice_lines_invoice ON public.invoice_lines USING btree (invoice_id, id);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS public.supervisor_tasks CASCADE;
+DROP SEQUENCE IF EXISTS public.supervisor_tasks_id_seq CASCADE;
+
+ALTER TABLE public.assignments DROP COLUMN IF EXISTS overdue_at;
+ALTER TABLE public.assignments DROP COLUMN IF EXISTS warned_at;
+
+ALTER TABLE public.bikes DROP CONSTRAINT IF EXISTS chk_bikes_status;
+ALTER TABLE public.bikes DROP COLUMN IF EXISTS status;
@@ -0,0 +1,34 @@
+-- A bike still out 24 hours after its assignment is suspected lost until
+-- it is docked again
+ALTER TABLE public.bikes ADD COLUMN status character varying(20) NOT NULL DEFAULT 'in_service';
+ALTER TABLE public.bikes ADD CONSTRAINT chk_bikes_status CHECK (status IN ('in_service', 'lost_suspected'));
+
+-- Steps of the overdue escalation reached by an open assignment
+ALTER TABLE public.assignments ADD COLUMN warned_at timestamp with time zone;
+ALTER TABLE public.assignments ADD COLUMN overdue_at timestamp with time zone;
+
+CREATE SEQUENCE public.supervisor_tasks_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+-- Follow-ups for the supervisors, such as recovering an overdue bike
+CREATE TABLE public.supervisor_tasks (
+    id bigint NOT NULL DEFAULT nextval('public.supervisor_tasks_id_seq'::regclass),
+    kind character varying(30) NOT NULL,
+    status character varying(20) NOT NULL DEFAULT 'open',
+    bike_id uuid REFERENCES public.bikes (id),
+    assignment_id bigint REFERENCES public.assignments (id),
+    user_id uuid REFERENCES public.users (id),
+    note text,
+    created_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    closed_at timestamp with time zone,
+    closed_by character varying(255),
+    CONSTRAINT supervisor_tasks_pkey PRIMARY KEY (id),
+    CONSTRAINT chk_supervisor_tasks_status CHECK (status IN ('open', 'done'))
+);
+
+CREATE INDEX idx_supervisor_tasks_open ON public.supervisor_tasks USING btree (created_at) WHERE status = 'open';
+CREATE INDEX idx_supervisor_tasks_assignment ON public.supervisor_tasks USING btree (assignment_id);
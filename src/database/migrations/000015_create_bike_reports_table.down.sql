@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS public.bike_reports CASCADE;
+DROP SEQUENCE IF EXISTS public.bike_reports_id_seq CASCADE;
+
+UPDATE public.bikes SET status = 'lost_suspected' WHERE status IN ('lost', 'stolen');
+ALTER TABLE public.bikes DROP CONSTRAINT IF EXISTS chk_bikes_status;
+ALTER TABLE public.bikes ADD CONSTRAINT chk_bikes_status CHECK (status IN ('in_service', 'lost_suspected'));
@@ -0,0 +1,31 @@
+ALTER TABLE public.bikes DROP CONSTRAINT chk_bikes_status;
+ALTER TABLE public.bikes ADD CONSTRAINT chk_bikes_status CHECK (status IN ('in_service', 'lost_suspected', 'lost', 'stolen'));
+
+CREATE SEQUENCE public.bike_reports_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+-- Bikes declared lost or stolen by a supervisor, until they are recovered.
+-- The user riding the bike when it was declared is charged fee_cents.
+CREATE TABLE public.bike_reports (
+    id bigint NOT NULL DEFAULT nextval('public.bike_reports_id_seq'::regclass),
+    bike_id uuid NOT NULL REFERENCES public.bikes (id),
+    kind character varying(20) NOT NULL,
+    report text NOT NULL,
+    assignment_id bigint REFERENCES public.assignments (id),
+    user_id uuid REFERENCES public.users (id),
+    fee_cents bigint NOT NULL DEFAULT 0,
+    reported_by character varying(255) NOT NULL,
+    reported_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    recovered_at timestamp with time zone,
+    recovered_by character varying(255),
+    recovery_station_id uuid REFERENCES public.stations (id),
+    CONSTRAINT bike_reports_pkey PRIMARY KEY (id),
+    CONSTRAINT chk_bike_reports_kind CHECK (kind IN ('lost', 'stolen'))
+);
+
+-- A bike has one open report at most
+CREATE UNIQUE INDEX uni_bike_reports_open_bike ON public.bike_reports USING btree (bike_id) WHERE recovered_at IS NULL;
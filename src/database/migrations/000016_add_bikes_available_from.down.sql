@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS public.idx_bikes_available;
+ALTER TABLE public.bikes DROP COLUMN IF EXISTS available_from;
+
+ALTER TABLE public.bikes ALTER COLUMN last_unassigned TYPE timestamp without time zone USING last_unassigned AT TIME ZONE 'UTC';
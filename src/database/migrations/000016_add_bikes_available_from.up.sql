@@ -0,0 +1,8 @@
+-- last_unassigned held the wall clock of the API, which runs in UTC
+ALTER TABLE public.bikes ALTER COLUMN last_unassigned TYPE timestamp with time zone USING last_unassigned AT TIME ZONE 'UTC';
+
+-- When a bike can be handed over again, after the cooldown of a return or a hold
+ALTER TABLE public.bikes ADD COLUMN available_from timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP;
+UPDATE public.bikes SET available_from = last_unassigned + interval '5 minutes' WHERE last_unassigned IS NOT NULL;
+
+CREATE INDEX idx_bikes_available ON public.bikes USING btree (station_id, available_from) WHERE is_assigned = false AND status = 'in_service';
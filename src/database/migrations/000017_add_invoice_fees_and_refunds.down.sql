@@ -0,0 +1,2 @@
+ALTER TABLE public.invoices DROP COLUMN IF EXISTS refund_cents;
+ALTER TABLE public.invoices DROP COLUMN IF EXISTS replacement_cents;
@@ -0,0 +1,4 @@
+-- Replacement fees charged and refunds credited over the period, the refunds
+-- being negative
+ALTER TABLE public.invoices ADD COLUMN replacement_cents bigint NOT NULL DEFAULT 0;
+ALTER TABLE public.invoices ADD COLUMN refund_cents bigint NOT NULL DEFAULT 0;
@@ -6,9 +6,13 @@ import (
 
 // Assignment represents a record in the assignments table
 type Assignment struct {
-	ID           uint         `json:"id"`
-	UserID       string       `json:"user_id"`
-	BikeID       string       `json:"bike_id"`
-	AssignedAt   sql.NullTime `json:"assigned_at"`
-	UnassignedAt sql.NullTime `json:"unassigned_at"`
+	ID           uint           `json:"id"`
+	UserID       string         `json:"user_id"`
+	BikeID       string         `json:"bike_id"`
+	AssignedAt   sql.NullTime   `json:"assigned_at"`
+	UnassignedAt sql.NullTime   `json:"unassigned_at"`
+	StartStation sql.NullString `json:"start_station_id"`
+	EndStation   sql.NullString `json:"end_station_id"`
+	FareCents    sql.NullInt64  `json:"fare_cents"`
+	FareCurrency sql.NullString `json:"fare_currency"`
 }
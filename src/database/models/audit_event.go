@@ -0,0 +1,22 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent represents a record in the append-only audit_events table
+type AuditEvent struct {
+	ID         uint            `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	ActorType  string          `json:"actor_type"`
+	ActorID    sql.NullString  `json:"actor_id"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Reason     sql.NullString  `json:"reason"`
+	RequestID  sql.NullString  `json:"request_id"`
+}
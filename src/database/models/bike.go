@@ -2,11 +2,23 @@ package models
 
 import (
 	"database/sql"
+	"time"
+)
+
+// Bike statuses
+const (
+	BikeInService     = "in_service"
+	BikeLostSuspected = "lost_suspected" // not docked 24 hours after its assignment
+	BikeLost          = "lost"
+	BikeStolen        = "stolen"
 )
 
 type Bike struct {
-	ID             string       `json:"id"`
-	UsageCount     int          `json:"usage_count"`
-	LastUnassigned sql.NullTime `json:"last_unassigned"`
-	IsAssigned     bool         `json:"is_assigned"`
+	ID             string         `json:"id"`
+	UsageCount     int            `json:"usage_count"`
+	LastUnassigned sql.NullTime   `json:"last_unassigned"`
+	AvailableFrom  time.Time      `json:"available_from"` // end of the cooldown after a return, or of a hold
+	IsAssigned     bool           `json:"is_assigned"`
+	StationID      sql.NullString `json:"station_id"` // where the bike is parked, or was taken from while assigned
+	Status         string         `json:"status,omitempty"`
 }
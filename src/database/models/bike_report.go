@@ -0,0 +1,23 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// BikeReport represents a record in the bike_reports table, a bike declared
+// lost or stolen and its recovery
+type BikeReport struct {
+	ID                uint           `json:"id"`
+	BikeID            string         `json:"bike_id"`
+	Kind              string         `json:"kind"` // BikeLost or BikeStolen
+	Report            string         `json:"report"`
+	AssignmentID      sql.NullInt64  `json:"assignment_id"` // the ride going on when declared
+	UserID            sql.NullString `json:"user_id"`       // the user held responsible
+	FeeCents          int64          `json:"fee_cents"`
+	ReportedBy        string         `json:"reported_by"`
+	ReportedAt        time.Time      `json:"reported_at"`
+	RecoveredAt       sql.NullTime   `json:"recovered_at"`
+	RecoveredBy       sql.NullString `json:"recovered_by"`
+	RecoveryStationID sql.NullString `json:"recovery_station_id"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// Dock states
+const (
+	DockFree         = "free"
+	DockOccupied     = "occupied"       // a bike is locked in the slot
+	DockOutOfService = "out_of_service" // the slot cannot be used
+)
+
+// Dock represents a record in the docks table, a slot of a station
+type Dock struct {
+	ID        uint           `json:"id"`
+	StationID string         `json:"station_id"`
+	Slot      int            `json:"slot"`
+	State     string         `json:"state"`
+	BikeID    sql.NullString `json:"bike_id"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Invoice line kinds
+const (
+	InvoiceLineRide         = "ride"
+	InvoiceLineSubscription = "subscription"
+	InvoiceLinePenalty      = "overdue_penalty"
+	InvoiceLineReplacement  = "replacement_fee"
+	InvoiceLineRefund       = "refund" // negative
+)
+
+// Receipt represents a record in the receipts table, the priced summary of a
+// closed assignment
+type Receipt struct {
+	ID             uint           `json:"id"`
+	AssignmentID   uint           `json:"assignment_id"`
+	UserID         string         `json:"user_id"`
+	BikeID         string         `json:"bike_id"`
+	StartStationID sql.NullString `json:"start_station_id"`
+	EndStationID   sql.NullString `json:"end_station_id"`
+	StartedAt      time.Time      `json:"started_at"`
+	EndedAt        time.Time      `json:"ended_at"`
+	PlanID         sql.NullString `json:"plan_id"`
+	Minutes        int64          `json:"minutes"`
+	UnlockCents    int64          `json:"unlock_cents"`
+	TimeCents      int64          `json:"time_cents"`
+	PenaltyCents   int64          `json:"penalty_cents"`
+	TotalCents     int64          `json:"total_cents"`
+	Currency       string         `json:"currency"`
+}
+
+// Invoice represents a record in the invoices table, the monthly invoice of
+// a user
+type Invoice struct {
+	ID                uint          `json:"id"`
+	Number            string        `json:"number"`
+	UserID            string        `json:"user_id"`
+	PeriodStart       time.Time     `json:"period_start"`
+	PeriodEnd         time.Time     `json:"period_end"` // excluded
+	Currency          string        `json:"currency"`
+	Rides             int           `json:"rides"`
+	UnlockCents       int64         `json:"unlock_cents"`
+	TimeCents         int64         `json:"time_cents"`
+	PenaltyCents      int64         `json:"penalty_cents"`
+	SubscriptionCents int64         `json:"subscription_cents"`
+	ReplacementCents  int64         `json:"replacement_cents"`
+	RefundCents       int64         `json:"refund_cents"` // negative
+	TotalCents        int64         `json:"total_cents"`
+	IssuedAt          time.Time     `json:"issued_at"`
+	Lines             []InvoiceLine `json:"lines,omitempty"`
+}
+
+// InvoiceLine represents a record in the invoice_lines table, a ride, a
+// subscription payment, an overdue penalty, a replacement fee or a refund of
+// the period
+type InvoiceLine struct {
+	Kind         string        `json:"kind"`
+	Description  string        `json:"description"`
+	AssignmentID sql.NullInt64 `json:"assignment_id"`
+	OccurredAt   time.Time     `json:"occurred_at"`
+	AmountCents  int64         `json:"amount_cents"`
+}
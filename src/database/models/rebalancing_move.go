@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// RebalancingMove represents a record in the rebalancing_moves table, a
+// bike relocated between stations by a van crew
+type RebalancingMove struct {
+	ID            uint      `json:"id"`
+	BikeID        string    `json:"bike_id"`
+	FromStationID string    `json:"from_station_id"`
+	ToStationID   string    `json:"to_station_id"`
+	OperatorID    string    `json:"operator_id"`
+	MovedAt       time.Time `json:"moved_at"`
+}
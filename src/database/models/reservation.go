@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// Reservation statuses
+const (
+	ReservationActive    = "active"
+	ReservationConverted = "converted"
+	ReservationExpired   = "expired"
+	ReservationCancelled = "cancelled"
+)
+
+// Reservation represents a record in the reservations table. An active
+// reservation holds a bike for a user until it expires or is converted
+// into an assignment.
+type Reservation struct {
+	ID         uint      `json:"id"`
+	UserID     string    `json:"user_id"`
+	BikeID     string    `json:"bike_id"`
+	Status     string    `json:"status"`
+	ReservedAt time.Time `json:"reserved_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// Station represents a record in the stations table, a docking station
+type Station struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Capacity int    `json:"capacity"` // number of dock slots
+}
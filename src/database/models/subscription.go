@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Plan periods
+const (
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+)
+
+// Subscription statuses
+const (
+	SubscriptionActive  = "active"
+	SubscriptionExpired = "expired"
+)
+
+// Plan represents a record in the plans table, a subscription offer and its
+// entitlements
+type Plan struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Period          string `json:"period"`
+	PriceCents      int64  `json:"price_cents"`
+	IncludedMinutes int64  `json:"included_minutes"` // rides up to this long are free of charge
+	MaxRentals      int    `json:"max_rentals"`      // bikes the user can ride at once
+}
+
+// Subscription represents a record in the subscriptions table
+type Subscription struct {
+	ID        uint      `json:"id"`
+	UserID    string    `json:"user_id"`
+	PlanID    string    `json:"plan_id"`
+	Status    string    `json:"status"`
+	AutoRenew bool      `json:"auto_renew"`
+	StartedAt time.Time `json:"started_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
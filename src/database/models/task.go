@@ -0,0 +1,32 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Supervisor task kinds
+const (
+	TaskOverdueBike = "overdue_bike"
+)
+
+// Supervisor task statuses
+const (
+	TaskOpen = "open"
+	TaskDone = "done"
+)
+
+// SupervisorTask represents a record in the supervisor_tasks table, a
+// follow-up for the supervisors
+type SupervisorTask struct {
+	ID           uint           `json:"id"`
+	Kind         string         `json:"kind"`
+	Status       string         `json:"status"`
+	BikeID       sql.NullString `json:"bike_id"`
+	AssignmentID sql.NullInt64  `json:"assignment_id"`
+	UserID       sql.NullString `json:"user_id"`
+	Note         sql.NullString `json:"note"`
+	CreatedAt    time.Time      `json:"created_at"`
+	ClosedAt     sql.NullTime   `json:"closed_at"`
+	ClosedBy     sql.NullString `json:"closed_by"`
+}
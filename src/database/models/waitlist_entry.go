@@ -0,0 +1,27 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Waitlist entry statuses
+const (
+	WaitlistWaiting = "waiting" // in the queue
+	WaitlistOffered = "offered" // a bike is held for the user
+	WaitlistClaimed = "claimed" // the user took the held bike
+	WaitlistExpired = "expired" // the user did not claim the held bike in time
+	WaitlistLeft    = "left"    // the user left the queue or got a bike otherwise
+)
+
+// WaitlistEntry represents a record in the waitlist_entries table, a user
+// waiting for a bike at a station
+type WaitlistEntry struct {
+	ID            uint          `json:"id"`
+	StationID     string        `json:"station_id"`
+	UserID        string        `json:"user_id"`
+	Status        string        `json:"status"`
+	JoinedAt      time.Time     `json:"joined_at"`
+	ReservationID sql.NullInt64 `json:"reservation_id"`
+	Position      int           `json:"position,omitempty"`
+}
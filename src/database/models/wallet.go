@@ -0,0 +1,40 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Wallet transaction kinds
+const (
+	WalletTopUp        = "top_up"
+	WalletRideCharge   = "ride_charge"
+	WalletRefund       = "refund"
+	WalletSubscription = "subscription"
+	WalletPenalty      = "overdue_penalty"
+	WalletReplacement  = "replacement_fee"
+)
+
+// WalletTransaction represents a record in the wallet_transactions table, a
+// movement of money between two accounts of the ledger
+type WalletTransaction struct {
+	ID           uint           `json:"id"`
+	Kind         string         `json:"kind"`
+	AmountCents  int64          `json:"amount_cents"`
+	AssignmentID sql.NullInt64  `json:"assignment_id"`
+	Description  sql.NullString `json:"description"`
+	CreatedBy    sql.NullString `json:"created_by"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// WalletStatementLine is an entry of a user account with its transaction,
+// and the balance after it
+type WalletStatementLine struct {
+	TransactionID uint           `json:"transaction_id"`
+	Kind          string         `json:"kind"`
+	AmountCents   int64          `json:"amount_cents"`
+	BalanceCents  int64          `json:"balance_cents"`
+	AssignmentID  sql.NullInt64  `json:"assignment_id"`
+	Description   sql.NullString `json:"description"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
@@ -1,81 +1,137 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/yourusername/bike-rental/src/audit"
 	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/wallet"
 )
 
-func SeedDatabase(db *sql.DB) {
-	// Seed Users
-	users := []models.User{
-		{
-			ID:   "d0ab33d7-8fcc-463d-bade-fefd53b77a96",
-			Name: "Alice",
-			Role: "Customer",
-		},
-		{
-			ID:   "0b28a7ed-39ef-418f-a0e3-8ad3f794dfc7",
-			Name: "Bob",
-			Role: "Customer",
-		},
-		{
-			ID:   "da690323-5a78-4d46-a214-943b2ec9d49e",
-			Name: "Charlie",
-			Role: "Admin",
-		},
+// Fixtures are the records of a seed file, such as
+// src/database/fixtures/dev.json
+type Fixtures struct {
+	Users    []UserFixture    `json:"users"`
+	Stations []models.Station `json:"stations"`
+	Bikes    []BikeFixture    `json:"bikes"`
+}
+
+// UserFixture is a user and the opening balance of their wallet
+type UserFixture struct {
+	models.User
+	BalanceCents int64 `json:"balance_cents,omitempty"`
+}
+
+// BikeFixture is a bike, docked at its station if any
+type BikeFixture struct {
+	ID        string `json:"id"`
+	StationID string `json:"station_id,omitempty"`
+}
+
+// LoadFixtures reads a seed file
+func LoadFixtures(path string) (*Fixtures, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
 
-	for _, user := range users {
+	fixtures := &Fixtures{}
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// Seed inserts the records of fixtures missing from the database, so
+// seeding twice has no effect
+func Seed(db *sql.DB, fixtures *Fixtures) error {
+	for _, user := range fixtures.Users {
 		// Check if the user already exists
 		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", user.ID).Scan(&exists)
-		if err != nil {
+		if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", user.ID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check if user %s exists: %w", user.ID, err)
+		}
+		if exists {
+			continue
+		}
 
-			log.Err(err).Msg("Failed to check if user exists")
+		if _, err := db.Exec("INSERT INTO users (id, name, role) VALUES ($1, $2, $3)", user.ID, user.Name, user.Role); err != nil {
+			return fmt.Errorf("failed to seed user %s: %w", user.ID, err)
 		}
+		recordCreation(db, audit.EntityUser, audit.ActionUserCreated, user.ID, user.User)
 
-		// Insert the user if they don't already exist
-		if !exists {
-			_, err := db.Exec("INSERT INTO users (id, name, role) VALUES ($1, $2, $3)", user.ID, user.Name, user.Role)
-			if err != nil {
-				log.Err(err).Msg("Failed to seed user")
+		// Customers need a balance to be assigned a bike
+		if user.BalanceCents > 0 {
+			transaction := models.WalletTransaction{
+				Kind:        models.WalletTopUp,
+				AmountCents: user.BalanceCents,
+				Description: sql.NullString{String: "Opening balance", Valid: true},
+				CreatedAt:   time.Now(),
+			}
+			if err := wallet.Transfer(context.Background(), db, wallet.System(wallet.AccountTopUps), wallet.User(user.ID), &transaction); err != nil {
+				return fmt.Errorf("failed to seed wallet of user %s: %w", user.ID, err)
 			}
 		}
 	}
 
-	// Seed Bikes
-	bikes := []models.Bike{
-		{
-			ID:         "331e7ffb-e583-4535-ba41-4c28dc34016d",
-			UsageCount: 0,
-			IsAssigned: false,
-		},
-		{
-			ID:         "e4ef2d9b-5d5a-4f85-bb3a-b2df8bf42ac1",
-			UsageCount: 0,
-			IsAssigned: false,
-		},
+	for _, station := range fixtures.Stations {
+		if _, err := db.Exec("INSERT INTO stations (id, name, capacity) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING", station.ID, station.Name, station.Capacity); err != nil {
+			return fmt.Errorf("failed to seed station %s: %w", station.ID, err)
+		}
+
+		// One dock per slot
+		if _, err := db.Exec("INSERT INTO docks (station_id, slot) SELECT $1, generate_series(1, $2) ON CONFLICT (station_id, slot) DO NOTHING", station.ID, station.Capacity); err != nil {
+			return fmt.Errorf("failed to seed docks of station %s: %w", station.ID, err)
+		}
 	}
 
-	for _, bike := range bikes {
+	for _, bike := range fixtures.Bikes {
 		// Check if the bike already exists
 		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM bikes WHERE id = $1)", bike.ID).Scan(&exists)
-		if err != nil {
-			log.Err(err).Msg("Failed to check if bike exists")
+		if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM bikes WHERE id = $1)", bike.ID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check if bike %s exists: %w", bike.ID, err)
+		}
+		if exists {
+			continue
+		}
 
+		station := sql.NullString{String: bike.StationID, Valid: bike.StationID != ""}
+		if _, err := db.Exec("INSERT INTO bikes (id, usage_count, is_assigned, station_id) VALUES ($1, 0, false, $2)", bike.ID, station); err != nil {
+			return fmt.Errorf("failed to seed bike %s: %w", bike.ID, err)
 		}
 
-		// Insert the bike if it doesn't already exist
-		if !exists {
-			_, err := db.Exec("INSERT INTO bikes (id, usage_count, is_assigned) VALUES ($1, $2, $3)", bike.ID, bike.UsageCount, bike.IsAssigned)
-			if err != nil {
-				log.Err(err).Msg("Failed to seed bike")
+		// Lock the bike in the first free dock of its station
+		if station.Valid {
+			if _, err := db.Exec("UPDATE docks SET state = 'occupied', bike_id = $1 WHERE id = (SELECT id FROM docks WHERE station_id = $2 AND state = 'free' ORDER BY slot LIMIT 1)", bike.ID, station); err != nil {
+				return fmt.Errorf("failed to dock bike %s: %w", bike.ID, err)
 			}
 		}
+		recordCreation(db, audit.EntityBike, audit.ActionBikeCreated, bike.ID, bike)
 	}
 
-	log.Info().Msg("Database seeded successfully")
+	log.Info().Int("users", len(fixtures.Users)).Int("stations", len(fixtures.Stations)).Int("bikes", len(fixtures.Bikes)).Msg("Database seeded successfully")
+	return nil
+}
+
+func recordCreation(db *sql.DB, entityType, action, entityID string, entity interface{}) {
+	event := audit.Event{
+		OccurredAt: time.Now(),
+		Actor:      audit.System("seed"),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		After:      entity,
+	}
+	if err := audit.Record(context.Background(), db, event); err != nil {
+		log.Err(err).Msg("Failed to record audit event")
+	}
 }
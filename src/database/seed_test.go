@@ -0,0 +1,33 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	fixtures, err := LoadFixtures("fixtures/dev.json")
+	require.NoError(t, err)
+
+	require.Len(t, fixtures.Users, 3)
+	assert.Equal(t, "Alice", fixtures.Users[0].Name)
+	assert.Equal(t, "Customer", fixtures.Users[0].Role)
+	assert.Equal(t, int64(2000), fixtures.Users[0].BalanceCents)
+	assert.Zero(t, fixtures.Users[2].BalanceCents)
+	require.Len(t, fixtures.Stations, 1)
+	assert.Equal(t, 20, fixtures.Stations[0].Capacity)
+	require.Len(t, fixtures.Bikes, 2)
+	assert.Equal(t, fixtures.Stations[0].ID, fixtures.Bikes[0].StationID)
+}
+
+func TestLoadFixtures_UnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"bikes": [{"id": "bike-1", "station": "central"}]}`), 0o644))
+
+	_, err := LoadFixtures(path)
+	assert.ErrorContains(t, err, `unknown field "station"`)
+}
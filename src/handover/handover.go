@@ -0,0 +1,36 @@
+// Package handover holds the rule choosing the bike handed over to a user
+// among the ones at a station. The API selects the bike in SQL; the
+// simulation replays the same rule on its own fleet.
+package handover
+
+import (
+	"time"
+
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+// Cooldown is how long a returned bike rests before it can be handed over
+const Cooldown = 5 * time.Minute
+
+// Ready reports whether bike can be handed over at now, its cooldown or
+// hold being over
+func Ready(bike models.Bike, now time.Time) bool {
+	return !bike.AvailableFrom.After(now)
+}
+
+// LeastUsed returns the index of the bike handed over at now among bikes:
+// the least used of the ready ones, ties going to the lowest ID, as the API
+// orders them by usage_count, id. It returns -1 when none is ready.
+func LeastUsed(bikes []models.Bike, now time.Time) int {
+	picked := -1
+	for i, bike := range bikes {
+		if !Ready(bike, now) {
+			continue
+		}
+		if picked < 0 || bike.UsageCount < bikes[picked].UsageCount ||
+			(bike.UsageCount == bikes[picked].UsageCount && bike.ID < bikes[picked].ID) {
+			picked = i
+		}
+	}
+	return picked
+}
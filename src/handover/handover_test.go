@@ -0,0 +1,27 @@
+package handover
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func TestLeastUsed(t *testing.T) {
+	now := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+	returned := now.Add(-time.Minute).Add(Cooldown)
+
+	bikes := []models.Bike{
+		{ID: "bike-3", UsageCount: 4, AvailableFrom: now},
+		{ID: "bike-2", UsageCount: 4, AvailableFrom: now.Add(-time.Hour)},
+		{ID: "bike-1", UsageCount: 1, AvailableFrom: returned}, // cooling down
+	}
+	assert.Equal(t, 1, LeastUsed(bikes, now))
+
+	// Once cooled down, the least used bike is handed over
+	assert.Equal(t, 2, LeastUsed(bikes, returned))
+
+	assert.Equal(t, -1, LeastUsed(bikes[2:], now))
+	assert.Equal(t, -1, LeastUsed(nil, now))
+}
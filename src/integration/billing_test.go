@@ -0,0 +1,183 @@
+//go:build integration
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/bike-rental/src/api"
+	"github.com/yourusername/bike-rental/src/cronjobs"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/invoices"
+)
+
+// ride assigns a bike to user and docks it back at Central after duration,
+// returning the closed assignment
+func (e *env) ride(user string, duration time.Duration) models.Assignment {
+	e.t.Helper()
+
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": user}), http.StatusOK, nil)
+
+	var assignments []models.Assignment
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	var ride models.Assignment
+	for _, assignment := range assignments {
+		if assignment.UserID == user && !assignment.UnassignedAt.Valid {
+			ride = assignment
+		}
+	}
+	require.NotZero(e.t, ride.ID, "No open assignment for %s", user)
+
+	e.clock.Advance(duration)
+	e.expect(e.post("/v1/bikes/unassign", map[string]interface{}{"bike_uuid": ride.BikeID, "user_uuid": user, "station_id": central}), http.StatusOK, nil)
+	return ride
+}
+
+// balance returns the wallet balance of a user
+func (e *env) balance(user string) int64 {
+	e.t.Helper()
+
+	var balance api.WalletBalance
+	e.expect(e.get("/v1/users/"+user+"/wallet"), http.StatusOK, &balance)
+	return balance.BalanceCents
+}
+
+func TestWallet(t *testing.T) {
+	e := newEnv(t)
+
+	var transaction models.WalletTransaction
+	e.expect(e.do(http.MethodPost, "/v1/users/"+alice+"/wallet/top-ups", map[string]interface{}{"amount_cents": 500}, charlie), http.StatusCreated, &transaction)
+	assert.Equal(t, models.WalletTopUp, transaction.Kind)
+	assert.Equal(t, int64(openingBalance+500), e.balance(alice))
+
+	e.expect(e.do(http.MethodPost, "/v1/users/"+alice+"/wallet/top-ups", map[string]interface{}{"amount_cents": 0}, charlie), http.StatusBadRequest, nil)
+	e.expect(e.post("/v1/users/"+alice+"/wallet/top-ups", map[string]interface{}{"amount_cents": 500}), http.StatusUnauthorized, nil)
+	e.expect(e.do(http.MethodPost, "/v1/users/"+alice+"/wallet/top-ups", map[string]interface{}{"amount_cents": 500}, alice), http.StatusForbidden, nil)
+	e.expect(e.do(http.MethodPost, "/v1/users/9a0f6a7e-8a55-4c1f-b9a1-3f3b1c6e2d10/wallet/top-ups", map[string]interface{}{"amount_cents": 500}, charlie), http.StatusNotFound, nil)
+	e.expect(e.get("/v1/users/9a0f6a7e-8a55-4c1f-b9a1-3f3b1c6e2d10/wallet"), http.StatusNotFound, nil)
+
+	ride := e.ride(alice, 10*time.Minute)
+	fare := testConfig.Pricing.UnlockFee + 10*testConfig.Pricing.PerMinute
+	assert.Equal(t, openingBalance+500-fare, e.balance(alice))
+
+	// Refunds are made by supervisors, up to the fare of the ride
+	refunds := "/v1/users/" + alice + "/wallet/refunds"
+	refund := map[string]interface{}{"amount_cents": fare, "assignment_id": ride.ID, "reason": "Flat tyre"}
+	e.expect(e.post(refunds, refund), http.StatusUnauthorized, nil)
+	e.expect(e.do(http.MethodPost, refunds, refund, bob), http.StatusForbidden, nil)
+	e.expect(e.do(http.MethodPost, refunds, map[string]interface{}{"amount_cents": fare + 1, "assignment_id": ride.ID, "reason": "Flat tyre"}, charlie), http.StatusBadRequest, nil)
+
+	e.expect(e.do(http.MethodPost, refunds, refund, charlie), http.StatusCreated, &transaction)
+	assert.Equal(t, models.WalletRefund, transaction.Kind)
+	assert.Equal(t, int64(openingBalance+500), e.balance(alice))
+
+	// The fare is refunded already
+	e.expect(e.do(http.MethodPost, refunds, map[string]interface{}{"amount_cents": 1, "assignment_id": ride.ID, "reason": "Flat tyre"}, charlie), http.StatusBadRequest, nil)
+
+	var statement []models.WalletStatementLine
+	e.expect(e.get("/v1/users/"+alice+"/wallet/statement"), http.StatusOK, &statement)
+	require.Len(t, statement, 4)
+	assert.Equal(t, models.WalletRefund, statement[0].Kind)
+	assert.Equal(t, models.WalletRideCharge, statement[1].Kind)
+	e.expect(e.get("/v1/users/"+alice+"/wallet/statement?limit=0"), http.StatusBadRequest, nil)
+}
+
+func TestSubscription(t *testing.T) {
+	e := newEnv(t)
+
+	var plans []models.Plan
+	e.expect(e.get("/v1/plans"), http.StatusOK, &plans)
+	require.Len(t, plans, 3)
+	prices := map[string]int64{}
+	for _, plan := range plans {
+		prices[plan.ID] = plan.PriceCents
+	}
+
+	subscription := "/v1/users/" + alice + "/subscription"
+	e.expect(e.get(subscription), http.StatusNotFound, nil)
+
+	// Paid from the wallet
+	e.expect(e.post("/v1/users/"+bob+"/subscription", map[string]interface{}{"plan_id": "annual"}), http.StatusPaymentRequired, nil)
+	e.expect(e.post(subscription, map[string]interface{}{"plan_id": "weekly"}), http.StatusNotFound, nil)
+
+	var subscribed models.Subscription
+	e.expect(e.post(subscription, map[string]interface{}{"plan_id": "monthly"}), http.StatusCreated, &subscribed)
+	assert.Equal(t, models.SubscriptionActive, subscribed.Status)
+	assert.True(t, subscribed.AutoRenew)
+	assert.Equal(t, openingBalance-prices["monthly"], e.balance(alice))
+
+	e.expect(e.post(subscription, map[string]interface{}{"plan_id": "student"}), http.StatusConflict, nil)
+
+	var current models.Subscription
+	e.expect(e.get(subscription), http.StatusOK, &current)
+	assert.Equal(t, subscribed.ID, current.ID)
+	assert.Equal(t, "monthly", current.PlanID)
+
+	// Subscribers ride the included minutes for free, without unlock fee
+	e.expect(e.do(http.MethodPost, "/v1/users/"+alice+"/wallet/top-ups", map[string]interface{}{"amount_cents": 1000}, charlie), http.StatusCreated, nil)
+	ride := e.ride(alice, 50*time.Minute)
+
+	var receipt models.Receipt
+	e.expect(e.get(fmt.Sprintf("/v1/users/%s/receipts/%d", alice, ride.ID)), http.StatusOK, &receipt)
+	assert.Equal(t, "monthly", receipt.PlanID.String)
+	assert.Equal(t, int64(0), receipt.UnlockCents)
+	assert.Equal(t, 5*testConfig.Pricing.PerMinute, receipt.TotalCents)
+
+	// Cancelled, it runs until it expires
+	e.expect(e.post(subscription+"/cancel", nil), http.StatusOK, &current)
+	assert.False(t, current.AutoRenew)
+	assert.Equal(t, models.SubscriptionActive, current.Status)
+
+	e.clock.Set(current.ExpiresAt)
+	cronjobs.RenewSubscriptions(e.db, e.clock)
+	e.expect(e.get(subscription), http.StatusNotFound, nil)
+	e.expect(e.post(subscription+"/cancel", nil), http.StatusNotFound, nil)
+}
+
+func TestInvoices(t *testing.T) {
+	e := newEnv(t)
+
+	ride := e.ride(alice, 10*time.Minute)
+	fare := testConfig.Pricing.UnlockFee + 10*testConfig.Pricing.PerMinute
+
+	var receipt models.Receipt
+	e.expect(e.get(fmt.Sprintf("/v1/users/%s/receipts/%d", alice, ride.ID)), http.StatusOK, &receipt)
+
+	// Part of it is refunded
+	refund := map[string]interface{}{"amount_cents": 100, "assignment_id": ride.ID, "reason": "Flat tyre"}
+	e.expect(e.do(http.MethodPost, "/v1/users/"+alice+"/wallet/refunds", refund, charlie), http.StatusCreated, nil)
+
+	// The month of the ride is invoiced early the next month
+	invoicesPath := "/v1/users/" + alice + "/invoices"
+	var list []models.Invoice
+	e.expect(e.get(invoicesPath), http.StatusOK, &list)
+	assert.Empty(t, list)
+
+	e.clock.Set(invoices.MonthStart(receipt.EndedAt).AddDate(0, 1, 0).Add(time.Hour))
+	cronjobs.IssueMonthlyInvoices(e.db, e.clock)
+	// Running again does not invoice twice
+	cronjobs.IssueMonthlyInvoices(e.db, e.clock)
+
+	e.expect(e.get(invoicesPath), http.StatusOK, &list)
+	require.Len(t, list, 1)
+	assert.Equal(t, 1, list[0].Rides)
+	assert.Equal(t, fare-100, list[0].TotalCents)
+	assert.Equal(t, int64(-100), list[0].RefundCents)
+	assert.True(t, invoices.MonthStart(receipt.EndedAt).Equal(list[0].PeriodStart))
+
+	var invoice models.Invoice
+	e.expect(e.get(invoicesPath+"/"+list[0].Number), http.StatusOK, &invoice)
+	require.Len(t, invoice.Lines, 2)
+	assert.Equal(t, models.InvoiceLineRide, invoice.Lines[0].Kind)
+	assert.Equal(t, models.InvoiceLineRefund, invoice.Lines[1].Kind)
+	assert.Equal(t, int64(-100), invoice.Lines[1].AmountCents)
+
+	e.expect(e.get(invoicesPath+"/INV-0000-000000"), http.StatusNotFound, nil)
+	e.expect(e.get("/v1/users/"+bob+"/invoices"), http.StatusOK, &list)
+	assert.Empty(t, list)
+}
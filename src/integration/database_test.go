@@ -0,0 +1,62 @@
+//go:build integration
+
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/bike-rental/src/database"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func TestMigrations(t *testing.T) {
+	migrator, err := database.NewMigrator(testDB, migrationsPath)
+	require.NoError(t, err)
+
+	status, err := migrator.Status()
+	require.NoError(t, err)
+	assert.Equal(t, status.Latest, status.Version)
+	assert.Zero(t, status.Pending)
+	assert.False(t, status.Dirty)
+	latest := status.Latest
+
+	// Every migration can be reverted and applied again
+	require.NoError(t, migrator.Down(2))
+	status, err = migrator.Status()
+	require.NoError(t, err)
+	assert.Equal(t, latest-2, status.Version)
+	assert.Equal(t, 2, status.Pending)
+
+	require.NoError(t, migrator.To(latest-1))
+	status, err = migrator.Status()
+	require.NoError(t, err)
+	assert.Equal(t, latest-1, status.Version)
+
+	require.NoError(t, migrator.Up())
+	require.NoError(t, migrator.Up(), "Nothing left to apply")
+	status, err = migrator.Status()
+	require.NoError(t, err)
+	assert.Equal(t, latest, status.Version)
+}
+
+func TestSeed(t *testing.T) {
+	e := newEnv(t)
+
+	// Seeding again leaves the records and the balances alone
+	require.NoError(t, database.Seed(e.db, fixtures))
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": alice}), http.StatusOK, nil)
+	require.NoError(t, database.Seed(e.db, fixtures))
+
+	var bikes []models.Bike
+	e.expect(e.get("/v1/bikes"), http.StatusOK, &bikes)
+	assert.Len(t, bikes, len(fixtures.Bikes))
+	assert.Equal(t, int64(openingBalance), e.balance(bob))
+
+	// Cleaning empties the database
+	require.NoError(t, database.CleanDatabase(e.db))
+	e.expect(e.get("/v1/bikes"), http.StatusOK, &bikes)
+	assert.Empty(t, bikes)
+}
@@ -0,0 +1,270 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/bike-rental/src/api"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/database"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/pricing"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// The seeded fixtures
+const (
+	alice     = "d0ab33d7-8fcc-463d-bade-fefd53b77a96"
+	bob       = "0b28a7ed-39ef-418f-a0e3-8ad3f794dfc7"
+	charlie   = "da690323-5a78-4d46-a214-943b2ec9d49e" // Admin
+	central   = "7c1a3b5e-2f4d-4e6a-9b8c-0d1e2f3a4b5c"
+	firstBike = "331e7ffb-e583-4535-ba41-4c28dc34016d"
+	lastBike  = "e4ef2d9b-5d5a-4f85-bb3a-b2df8bf42ac1"
+
+	// openingBalance is credited to the seeded customers
+	openingBalance = 2000
+)
+
+// migrationsPath is the source of the migrations, from this directory
+const migrationsPath = "file://../database/migrations"
+
+// testConfig is the [api] section of config.toml
+var testConfig = api.Config{
+	Reservations: api.ReservationConfig{Hold: 15 * time.Minute},
+	Wallet:       api.WalletConfig{MinimumBalance: 500},
+	Pricing: pricing.Tariff{
+		Currency:       "EUR",
+		UnlockFee:      100,
+		PerMinute:      15,
+		DailyCap:       1500,
+		OverduePenalty: 5000,
+		ReplacementFee: 30000,
+	},
+}
+
+// testDB is the migrated database shared by the tests, which must not run
+// in parallel
+var testDB *sql.DB
+
+// fixtures are the records of the development seed file
+var fixtures *database.Fixtures
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	logger.Init(&logger.Config{Level: "warn"})
+
+	dsn, stop, err := startPostgres()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: %v\n", err)
+		return 1
+	}
+	defer stop()
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: failed to open the database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if err := waitForPostgres(db, time.Minute); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: %v\n", err)
+		return 1
+	}
+
+	// The schema is built by the migrations alone, as in production
+	if err := database.RunMigrations(db, migrationsPath); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: failed to run migrations: %v\n", err)
+		return 1
+	}
+
+	if fixtures, err = database.LoadFixtures("../database/fixtures/dev.json"); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: %v\n", err)
+		return 1
+	}
+
+	testDB = db
+	return m.Run()
+}
+
+// startPostgres returns the DSN of INTEGRATION_DATABASE_URL, or starts a
+// postgres container on a free port of the loopback interface. stop
+// removes the container.
+func startPostgres() (dsn string, stop func(), err error) {
+	if dsn := os.Getenv("INTEGRATION_DATABASE_URL"); dsn != "" {
+		return dsn, func() {}, nil
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", nil, errors.New("set INTEGRATION_DATABASE_URL or install docker to run the integration tests")
+	}
+
+	out, err := exec.Command("docker", "run", "--detach", "--rm",
+		"--env", "POSTGRES_USER=bikesharing",
+		"--env", "POSTGRES_PASSWORD=password",
+		"--env", "POSTGRES_DB=bikedb",
+		"--publish", "127.0.0.1::5432",
+		"postgres:13").Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to start postgres container: %w", err)
+	}
+	container := strings.TrimSpace(string(out))
+	stop = func() {
+		exec.Command("docker", "stop", container).Run()
+	}
+
+	out, err = exec.Command("docker", "port", container, "5432/tcp").Output()
+	if err != nil {
+		stop()
+		return "", nil, fmt.Errorf("failed to find postgres port: %w", err)
+	}
+	// One line per address, e.g. 127.0.0.1:49153
+	address := strings.SplitN(strings.TrimSpace(string(out)), "\n", 2)[0]
+
+	dsn = fmt.Sprintf("postgres://bikesharing:password@%s/bikedb?sslmode=disable", address)
+	return dsn, stop, nil
+}
+
+// waitForPostgres pings the database until it accepts connections. The
+// container only listens on TCP once initialized.
+func waitForPostgres(db *sql.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := db.Ping()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("postgres not ready after %s: %w", timeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// env is the API served from the test database, emptied and seeded again,
+// and a fake clock
+type env struct {
+	t     *testing.T
+	db    *sql.DB
+	clock *clock.Fake
+	url   string
+}
+
+func newEnv(t *testing.T) *env {
+	t.Helper()
+
+	require.NoError(t, database.CleanDatabase(testDB), "Failed to clean the database")
+	require.NoError(t, database.Seed(testDB, fixtures), "Failed to seed the database")
+
+	// The seeded bikes are available from the time of the seed. The clock
+	// starts after it, on a whole second so that times survive the round
+	// trip through Postgres.
+	clk := clock.NewFake(time.Now().UTC().Truncate(time.Second).Add(time.Second))
+
+	metrics, err := api.NewMetrics(noop.Meter{})
+	require.NoError(t, err, "Failed to create the metrics")
+	server, err := api.NewServer(testDB, clk, &testConfig, func() *zerolog.Logger { return logger.For("api") }, metrics)
+	require.NoError(t, err, "Failed to build the server")
+
+	ts := httptest.NewServer(server.Routes())
+	t.Cleanup(ts.Close)
+
+	return &env{t: t, db: testDB, clock: clk, url: ts.URL}
+}
+
+// response is a response of the API, its body read
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// do calls the API, with body encoded to JSON when not nil and the
+// X-Operator-UUID header when operator is not empty
+func (e *env) do(method, path string, body interface{}, operator string) response {
+	e.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		require.NoError(e.t, err)
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, e.url+path, reader)
+	require.NoError(e.t, err)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if operator != "" {
+		req.Header.Set("X-Operator-UUID", operator)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(e.t, err, "Failed to call %s %s", method, path)
+	defer resp.Body.Close()
+
+	read, err := io.ReadAll(resp.Body)
+	require.NoError(e.t, err)
+	return response{status: resp.StatusCode, header: resp.Header, body: read}
+}
+
+func (e *env) get(path string) response {
+	e.t.Helper()
+	return e.do(http.MethodGet, path, nil, "")
+}
+
+func (e *env) post(path string, body interface{}) response {
+	e.t.Helper()
+	return e.do(http.MethodPost, path, body, "")
+}
+
+// expect checks the status of the response and decodes its JSON body into
+// v, when not nil
+func (e *env) expect(resp response, status int, v interface{}) {
+	e.t.Helper()
+
+	require.Equal(e.t, status, resp.status, "Unexpected response: %s", resp.body)
+	if v != nil {
+		require.NoError(e.t, json.Unmarshal(resp.body, v), "Failed to decode response: %s", resp.body)
+	}
+}
+
+// exec runs a statement setting up a test, which must succeed
+func (e *env) exec(query string, args ...interface{}) {
+	e.t.Helper()
+
+	_, err := e.db.Exec(query, args...)
+	require.NoError(e.t, err, "Failed to run %q", query)
+}
+
+// addCustomer creates a customer with an empty wallet
+func (e *env) addCustomer(id, name string) {
+	e.t.Helper()
+	e.exec("INSERT INTO users (id, name, role) VALUES ($1, $2, 'Customer')", id, name)
+}
+
+// addStation creates a station with a free dock per slot
+func (e *env) addStation(id, name string, capacity int) {
+	e.t.Helper()
+	e.exec("INSERT INTO stations (id, name, capacity) VALUES ($1, $2, $3)", id, name, capacity)
+	e.exec("INSERT INTO docks (station_id, slot) SELECT $1, generate_series(1, $2)", id, capacity)
+}
@@ -0,0 +1,268 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/bike-rental/src/api"
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/cronjobs"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/reconcile"
+)
+
+// bike returns a bike of the fleet by ID
+func (e *env) bike(id string) models.Bike {
+	e.t.Helper()
+
+	var bikes []models.Bike
+	e.expect(e.get("/v1/bikes"), http.StatusOK, &bikes)
+	for _, bike := range bikes {
+		if bike.ID == id {
+			return bike
+		}
+	}
+	e.t.Fatalf("Bike %s not found", id)
+	return models.Bike{}
+}
+
+func TestOverdueRide(t *testing.T) {
+	e := newEnv(t)
+
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": alice}), http.StatusOK, nil)
+	var assignments []models.Assignment
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	require.Len(t, assignments, 1)
+	ride := assignments[0]
+
+	// Not overdue at 24 hours yet
+	e.clock.Advance(24 * time.Hour)
+	cronjobs.EscalateOverdueAssignments(e.db, e.clock, &testConfig.Pricing)
+	var tasks []models.SupervisorTask
+	e.expect(e.do(http.MethodGet, "/v1/tasks", nil, charlie), http.StatusOK, &tasks)
+	assert.Empty(t, tasks)
+
+	e.clock.Advance(time.Minute)
+	cronjobs.EscalateOverdueAssignments(e.db, e.clock, &testConfig.Pricing)
+
+	// The tasks are for supervisors only
+	e.expect(e.get("/v1/tasks"), http.StatusUnauthorized, nil)
+	e.expect(e.do(http.MethodGet, "/v1/tasks", nil, alice), http.StatusForbidden, nil)
+
+	e.expect(e.do(http.MethodGet, "/v1/tasks", nil, charlie), http.StatusOK, &tasks)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, models.TaskOverdueBike, tasks[0].Kind)
+	assert.Equal(t, int64(ride.ID), tasks[0].AssignmentID.Int64)
+	assert.Equal(t, models.BikeLostSuspected, e.bike(ride.BikeID).Status)
+
+	// The penalty is collected right away
+	var balance api.WalletBalance
+	e.expect(e.get("/v1/users/"+alice+"/wallet"), http.StatusOK, &balance)
+	assert.Equal(t, openingBalance-testConfig.Pricing.OverduePenalty, balance.BalanceCents)
+
+	var task models.SupervisorTask
+	e.expect(e.do(http.MethodPost, fmt.Sprintf("/v1/tasks/%d/close", tasks[0].ID), map[string]interface{}{"note": "Customer reached"}, charlie), http.StatusOK, &task)
+	assert.Equal(t, models.TaskDone, task.Status)
+	assert.Equal(t, charlie, task.ClosedBy.String)
+
+	e.expect(e.do(http.MethodGet, "/v1/tasks?status=done", nil, charlie), http.StatusOK, &tasks)
+	assert.Len(t, tasks, 1)
+	e.expect(e.do(http.MethodGet, "/v1/tasks?status=open", nil, charlie), http.StatusOK, &tasks)
+	assert.Empty(t, tasks)
+
+	// Docking the bike puts it back in service
+	e.expect(e.post("/v1/bikes/unassign", map[string]interface{}{"bike_uuid": ride.BikeID, "user_uuid": alice, "station_id": central}), http.StatusOK, nil)
+	assert.Equal(t, models.BikeInService, e.bike(ride.BikeID).Status)
+
+	var receipt models.Receipt
+	e.expect(e.get(fmt.Sprintf("/v1/users/%s/receipts/%d", alice, ride.ID)), http.StatusOK, &receipt)
+	assert.Equal(t, testConfig.Pricing.OverduePenalty, receipt.PenaltyCents)
+}
+
+func TestBikeReport(t *testing.T) {
+	e := newEnv(t)
+
+	harbour := "1b6e4f2a-7c3d-4e8f-9a0b-5c6d7e8f9a0b"
+	e.addStation(harbour, "Harbour", 2)
+
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": alice}), http.StatusOK, nil)
+	var assignments []models.Assignment
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	require.Len(t, assignments, 1)
+	ride := assignments[0]
+
+	e.clock.Advance(time.Hour)
+
+	// Stolen during the ride, the rider pays for the bike
+	report := "/v1/bikes/" + ride.BikeID + "/report"
+	declaration := map[string]interface{}{"kind": "stolen", "report": "Stolen outside the station"}
+	e.expect(e.post(report, declaration), http.StatusUnauthorized, nil)
+
+	var declared models.BikeReport
+	e.expect(e.do(http.MethodPost, report, declaration, charlie), http.StatusCreated, &declared)
+	assert.Equal(t, models.BikeStolen, declared.Kind)
+	assert.Equal(t, alice, declared.UserID.String)
+	assert.Equal(t, int64(ride.ID), declared.AssignmentID.Int64)
+	assert.Equal(t, testConfig.Pricing.ReplacementFee, declared.FeeCents)
+
+	e.expect(e.do(http.MethodPost, report, declaration, charlie), http.StatusConflict, nil)
+
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	require.Len(t, assignments, 1)
+	assert.True(t, assignments[0].UnassignedAt.Valid)
+	assert.Equal(t, models.BikeStolen, e.bike(ride.BikeID).Status)
+
+	var statement []models.WalletStatementLine
+	e.expect(e.get("/v1/users/"+alice+"/wallet/statement?limit=1"), http.StatusOK, &statement)
+	require.Len(t, statement, 1)
+	assert.Equal(t, models.WalletReplacement, statement[0].Kind)
+
+	// Found at the harbour
+	recover := "/v1/bikes/" + ride.BikeID + "/recover"
+	e.expect(e.do(http.MethodPost, recover, map[string]interface{}{"station_id": harbour}, charlie), http.StatusOK, nil)
+	e.expect(e.do(http.MethodPost, recover, map[string]interface{}{"station_id": harbour}, charlie), http.StatusConflict, nil)
+
+	bike := e.bike(ride.BikeID)
+	assert.Equal(t, models.BikeInService, bike.Status)
+	assert.Equal(t, harbour, bike.StationID.String)
+
+	var occupancy api.StationOccupancy
+	e.expect(e.get("/v1/stations/"+harbour+"/occupancy"), http.StatusOK, &occupancy)
+	assert.Equal(t, 1, occupancy.FreeDocks)
+	assert.Equal(t, 1, occupancy.BikesCoolingDown)
+}
+
+func TestRebalancing(t *testing.T) {
+	// Audit events are never deleted, only those of this test are listed
+	since := time.Now().UTC().Add(-time.Second)
+	e := newEnv(t)
+
+	harbour := "1b6e4f2a-7c3d-4e8f-9a0b-5c6d7e8f9a0b"
+	e.addStation(harbour, "Harbour", 4)
+
+	var plan api.RebalancingPlan
+	e.expect(e.do(http.MethodGet, "/v1/rebalancing/plan?days=30", nil, charlie), http.StatusOK, &plan)
+	assert.True(t, e.clock.Now().AddDate(0, 0, -30).Equal(plan.DemandSince))
+	e.expect(e.do(http.MethodGet, "/v1/rebalancing/plan?days=0", nil, charlie), http.StatusBadRequest, nil)
+	e.expect(e.do(http.MethodGet, "/v1/rebalancing/plan", nil, bob), http.StatusForbidden, nil)
+
+	move := map[string]interface{}{"from_station_id": central, "to_station_id": harbour, "bike_ids": []string{firstBike}}
+	var moves []models.RebalancingMove
+	e.expect(e.do(http.MethodPost, "/v1/rebalancing/moves", move, charlie), http.StatusCreated, &moves)
+	require.Len(t, moves, 1)
+	assert.Equal(t, charlie, moves[0].OperatorID)
+
+	// The bike is not at Central anymore
+	e.expect(e.do(http.MethodPost, "/v1/rebalancing/moves", move, charlie), http.StatusConflict, nil)
+
+	e.expect(e.do(http.MethodGet, "/v1/rebalancing/moves", nil, charlie), http.StatusOK, &moves)
+	assert.Len(t, moves, 1)
+
+	assert.Equal(t, harbour, e.bike(firstBike).StationID.String)
+	var occupancy api.StationOccupancy
+	e.expect(e.get("/v1/stations/"+harbour+"/occupancy"), http.StatusOK, &occupancy)
+	assert.Equal(t, 3, occupancy.FreeDocks)
+	assert.Equal(t, 1, occupancy.BikesReady)
+
+	// The move is in the audit log, with the operator and the request ID
+	query := url.Values{
+		"entity_type": {audit.EntityBike},
+		"entity_id":   {firstBike},
+		"action":      {audit.ActionBikeRelocated},
+		"since":       {since.Format(time.RFC3339)},
+	}
+	var events []models.AuditEvent
+	e.expect(e.do(http.MethodGet, "/v1/audit?"+query.Encode(), nil, charlie), http.StatusOK, &events)
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.ActorOperator, events[0].ActorType)
+	assert.Equal(t, charlie, events[0].ActorID.String)
+	assert.True(t, events[0].RequestID.Valid)
+}
+
+func TestReconcile(t *testing.T) {
+	since := time.Now().UTC().Add(-time.Second)
+	e := newEnv(t)
+
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": alice}), http.StatusOK, nil)
+	var assignments []models.Assignment
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	require.Len(t, assignments, 1)
+	ride := assignments[0]
+	other := firstBike
+	if ride.BikeID == firstBike {
+		other = lastBike
+	}
+
+	// Requests failing halfway
+	e.exec("UPDATE bikes SET is_assigned = false, last_unassigned = $2 WHERE id = $1", ride.BikeID, e.clock.Now())
+	e.exec("UPDATE bikes SET is_assigned = true, usage_count = 1 WHERE id = $1", other)
+	e.exec("UPDATE docks SET state = 'free', bike_id = NULL WHERE bike_id = $1", other)
+
+	found, err := reconcile.Run(context.Background(), e.db, e.clock, &reconcile.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, []reconcile.Inconsistency{
+		{Kind: reconcile.KindBikeWithoutAssignment, BikeID: other},
+		{Kind: reconcile.KindAssignmentOnFreeBike, BikeID: ride.BikeID, UserID: alice, AssignmentID: ride.ID},
+		{Kind: reconcile.KindUsageCountMismatch, BikeID: other, Found: 1, Expected: 0},
+	}, found)
+
+	found, err = reconcile.Run(context.Background(), e.db, e.clock, &reconcile.Config{Repair: true})
+	require.NoError(t, err)
+	require.Len(t, found, 3)
+	for _, inconsistency := range found {
+		assert.True(t, inconsistency.Repaired, inconsistency.String())
+	}
+
+	found, err = reconcile.Check(context.Background(), e.db, e.clock.Now())
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	// Both bikes can be assigned again
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	require.Len(t, assignments, 1)
+	assert.True(t, assignments[0].UnassignedAt.Valid)
+	assert.False(t, e.bike(other).IsAssigned)
+	assert.Equal(t, 0, e.bike(other).UsageCount)
+
+	// The bike is docked at its station again
+	var docked bool
+	require.NoError(t, e.db.QueryRow("SELECT EXISTS (SELECT 1 FROM docks d INNER JOIN bikes b ON b.station_id = d.station_id WHERE d.bike_id = $1 AND b.id = $1)", other).Scan(&docked))
+	assert.True(t, docked)
+
+	query := url.Values{
+		"action": {audit.ActionBikeReconciled},
+		"since":  {since.Format(time.RFC3339)},
+	}
+	var events []models.AuditEvent
+	e.expect(e.do(http.MethodGet, "/v1/audit?"+query.Encode(), nil, charlie), http.StatusOK, &events)
+	assert.Len(t, events, 3)
+}
+
+func TestLogLevel(t *testing.T) {
+	e := newEnv(t)
+
+	// The log levels are for supervisors only, under /v1 only
+	e.expect(e.do(http.MethodPut, "/v1/admin/log-level", map[string]interface{}{"package": "api", "level": "debug"}, ""), http.StatusUnauthorized, nil)
+	e.expect(e.do(http.MethodPut, "/v1/admin/log-level", map[string]interface{}{"package": "api", "level": "debug"}, alice), http.StatusForbidden, nil)
+	e.expect(e.do(http.MethodGet, "/admin/log-level", nil, charlie), http.StatusNotFound, nil)
+
+	e.expect(e.do(http.MethodGet, "/v1/admin/log-level", nil, charlie), http.StatusOK, nil)
+
+	e.expect(e.do(http.MethodPut, "/v1/admin/log-level", map[string]interface{}{"package": "api", "level": "debug"}, charlie), http.StatusOK, nil)
+	var levels struct {
+		Packages map[string]string `json:"packages"`
+	}
+	e.expect(e.do(http.MethodGet, "/v1/admin/log-level", nil, charlie), http.StatusOK, &levels)
+	assert.Equal(t, "debug", levels.Packages["api"])
+
+	e.expect(e.do(http.MethodPut, "/v1/admin/log-level", map[string]interface{}{"package": "api", "level": ""}, charlie), http.StatusOK, nil)
+	e.expect(e.do(http.MethodPut, "/v1/admin/log-level", map[string]interface{}{"level": "loud"}, charlie), http.StatusBadRequest, nil)
+}
@@ -0,0 +1,154 @@
+//go:build integration
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/bike-rental/src/api"
+	"github.com/yourusername/bike-rental/src/cronjobs"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func TestReservation(t *testing.T) {
+	e := newEnv(t)
+
+	var reservation models.Reservation
+	e.expect(e.post("/v1/reservations", map[string]interface{}{"user_uuid": alice, "station_id": central}), http.StatusCreated, &reservation)
+	assert.Equal(t, models.ReservationActive, reservation.Status)
+	assert.True(t, e.clock.Now().Add(testConfig.Reservations.Hold).Equal(reservation.ExpiresAt))
+
+	// One reservation at a time
+	e.expect(e.post("/v1/reservations", map[string]interface{}{"user_uuid": alice}), http.StatusBadRequest, nil)
+
+	// The reserved bike is held for Alice alone
+	var bikes []models.Bike
+	e.expect(e.get("/v1/bikes/available"), http.StatusOK, &bikes)
+	require.Len(t, bikes, 1)
+	assert.NotEqual(t, reservation.BikeID, bikes[0].ID)
+
+	var occupancy api.StationOccupancy
+	e.expect(e.get("/v1/stations/"+central+"/occupancy"), http.StatusOK, &occupancy)
+	assert.Equal(t, 1, occupancy.BikesReady)
+	assert.Equal(t, 1, occupancy.BikesReserved)
+
+	var other models.Reservation
+	e.expect(e.post("/v1/reservations", map[string]interface{}{"user_uuid": bob}), http.StatusCreated, &other)
+	assert.NotEqual(t, reservation.BikeID, other.BikeID)
+
+	// She is handed the bike she reserved
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": alice}), http.StatusOK, nil)
+	var assignments []models.Assignment
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	require.Len(t, assignments, 1)
+	assert.Equal(t, reservation.BikeID, assignments[0].BikeID)
+
+	// Only Bob can cancel his reservation, once
+	cancel := fmt.Sprintf("/v1/reservations/%d/cancel", other.ID)
+	e.expect(e.post(cancel, map[string]interface{}{"user_uuid": alice}), http.StatusNotFound, nil)
+	e.expect(e.post(cancel, map[string]interface{}{"user_uuid": bob}), http.StatusOK, nil)
+	e.expect(e.post(cancel, map[string]interface{}{"user_uuid": bob}), http.StatusNotFound, nil)
+
+	e.expect(e.get("/v1/bikes/available"), http.StatusOK, &bikes)
+	assert.Len(t, bikes, 1)
+}
+
+func TestReservation_Expires(t *testing.T) {
+	e := newEnv(t)
+
+	var reservation models.Reservation
+	e.expect(e.post("/v1/reservations", map[string]interface{}{"user_uuid": alice}), http.StatusCreated, &reservation)
+
+	// Still held a second before it expires
+	e.clock.Advance(testConfig.Reservations.Hold - time.Second)
+	cronjobs.ExpireReservations(e.db, e.clock)
+	var bikes []models.Bike
+	e.expect(e.get("/v1/bikes/available"), http.StatusOK, &bikes)
+	assert.Len(t, bikes, 1)
+
+	e.clock.Advance(time.Second)
+	cronjobs.ExpireReservations(e.db, e.clock)
+	e.expect(e.get("/v1/bikes/available"), http.StatusOK, &bikes)
+	assert.Len(t, bikes, 2)
+
+	e.expect(e.post(fmt.Sprintf("/v1/reservations/%d/cancel", reservation.ID), map[string]interface{}{"user_uuid": alice}), http.StatusNotFound, nil)
+}
+
+func TestWaitlist(t *testing.T) {
+	e := newEnv(t)
+
+	dana := "5f3c8a2e-9d41-4b7e-a6c0-2e8f1b7d4c93"
+	erin := "8c2d9e4f-1a3b-4c5d-8e7f-6a5b4c3d2e1f"
+	e.addCustomer(dana, "Dana")
+	e.addCustomer(erin, "Erin")
+	e.expect(e.do(http.MethodPost, "/v1/users/"+dana+"/wallet/top-ups", map[string]interface{}{"amount_cents": 1000}, charlie), http.StatusCreated, nil)
+
+	waitlist := "/v1/stations/" + central + "/waitlist"
+
+	// Nobody waits while bikes are available
+	e.expect(e.post(waitlist, map[string]interface{}{"user_uuid": dana}), http.StatusConflict, nil)
+
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": alice}), http.StatusOK, nil)
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": bob}), http.StatusOK, nil)
+
+	var entry models.WaitlistEntry
+	e.expect(e.post(waitlist, map[string]interface{}{"user_uuid": dana}), http.StatusCreated, &entry)
+	assert.Equal(t, 1, entry.Position)
+	e.expect(e.post(waitlist, map[string]interface{}{"user_uuid": dana}), http.StatusBadRequest, nil)
+	e.expect(e.post(waitlist, map[string]interface{}{"user_uuid": alice}), http.StatusBadRequest, nil)
+
+	e.clock.Advance(time.Second)
+	e.expect(e.post(waitlist, map[string]interface{}{"user_uuid": erin}), http.StatusCreated, &entry)
+	assert.Equal(t, 2, entry.Position)
+
+	var entries []models.WaitlistEntry
+	e.expect(e.get(waitlist), http.StatusOK, &entries)
+	require.Len(t, entries, 2)
+	assert.Equal(t, dana, entries[0].UserID)
+	assert.Equal(t, erin, entries[1].UserID)
+
+	e.expect(e.post(waitlist+"/leave", map[string]interface{}{"user_uuid": erin}), http.StatusOK, nil)
+	e.expect(e.post(waitlist+"/leave", map[string]interface{}{"user_uuid": erin}), http.StatusNotFound, nil)
+
+	// Alice's bike is offered to Dana once cooled down
+	var assignments []models.Assignment
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	var returned string
+	for _, assignment := range assignments {
+		if assignment.UserID == alice {
+			returned = assignment.BikeID
+		}
+	}
+	e.expect(e.post("/v1/bikes/unassign", map[string]interface{}{"bike_uuid": returned, "user_uuid": alice, "station_id": central}), http.StatusOK, nil)
+
+	cronjobs.OfferBikesToWaitlist(e.db, e.clock, testConfig.Reservations.Hold, testConfig.Wallet.MinimumBalance)
+	e.expect(e.get(waitlist), http.StatusOK, &entries)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.WaitlistWaiting, entries[0].Status)
+
+	e.clock.Advance(5 * time.Minute)
+	cronjobs.OfferBikesToWaitlist(e.db, e.clock, testConfig.Reservations.Hold, testConfig.Wallet.MinimumBalance)
+	e.expect(e.get(waitlist), http.StatusOK, &entries)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.WaitlistOffered, entries[0].Status)
+	assert.True(t, entries[0].ReservationID.Valid)
+
+	// Dana claims it, leaving the queue
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": dana}), http.StatusOK, nil)
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	var claimed string
+	for _, assignment := range assignments {
+		if assignment.UserID == dana {
+			claimed = assignment.BikeID
+		}
+	}
+	assert.Equal(t, returned, claimed)
+
+	e.expect(e.get(waitlist), http.StatusOK, &entries)
+	assert.Empty(t, entries)
+}
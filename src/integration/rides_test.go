@@ -0,0 +1,182 @@
+//go:build integration
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/bike-rental/src/api"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func TestRide(t *testing.T) {
+	e := newEnv(t)
+
+	var bikes []models.Bike
+	e.expect(e.get("/v1/bikes/available"), http.StatusOK, &bikes)
+	assert.Len(t, bikes, 2)
+
+	resp := e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": alice, "station_id": central})
+	e.expect(resp, http.StatusOK, nil)
+	bike := resp.header.Get(api.BikeHeader)
+	slot := resp.header.Get(api.DockSlotHeader)
+	assert.NotEmpty(t, slot, "The dock slot unlocked is returned")
+
+	var assignments []models.Assignment
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	require.Len(t, assignments, 1)
+	ride := assignments[0]
+	assert.Equal(t, alice, ride.UserID)
+	assert.Equal(t, bike, ride.BikeID)
+	assert.False(t, ride.UnassignedAt.Valid)
+	assert.True(t, e.clock.Now().Equal(ride.AssignedAt.Time))
+
+	e.expect(e.get("/v1/bikes/available"), http.StatusOK, &bikes)
+	assert.Len(t, bikes, 1)
+
+	// A ride of 10 minutes, returned to the slot it was taken from
+	e.clock.Advance(10 * time.Minute)
+	resp = e.post("/v1/bikes/unassign", map[string]interface{}{"bike_uuid": ride.BikeID, "user_uuid": alice, "station_id": central})
+	e.expect(resp, http.StatusOK, nil)
+	assert.Equal(t, slot, resp.header.Get(api.DockSlotHeader))
+
+	// The returned bike cools down for 5 minutes
+	var occupancy api.StationOccupancy
+	e.expect(e.get("/v1/stations/"+central+"/occupancy"), http.StatusOK, &occupancy)
+	assert.Equal(t, 20, occupancy.Docks)
+	assert.Equal(t, 18, occupancy.FreeDocks)
+	assert.Equal(t, 1, occupancy.BikesReady)
+	assert.Equal(t, 1, occupancy.BikesCoolingDown)
+
+	e.expect(e.get("/v1/bikes/available"), http.StatusOK, &bikes)
+	assert.Len(t, bikes, 1)
+
+	e.clock.Advance(5 * time.Minute)
+	e.expect(e.get("/v1/bikes/available"), http.StatusOK, &bikes)
+	assert.Len(t, bikes, 2)
+
+	e.expect(e.get("/v1/bikes"), http.StatusOK, &bikes)
+	for _, bike := range bikes {
+		if bike.ID == ride.BikeID {
+			assert.Equal(t, 1, bike.UsageCount)
+			assert.False(t, bike.IsAssigned)
+		} else {
+			assert.Equal(t, 0, bike.UsageCount)
+		}
+	}
+
+	// Unlock fee and 10 minutes
+	fare := testConfig.Pricing.UnlockFee + 10*testConfig.Pricing.PerMinute
+
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	require.Len(t, assignments, 1)
+	assert.True(t, assignments[0].UnassignedAt.Valid)
+	assert.Equal(t, central, assignments[0].EndStation.String)
+	assert.Equal(t, fare, assignments[0].FareCents.Int64)
+
+	var receipts []models.Receipt
+	e.expect(e.get("/v1/users/"+alice+"/receipts"), http.StatusOK, &receipts)
+	require.Len(t, receipts, 1)
+	assert.Equal(t, int64(10), receipts[0].Minutes)
+	assert.Equal(t, fare, receipts[0].TotalCents)
+
+	var receipt models.Receipt
+	e.expect(e.get(fmt.Sprintf("/v1/users/%s/receipts/%d", alice, ride.ID)), http.StatusOK, &receipt)
+	assert.Equal(t, ride.BikeID, receipt.BikeID)
+	e.expect(e.get(fmt.Sprintf("/v1/users/%s/receipts/%d", bob, ride.ID)), http.StatusNotFound, nil)
+
+	// The fare is debited from the wallet
+	var balance api.WalletBalance
+	e.expect(e.get("/v1/users/"+alice+"/wallet"), http.StatusOK, &balance)
+	assert.Equal(t, openingBalance-fare, balance.BalanceCents)
+
+	var statement []models.WalletStatementLine
+	e.expect(e.get("/v1/users/"+alice+"/wallet/statement"), http.StatusOK, &statement)
+	require.Len(t, statement, 2)
+	assert.Equal(t, models.WalletRideCharge, statement[0].Kind)
+	assert.Equal(t, int64(ride.ID), statement[0].AssignmentID.Int64)
+	assert.Equal(t, openingBalance-fare, statement[0].BalanceCents)
+	assert.Equal(t, models.WalletTopUp, statement[1].Kind)
+}
+
+func TestAssignBike_Rejected(t *testing.T) {
+	e := newEnv(t)
+
+	dana := "5f3c8a2e-9d41-4b7e-a6c0-2e8f1b7d4c93"
+	e.addCustomer(dana, "Dana")
+
+	// Checked against the OpenAPI document first
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": "alice"}), http.StatusBadRequest, nil)
+
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": "9a0f6a7e-8a55-4c1f-b9a1-3f3b1c6e2d10"}), http.StatusNotFound, nil)
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": charlie}), http.StatusBadRequest, nil)
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": dana}), http.StatusPaymentRequired, nil)
+
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": alice}), http.StatusOK, nil)
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": alice}), http.StatusBadRequest, nil)
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": bob}), http.StatusOK, nil)
+
+	// Both bikes are out
+	var topUp models.WalletTransaction
+	e.expect(e.do(http.MethodPost, "/v1/users/"+dana+"/wallet/top-ups", map[string]interface{}{"amount_cents": 1000}, charlie), http.StatusCreated, &topUp)
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": dana}), http.StatusNotFound, nil)
+
+	// Only the rider can return a bike
+	var assignments []models.Assignment
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	require.Len(t, assignments, 2)
+	e.expect(e.post("/v1/bikes/unassign", map[string]interface{}{"bike_uuid": assignments[0].BikeID, "user_uuid": dana}), http.StatusNotFound, nil)
+}
+
+func TestUnassignBike_Docks(t *testing.T) {
+	e := newEnv(t)
+
+	small := "1b6e4f2a-7c3d-4e8f-9a0b-5c6d7e8f9a0b"
+	e.addStation(small, "Harbour", 1)
+
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": alice}), http.StatusOK, nil)
+	e.expect(e.post("/v1/bikes/assign", map[string]interface{}{"user_uuid": bob}), http.StatusOK, nil)
+
+	var assignments []models.Assignment
+	e.expect(e.get("/v1/assignments"), http.StatusOK, &assignments)
+	require.Len(t, assignments, 2)
+	rides := map[string]string{}
+	for _, assignment := range assignments {
+		rides[assignment.UserID] = assignment.BikeID
+	}
+
+	e.clock.Advance(time.Minute)
+
+	// Slot 3 of Central is free, the harbour only has one
+	resp := e.post("/v1/bikes/unassign", map[string]interface{}{"bike_uuid": rides[alice], "user_uuid": alice, "station_id": central, "dock_slot": 3})
+	e.expect(resp, http.StatusOK, nil)
+	assert.Equal(t, "3", resp.header.Get(api.DockSlotHeader))
+
+	e.expect(e.post("/v1/bikes/unassign", map[string]interface{}{"bike_uuid": rides[bob], "user_uuid": bob, "station_id": central, "dock_slot": 3}), http.StatusConflict, nil)
+	e.expect(e.post("/v1/bikes/unassign", map[string]interface{}{"bike_uuid": rides[bob], "user_uuid": bob, "station_id": small}), http.StatusOK, nil)
+
+	var occupancy api.StationOccupancy
+	e.expect(e.get("/v1/stations/"+small+"/occupancy"), http.StatusOK, &occupancy)
+	assert.Equal(t, 0, occupancy.FreeDocks)
+	require.Len(t, occupancy.Slots, 1)
+	assert.Equal(t, rides[bob], occupancy.Slots[0].BikeID.String)
+
+	e.expect(e.get("/v1/stations/9a0f6a7e-8a55-4c1f-b9a1-3f3b1c6e2d10/occupancy"), http.StatusNotFound, nil)
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	e := newEnv(t)
+
+	resp := e.get("/bikes/available")
+	e.expect(resp, http.StatusOK, nil)
+	assert.Equal(t, "true", resp.header.Get("Deprecation"))
+	assert.Equal(t, `</v1/bikes/available>; rel="successor-version"`, resp.header.Get("Link"))
+
+	e.expect(e.get("/openapi.json"), http.StatusOK, nil)
+	e.expect(e.get("/docs"), http.StatusOK, nil)
+}
@@ -0,0 +1,225 @@
+package invoices
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/pricing"
+)
+
+// defaultCurrency is used for the invoices without any ride
+const defaultCurrency = "EUR"
+
+// ErrNothingToInvoice is returned when a user has neither rides nor
+// transactions to invoice in the period
+var ErrNothingToInvoice = errors.New("invoices: nothing to invoice")
+
+// TransactionCondition selects the wallet transactions t invoiced on their
+// own line, the ride charges being invoiced from the receipts
+const TransactionCondition = "t.kind IN ('subscription', 'overdue_penalty', 'replacement_fee', 'refund')"
+
+// Queryer is satisfied by both *sql.DB and *sql.Tx
+type Queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// NewReceipt returns the receipt of an assignment ended at endedAt, priced at
+// fare with the plan of the user, if any
+func NewReceipt(assignment models.Assignment, endedAt time.Time, fare pricing.Fare, plan *models.Plan) models.Receipt {
+	receipt := models.Receipt{
+		AssignmentID:   assignment.ID,
+		UserID:         assignment.UserID,
+		BikeID:         assignment.BikeID,
+		StartStationID: assignment.StartStation,
+		EndStationID:   assignment.EndStation,
+		StartedAt:      assignment.AssignedAt.Time,
+		EndedAt:        endedAt,
+		Minutes:        fare.Minutes,
+		UnlockCents:    fare.Unlock,
+		TimeCents:      fare.Time,
+		PenaltyCents:   fare.Penalty,
+		TotalCents:     fare.Total,
+		Currency:       fare.Currency,
+	}
+	if plan != nil {
+		receipt.PlanID = sql.NullString{String: plan.ID, Valid: true}
+	}
+	return receipt
+}
+
+// RecordReceipt stores the receipt of a closed assignment
+func RecordReceipt(ctx context.Context, db Queryer, receipt *models.Receipt) error {
+	query := `INSERT INTO receipts (assignment_id, user_id, bike_id, start_station_id, end_station_id, started_at, ended_at, plan_id,
+	                                minutes, unlock_cents, time_cents, penalty_cents, total_cents, currency)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	          RETURNING id`
+	return db.QueryRowContext(ctx, query, receipt.AssignmentID, receipt.UserID, receipt.BikeID, receipt.StartStationID, receipt.EndStationID, receipt.StartedAt, receipt.EndedAt, receipt.PlanID,
+		receipt.Minutes, receipt.UnlockCents, receipt.TimeCents, receipt.PenaltyCents, receipt.TotalCents, receipt.Currency).Scan(&receipt.ID)
+}
+
+// MonthStart returns the first instant of the month of t, in UTC
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Issue invoices a user for the rides ended, the subscriptions, penalties
+// and fees paid and the refunds received in the month starting at
+// periodStart. The invoice is numbered in sequence
+// within the year it is issued.
+func Issue(ctx context.Context, db *sql.DB, userID string, periodStart, at time.Time) (*models.Invoice, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	invoice := models.Invoice{
+		UserID:      userID,
+		PeriodStart: periodStart,
+		PeriodEnd:   periodStart.AddDate(0, 1, 0),
+		Currency:    defaultCurrency,
+		IssuedAt:    at,
+	}
+	if err := addRides(ctx, tx, &invoice); err != nil {
+		return nil, err
+	}
+	if err := addTransactions(ctx, tx, &invoice); err != nil {
+		return nil, err
+	}
+	if len(invoice.Lines) == 0 {
+		return nil, ErrNothingToInvoice
+	}
+
+	if invoice.Number, err = nextNumber(ctx, tx, at.Year()); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO invoices (number, user_id, period_start, period_end, currency, rides, unlock_cents, time_cents, penalty_cents, subscription_cents,
+	                                replacement_cents, refund_cents, total_cents, issued_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	          RETURNING id`
+	if err := tx.QueryRowContext(ctx, query, invoice.Number, invoice.UserID, invoice.PeriodStart, invoice.PeriodEnd, invoice.Currency, invoice.Rides,
+		invoice.UnlockCents, invoice.TimeCents, invoice.PenaltyCents, invoice.SubscriptionCents,
+		invoice.ReplacementCents, invoice.RefundCents, invoice.TotalCents, invoice.IssuedAt).Scan(&invoice.ID); err != nil {
+		return nil, err
+	}
+
+	query = `INSERT INTO invoice_lines (invoice_id, kind, description, assignment_id, occurred_at, amount_cents)
+	         VALUES ($1, $2, $3, $4, $5, $6)`
+	for _, line := range invoice.Lines {
+		if _, err := tx.ExecContext(ctx, query, invoice.ID, line.Kind, line.Description, line.AssignmentID, line.OccurredAt, line.AmountCents); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// addRides adds the receipts of the period to invoice. The overdue penalty
+// of a ride is collected on its own by the escalation job, and invoiced as
+// such by addTransactions.
+func addRides(ctx context.Context, tx *sql.Tx, invoice *models.Invoice) error {
+	query := `SELECT assignment_id, ended_at, minutes, unlock_cents, time_cents, penalty_cents, total_cents, currency
+	          FROM receipts
+	          WHERE user_id = $1 AND ended_at >= $2 AND ended_at < $3
+	          ORDER BY ended_at, id`
+	rows, err := tx.QueryContext(ctx, query, invoice.UserID, invoice.PeriodStart, invoice.PeriodEnd)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var receipt models.Receipt
+		if err := rows.Scan(&receipt.AssignmentID, &receipt.EndedAt, &receipt.Minutes, &receipt.UnlockCents, &receipt.TimeCents, &receipt.PenaltyCents, &receipt.TotalCents, &receipt.Currency); err != nil {
+			return err
+		}
+
+		description := fmt.Sprintf("Ride of %d min", receipt.Minutes)
+		if receipt.PenaltyCents > 0 {
+			description += ", overdue"
+		}
+		charged := receipt.TotalCents - receipt.PenaltyCents
+		invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+			Kind:         models.InvoiceLineRide,
+			Description:  description,
+			AssignmentID: sql.NullInt64{Int64: int64(receipt.AssignmentID), Valid: true},
+			OccurredAt:   receipt.EndedAt,
+			AmountCents:  charged,
+		})
+		invoice.Currency = receipt.Currency
+		invoice.Rides++
+		invoice.UnlockCents += receipt.UnlockCents
+		invoice.TimeCents += receipt.TimeCents
+		invoice.TotalCents += charged
+	}
+	return rows.Err()
+}
+
+// addTransactions adds the subscription payments, overdue penalties,
+// replacement fees and refunds of the period to invoice, the refunds as
+// negative amounts
+func addTransactions(ctx context.Context, tx *sql.Tx, invoice *models.Invoice) error {
+	query := `SELECT t.kind, t.assignment_id, t.created_at, COALESCE(t.description, ''), -e.amount_cents
+	          FROM wallet_transactions t
+	          INNER JOIN wallet_entries e ON e.transaction_id = t.id
+	          INNER JOIN wallet_accounts a ON a.id = e.account_id
+	          WHERE a.user_id = $1 AND ` + TransactionCondition + ` AND t.created_at >= $2 AND t.created_at < $3
+	          ORDER BY t.created_at, t.id`
+	rows, err := tx.QueryContext(ctx, query, invoice.UserID, invoice.PeriodStart, invoice.PeriodEnd)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line models.InvoiceLine
+		var description string
+		if err := rows.Scan(&line.Kind, &line.AssignmentID, &line.OccurredAt, &description, &line.AmountCents); err != nil {
+			return err
+		}
+
+		switch line.Kind {
+		case models.InvoiceLineSubscription:
+			line.Description = description
+			invoice.SubscriptionCents += line.AmountCents
+		case models.InvoiceLinePenalty:
+			line.Description = "Overdue penalty"
+			invoice.PenaltyCents += line.AmountCents
+		case models.InvoiceLineReplacement:
+			line.Description = "Replacement fee"
+			invoice.ReplacementCents += line.AmountCents
+		case models.InvoiceLineRefund:
+			line.Description = "Refund"
+			if description != "" {
+				line.Description += ": " + description
+			}
+			invoice.RefundCents += line.AmountCents
+		}
+		invoice.Lines = append(invoice.Lines, line)
+		invoice.TotalCents += line.AmountCents
+	}
+	return rows.Err()
+}
+
+// nextNumber returns the next invoice number of year. The counter row stays
+// locked until the transaction ends, so concurrent invoices wait for each
+// other and a rolled back one leaves no gap.
+func nextNumber(ctx context.Context, tx *sql.Tx, year int) (string, error) {
+	var number int
+	query := `INSERT INTO invoice_counters (year, last_number) VALUES ($1, 1)
+	          ON CONFLICT (year) DO UPDATE SET last_number = invoice_counters.last_number + 1
+	          RETURNING last_number`
+	if err := tx.QueryRowContext(ctx, query, year).Scan(&number); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("INV-%d-%06d", year, number), nil
+}
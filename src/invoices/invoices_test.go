@@ -0,0 +1,122 @@
+package invoices
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/pricing"
+)
+
+func TestIssue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	periodStart := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2024, 8, 1, 2, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT assignment_id, ended_at, minutes, unlock_cents, time_cents, penalty_cents, total_cents, currency FROM receipts`).
+		WithArgs("user-1", periodStart, periodEnd).
+		WillReturnRows(sqlmock.NewRows([]string{"assignment_id", "ended_at", "minutes", "unlock_cents", "time_cents", "penalty_cents", "total_cents", "currency"}).
+			AddRow(12, time.Date(2024, 7, 3, 8, 30, 0, 0, time.UTC), 30, 100, 450, 0, 550, "EUR").
+			AddRow(15, time.Date(2024, 7, 9, 9, 0, 0, 0, time.UTC), 1500, 100, 1500, 5000, 6600, "EUR"))
+
+	// The penalty of the overdue ride was collected before it ended, the
+	// first ride was refunded and a bike lost
+	mock.ExpectQuery(`SELECT t.kind, t.assignment_id, t.created_at, COALESCE\(t.description, ''\), -e.amount_cents FROM wallet_transactions t (.+) t.kind IN \('subscription', 'overdue_penalty', 'replacement_fee', 'refund'\)`).
+		WithArgs("user-1", periodStart, periodEnd).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "assignment_id", "created_at", "description", "amount_cents"}).
+			AddRow("overdue_penalty", 15, time.Date(2024, 7, 8, 9, 0, 0, 0, time.UTC), "", 5000).
+			AddRow("subscription", nil, time.Date(2024, 7, 20, 7, 0, 0, 0, time.UTC), "Monthly pass from 2024-07-20 to 2024-08-20", 1990).
+			AddRow("replacement_fee", 18, time.Date(2024, 7, 25, 18, 0, 0, 0, time.UTC), "", 25000).
+			AddRow("refund", 12, time.Date(2024, 7, 28, 10, 0, 0, 0, time.UTC), "Flat tyre", -550))
+
+	// Numbered after the invoices already issued this year
+	mock.ExpectQuery(`INSERT INTO invoice_counters \(year, last_number\) VALUES \(\$1, 1\) ON CONFLICT \(year\) DO UPDATE SET last_number = invoice_counters.last_number \+ 1 RETURNING last_number`).
+		WithArgs(2024).
+		WillReturnRows(sqlmock.NewRows([]string{"last_number"}).AddRow(42))
+	mock.ExpectQuery(`INSERT INTO invoices`).
+		WithArgs("INV-2024-000042", "user-1", periodStart, periodEnd, "EUR", 2, int64(200), int64(1950), int64(5000), int64(1990), int64(25000), int64(-550), int64(33590), at).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec(`INSERT INTO invoice_lines`).
+		WithArgs(7, "ride", "Ride of 30 min", sql.NullInt64{Int64: 12, Valid: true}, sqlmock.AnyArg(), int64(550)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO invoice_lines`).
+		WithArgs(7, "ride", "Ride of 1500 min, overdue", sql.NullInt64{Int64: 15, Valid: true}, sqlmock.AnyArg(), int64(1600)).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec(`INSERT INTO invoice_lines`).
+		WithArgs(7, "overdue_penalty", "Overdue penalty", sql.NullInt64{Int64: 15, Valid: true}, sqlmock.AnyArg(), int64(5000)).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec(`INSERT INTO invoice_lines`).
+		WithArgs(7, "subscription", "Monthly pass from 2024-07-20 to 2024-08-20", sql.NullInt64{}, sqlmock.AnyArg(), int64(1990)).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectExec(`INSERT INTO invoice_lines`).
+		WithArgs(7, "replacement_fee", "Replacement fee", sql.NullInt64{Int64: 18, Valid: true}, sqlmock.AnyArg(), int64(25000)).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectExec(`INSERT INTO invoice_lines`).
+		WithArgs(7, "refund", "Refund: Flat tyre", sql.NullInt64{Int64: 12, Valid: true}, sqlmock.AnyArg(), int64(-550)).
+		WillReturnResult(sqlmock.NewResult(6, 1))
+	mock.ExpectCommit()
+
+	invoice, err := Issue(context.Background(), db, "user-1", periodStart, at)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), invoice.ID)
+	assert.Equal(t, "INV-2024-000042", invoice.Number)
+	assert.Equal(t, int64(33590), invoice.TotalCents)
+	assert.Len(t, invoice.Lines, 6)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIssue_NothingToInvoice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT assignment_id, (.+) FROM receipts`).
+		WillReturnRows(sqlmock.NewRows([]string{"assignment_id", "ended_at", "minutes", "unlock_cents", "time_cents", "penalty_cents", "total_cents", "currency"}))
+	mock.ExpectQuery(`SELECT t.kind, (.+) FROM wallet_transactions t`).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "assignment_id", "created_at", "description", "amount_cents"}))
+
+	// No number is taken
+	mock.ExpectRollback()
+
+	_, err = Issue(context.Background(), db, "user-1", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), time.Now())
+	assert.Equal(t, ErrNothingToInvoice, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMonthStart(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*60*60)
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), MonthStart(time.Date(2024, 7, 31, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), MonthStart(time.Date(2024, 8, 1, 1, 0, 0, 0, paris)))
+}
+
+func TestNewReceipt(t *testing.T) {
+	assignment := models.Assignment{
+		ID:         12,
+		UserID:     "user-1",
+		BikeID:     "bike-1",
+		AssignedAt: sql.NullTime{Time: time.Date(2024, 7, 3, 8, 0, 0, 0, time.UTC), Valid: true},
+	}
+	plan := &models.Plan{ID: "monthly"}
+
+	receipt := NewReceipt(assignment, time.Date(2024, 7, 3, 8, 30, 0, 0, time.UTC), pricing.Fare{Currency: "EUR", Minutes: 30}, plan)
+	assert.Equal(t, uint(12), receipt.AssignmentID)
+	assert.Equal(t, sql.NullString{String: "monthly", Valid: true}, receipt.PlanID)
+	assert.Equal(t, int64(30), receipt.Minutes)
+	assert.Equal(t, int64(0), receipt.TotalCents)
+}
@@ -0,0 +1,112 @@
+package invoices
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+// issuer is printed at the top of every document
+const issuer = "Bike Rental"
+
+// WriteReceiptPDF renders a receipt as a one page A4 PDF
+func WriteReceiptPDF(w io.Writer, receipt models.Receipt) error {
+	pdf := newDocument(fmt.Sprintf("Receipt for ride %d", receipt.AssignmentID))
+
+	field(pdf, "User", receipt.UserID)
+	field(pdf, "Bike", receipt.BikeID)
+	field(pdf, "Started", formatTime(receipt.StartedAt))
+	field(pdf, "Ended", formatTime(receipt.EndedAt))
+	field(pdf, "Duration", fmt.Sprintf("%d min", receipt.Minutes))
+	if receipt.PlanID.Valid {
+		field(pdf, "Plan", receipt.PlanID.String)
+	}
+	pdf.Ln(6)
+
+	amount(pdf, "Unlock fee", receipt.UnlockCents, receipt.Currency, false)
+	amount(pdf, "Time", receipt.TimeCents, receipt.Currency, false)
+	if receipt.PenaltyCents > 0 {
+		amount(pdf, "Overdue penalty", receipt.PenaltyCents, receipt.Currency, false)
+	}
+	amount(pdf, "Total", receipt.TotalCents, receipt.Currency, true)
+
+	return pdf.Output(w)
+}
+
+// WriteInvoicePDF renders an invoice and its lines as an A4 PDF
+func WriteInvoicePDF(w io.Writer, invoice models.Invoice) error {
+	pdf := newDocument("Invoice " + invoice.Number)
+
+	field(pdf, "User", invoice.UserID)
+	field(pdf, "Period", fmt.Sprintf("%s to %s", invoice.PeriodStart.Format(time.DateOnly), invoice.PeriodEnd.AddDate(0, 0, -1).Format(time.DateOnly)))
+	field(pdf, "Issued", formatTime(invoice.IssuedAt))
+	field(pdf, "Rides", fmt.Sprint(invoice.Rides))
+	pdf.Ln(6)
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(45, 7, "Date", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(105, 7, "Description", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(40, 7, "Amount", "B", 1, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range invoice.Lines {
+		pdf.CellFormat(45, 6, formatTime(line.OccurredAt), "", 0, "L", false, 0, "")
+		pdf.CellFormat(105, 6, line.Description, "", 0, "L", false, 0, "")
+		pdf.CellFormat(40, 6, formatAmount(line.AmountCents, invoice.Currency), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	amount(pdf, "Unlock fees", invoice.UnlockCents, invoice.Currency, false)
+	amount(pdf, "Ride time", invoice.TimeCents, invoice.Currency, false)
+	amount(pdf, "Overdue penalties", invoice.PenaltyCents, invoice.Currency, false)
+	amount(pdf, "Subscriptions", invoice.SubscriptionCents, invoice.Currency, false)
+	amount(pdf, "Replacement fees", invoice.ReplacementCents, invoice.Currency, false)
+	amount(pdf, "Refunds", invoice.RefundCents, invoice.Currency, false)
+	amount(pdf, "Total", invoice.TotalCents, invoice.Currency, true)
+
+	return pdf.Output(w)
+}
+
+// newDocument starts an A4 document with the issuer and title
+func newDocument(title string) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(title, false)
+	pdf.SetCreator(issuer, false)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, issuer, "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 12, title, "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	return pdf
+}
+
+func field(pdf *gofpdf.Fpdf, label, value string) {
+	pdf.CellFormat(40, 6, label, "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 6, value, "", 1, "L", false, 0, "")
+}
+
+func amount(pdf *gofpdf.Fpdf, label string, cents int64, currency string, total bool) {
+	if total {
+		pdf.SetFont("Helvetica", "B", 11)
+		defer pdf.SetFont("Helvetica", "", 10)
+	}
+	pdf.CellFormat(150, 7, label, "", 0, "R", false, 0, "")
+	pdf.CellFormat(40, 7, formatAmount(cents, currency), "", 1, "R", false, 0, "")
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+// formatAmount formats cents of currency, e.g. "12.30 EUR"
+func formatAmount(cents int64, currency string) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d %s", sign, cents/100, cents%100, currency)
+}
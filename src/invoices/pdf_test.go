@@ -0,0 +1,58 @@
+package invoices
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func TestWriteInvoicePDF(t *testing.T) {
+	invoice := models.Invoice{
+		Number:      "INV-2024-000042",
+		UserID:      "user-1",
+		PeriodStart: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:   time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC),
+		Currency:    "EUR",
+		Rides:       1,
+		UnlockCents: 100,
+		TimeCents:   450,
+		TotalCents:  550,
+		IssuedAt:    time.Date(2024, 8, 1, 2, 0, 0, 0, time.UTC),
+		Lines: []models.InvoiceLine{
+			{Kind: models.InvoiceLineRide, Description: "Ride of 30 min", AssignmentID: sql.NullInt64{Int64: 12, Valid: true}, OccurredAt: time.Date(2024, 7, 3, 8, 30, 0, 0, time.UTC), AmountCents: 550},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteInvoicePDF(&buf, invoice))
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+}
+
+func TestWriteReceiptPDF(t *testing.T) {
+	receipt := models.Receipt{
+		AssignmentID: 12,
+		UserID:       "user-1",
+		BikeID:       "bike-1",
+		StartedAt:    time.Date(2024, 7, 3, 8, 0, 0, 0, time.UTC),
+		EndedAt:      time.Date(2024, 7, 3, 8, 30, 0, 0, time.UTC),
+		Minutes:      30,
+		UnlockCents:  100,
+		TimeCents:    450,
+		TotalCents:   550,
+		Currency:     "EUR",
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteReceiptPDF(&buf, receipt))
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "12.30 EUR", formatAmount(1230, "EUR"))
+	assert.Equal(t, "0.05 EUR", formatAmount(5, "EUR"))
+	assert.Equal(t, "-1.00 GBP", formatAmount(-100, "GBP"))
+}
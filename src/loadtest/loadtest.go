@@ -0,0 +1,281 @@
+// Package loadtest sends assign and unassign requests to a running server
+// at a steady rate, on behalf of a pool of users, and measures their
+// latency. The state of the fleet is checked afterwards.
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/api"
+	"github.com/yourusername/bike-rental/src/audit"
+)
+
+// Endpoints under load
+const (
+	EndpointAssign   = "/v1/bikes/assign"
+	EndpointUnassign = "/v1/bikes/unassign"
+)
+
+// Config describes the traffic to send
+type Config struct {
+	URL         string        // of the server, e.g. http://localhost:8080
+	Users       []string      // UUIDs of the customers the requests are made for
+	Rate        float64       // requests per second
+	Duration    time.Duration // of the traffic
+	Concurrency int           // requests in flight at most
+	Timeout     time.Duration // of each request
+	StationID   string        // where the bikes are taken and returned, any when empty
+	TopUp       int64         // credited to each user before the run, in cents
+	Operator    string        // UUID of the supervisor recording the top-ups
+	Seed        int64
+}
+
+// Validate checks the config describes some traffic
+func (config *Config) Validate() error {
+	switch {
+	case config.URL == "":
+		return errors.New("url is required")
+	case len(config.Users) == 0:
+		return errors.New("at least one user is required")
+	case config.Rate <= 0:
+		return errors.New("rate must be positive")
+	case config.Duration <= 0:
+		return errors.New("duration must be positive")
+	case config.Concurrency < 1:
+		return errors.New("concurrency must be at least 1")
+	case config.TopUp < 0:
+		return errors.New("top-up must not be negative")
+	case config.TopUp > 0 && config.Operator == "":
+		return errors.New("operator is required to top up")
+	}
+	return nil
+}
+
+// user is a customer of the pool, riding the bike it holds if any. A user
+// has one request in flight at most.
+type user struct {
+	id   string
+	bike string
+	busy bool
+}
+
+type runner struct {
+	config Config
+	client *http.Client
+
+	mu      sync.Mutex
+	users   []*user
+	rng     *rand.Rand
+	results map[string]*Result
+}
+
+// Run sends the traffic of config, returns the bikes still ridden at the
+// end and checks the invariants of the fleet
+func Run(ctx context.Context, config Config) (*Report, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := &runner{
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: &http.Transport{MaxIdleConnsPerHost: config.Concurrency},
+		},
+		rng:     rand.New(rand.NewSource(config.Seed)),
+		results: map[string]*Result{EndpointAssign: {}, EndpointUnassign: {}},
+	}
+	for _, id := range config.Users {
+		r.users = append(r.users, &user{id: id})
+	}
+
+	if config.TopUp > 0 {
+		for _, u := range r.users {
+			if err := r.topUp(ctx, u.id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	report := &Report{Config: config, Results: r.results}
+	start := time.Now()
+	report.Skipped = r.send(ctx)
+	report.Elapsed = time.Since(start)
+
+	// Leave the fleet as it was found, so the usage counts can be checked
+	for _, u := range r.users {
+		if u.bike != "" {
+			r.unassign(context.Background(), u)
+		}
+	}
+
+	var err error
+	report.Violations, err = r.verify(context.Background())
+	if err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// send issues the requests at the configured rate until the duration is
+// over, and returns how many could not be sent because every user or
+// every connection was busy
+func (r *runner) send(ctx context.Context) int {
+	ctx, cancel := context.WithTimeout(ctx, r.config.Duration)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / r.config.Rate))
+	defer ticker.Stop()
+
+	slots := make(chan struct{}, r.config.Concurrency)
+	var wg sync.WaitGroup
+	skipped := 0
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return skipped
+		case <-ticker.C:
+		}
+
+		u := r.idleUser()
+		if u == nil {
+			skipped++
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			r.release(u)
+			skipped++
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer r.release(u)
+
+			// Requests are not cut short at the end of the run
+			if u.bike == "" {
+				r.assign(context.Background(), u)
+			} else {
+				r.unassign(context.Background(), u)
+			}
+		}()
+	}
+}
+
+// idleUser picks a random user without a request in flight, and marks it
+// busy
+func (r *runner) idleUser() *user {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	offset := r.rng.Intn(len(r.users))
+	for i := range r.users {
+		u := r.users[(offset+i)%len(r.users)]
+		if !u.busy {
+			u.busy = true
+			return u
+		}
+	}
+	return nil
+}
+
+func (r *runner) release(u *user) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u.busy = false
+}
+
+func (r *runner) assign(ctx context.Context, u *user) {
+	body := map[string]interface{}{"user_uuid": u.id}
+	if r.config.StationID != "" {
+		body["station_id"] = r.config.StationID
+	}
+
+	resp, ok := r.call(ctx, EndpointAssign, body)
+	if ok && resp.StatusCode == http.StatusOK {
+		u.bike = resp.Header.Get(api.BikeHeader)
+	}
+}
+
+func (r *runner) unassign(ctx context.Context, u *user) {
+	body := map[string]interface{}{"user_uuid": u.id, "bike_uuid": u.bike}
+	if r.config.StationID != "" {
+		body["station_id"] = r.config.StationID
+	}
+
+	resp, ok := r.call(ctx, EndpointUnassign, body)
+	// Not found means the bike was returned already, e.g. by the
+	// request timing out on our side
+	if ok && (resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound) {
+		u.bike = ""
+	}
+}
+
+// call posts body to an endpoint and records the outcome. ok is false when
+// no response was received.
+func (r *runner) call(ctx context.Context, endpoint string, body interface{}) (*http.Response, bool) {
+	encoded, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.config.URL+endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		r.record(endpoint, 0, 0)
+		return nil, false
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		r.record(endpoint, 0, latency)
+		return nil, false
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	r.record(endpoint, resp.StatusCode, latency)
+	return resp, true
+}
+
+func (r *runner) record(endpoint string, status int, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results[endpoint].add(status, latency)
+}
+
+// topUp credits a user so that they can be assigned bikes
+func (r *runner) topUp(ctx context.Context, userID string) error {
+	encoded, _ := json.Marshal(map[string]interface{}{"amount_cents": r.config.TopUp})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.config.URL+"/v1/users/"+userID+"/wallet/top-ups", bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(audit.OperatorHeader, r.config.Operator)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to top up %s: %w", userID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to top up %s: %s %s", userID, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/bike-rental/src/api"
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+// fleet serves the endpoints of the load test from memory
+type fleet struct {
+	mu          sync.Mutex
+	bikes       []models.Bike
+	assignments []models.Assignment
+	topUps      int
+}
+
+func (f *fleet) routes() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/v1/bikes/assign", f.assign)
+	r.Post("/v1/bikes/unassign", f.unassign)
+	r.Get("/v1/assignments", func(w http.ResponseWriter, r *http.Request) { f.encode(w, f.assignments) })
+	r.Get("/v1/bikes", func(w http.ResponseWriter, r *http.Request) { f.encode(w, f.bikes) })
+	r.Post("/v1/users/{id}/wallet/top-ups", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(audit.OperatorHeader) == "" {
+			http.Error(w, audit.OperatorHeader+" header is required", http.StatusUnauthorized)
+			return
+		}
+		f.mu.Lock()
+		f.topUps++
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	})
+	return r
+}
+
+func (f *fleet) encode(w http.ResponseWriter, v interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	json.NewEncoder(w).Encode(v)
+}
+
+func (f *fleet) assign(w http.ResponseWriter, r *http.Request) {
+	var req api.AssignBikeRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.bikes {
+		if !f.bikes[i].IsAssigned {
+			f.bikes[i].IsAssigned = true
+			f.bikes[i].UsageCount++
+			f.assignments = append(f.assignments, models.Assignment{ID: uint(len(f.assignments) + 1), UserID: req.UserUUID, BikeID: f.bikes[i].ID})
+			w.Header().Set(api.BikeHeader, f.bikes[i].ID)
+			w.Write([]byte("Bike assigned successfully"))
+			return
+		}
+	}
+	http.Error(w, "No available bikes", http.StatusNotFound)
+}
+
+func (f *fleet) unassign(w http.ResponseWriter, r *http.Request) {
+	var req api.UnassignBikeRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.assignments {
+		assignment := &f.assignments[i]
+		if assignment.BikeID == req.BikeUUID && assignment.UserID == req.UserUUID && !assignment.UnassignedAt.Valid {
+			assignment.UnassignedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			for j := range f.bikes {
+				if f.bikes[j].ID == req.BikeUUID {
+					f.bikes[j].IsAssigned = false
+				}
+			}
+			w.Write([]byte("Bike unassigned successfully"))
+			return
+		}
+	}
+	http.Error(w, "Bike not found or not assigned to the user", http.StatusNotFound)
+}
+
+func TestRun(t *testing.T) {
+	f := &fleet{bikes: []models.Bike{{ID: "331e7ffb-e583-4535-ba41-4c28dc34016d"}, {ID: "e4ef2d9b-5d5a-4f85-bb3a-b2df8bf42ac1"}}}
+	ts := httptest.NewServer(f.routes())
+	defer ts.Close()
+
+	config := Config{
+		URL:         ts.URL,
+		Users:       []string{"d0ab33d7-8fcc-463d-bade-fefd53b77a96", "0b28a7ed-39ef-418f-a0e3-8ad3f794dfc7", "5f3c8a2e-9d41-4b7e-a6c0-2e8f1b7d4c93"},
+		Rate:        200,
+		Duration:    300 * time.Millisecond,
+		Concurrency: 4,
+		Timeout:     time.Second,
+		TopUp:       10000,
+		Operator:    "da690323-5a78-4d46-a214-943b2ec9d49e",
+		Seed:        1,
+	}
+	report, err := Run(context.Background(), config)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, f.topUps)
+	assert.Empty(t, report.Violations)
+
+	// Three users share two bikes
+	assign := report.Results[EndpointAssign]
+	assert.Greater(t, assign.Statuses[http.StatusOK], 0)
+	assert.Greater(t, assign.Statuses[http.StatusNotFound], 0)
+	assert.Equal(t, assign.Statuses[http.StatusOK], report.Results[EndpointUnassign].Statuses[http.StatusOK])
+	assert.Len(t, assign.Latencies, assign.Requests())
+
+	// Every bike was returned
+	for _, bike := range f.bikes {
+		assert.False(t, bike.IsAssigned)
+	}
+
+	var out bytes.Buffer
+	report.Print(&out)
+	assert.Contains(t, out.String(), "Invariants hold")
+	assert.Contains(t, out.String(), "/v1/bikes/assign")
+}
+
+func TestRun_Violations(t *testing.T) {
+	// The usage count is not increased
+	f := &fleet{bikes: []models.Bike{{ID: "331e7ffb-e583-4535-ba41-4c28dc34016d", UsageCount: -1000}}}
+	ts := httptest.NewServer(f.routes())
+	defer ts.Close()
+
+	config := Config{URL: ts.URL, Users: []string{"d0ab33d7-8fcc-463d-bade-fefd53b77a96"}, Rate: 100, Duration: 50 * time.Millisecond, Concurrency: 1, Timeout: time.Second}
+	report, err := Run(context.Background(), config)
+	require.NoError(t, err)
+
+	require.Len(t, report.Violations, 1)
+	assert.True(t, strings.HasPrefix(report.Violations[0].Message, "usage count"))
+
+	var out bytes.Buffer
+	report.Print(&out)
+	assert.Contains(t, out.String(), "1 invariant violations")
+}
+
+func TestResult_Percentile(t *testing.T) {
+	result := &Result{}
+	for i := 10; i >= 1; i-- {
+		result.add(http.StatusOK, time.Duration(i)*time.Millisecond)
+	}
+	result.add(0, time.Second)
+
+	assert.Equal(t, 11, result.Requests())
+	assert.Equal(t, 5*time.Millisecond, result.Percentile(50))
+	assert.Equal(t, 9*time.Millisecond, result.Percentile(90))
+	assert.Equal(t, 10*time.Millisecond, result.Percentile(100))
+	assert.Equal(t, "error: 1, 200 OK: 10", statuses(result.Statuses))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	config := Config{URL: "http://localhost:8080", Users: []string{"d0ab33d7-8fcc-463d-bade-fefd53b77a96"}, Rate: 10, Duration: time.Second, Concurrency: 1}
+	assert.NoError(t, config.Validate())
+
+	config.TopUp = 10000
+	assert.EqualError(t, config.Validate(), "operator is required to top up")
+
+	config.Users = nil
+	_, err := Run(context.Background(), config)
+	assert.EqualError(t, err, "at least one user is required")
+}
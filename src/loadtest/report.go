@@ -0,0 +1,118 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Result gathers the responses of an endpoint
+type Result struct {
+	Statuses  map[int]int // responses by status code, 0 for transport errors
+	Latencies []time.Duration
+}
+
+func (r *Result) add(status int, latency time.Duration) {
+	if r.Statuses == nil {
+		r.Statuses = map[int]int{}
+	}
+	r.Statuses[status]++
+	if status != 0 {
+		r.Latencies = append(r.Latencies, latency)
+	}
+}
+
+// Requests returns the number of requests sent
+func (r *Result) Requests() int {
+	total := 0
+	for _, count := range r.Statuses {
+		total += count
+	}
+	return total
+}
+
+// Percentile returns the latency under which p percent of the responses
+// were received
+func (r *Result) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	latencies := append([]time.Duration(nil), r.Latencies...)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	// Nearest rank
+	rank := int(math.Ceil(p / 100 * float64(len(latencies))))
+	if rank < 1 {
+		rank = 1
+	}
+	return latencies[rank-1]
+}
+
+// Report is the outcome of a run
+type Report struct {
+	Config     Config
+	Elapsed    time.Duration
+	Skipped    int // not sent, every user or connection being busy
+	Results    map[string]*Result
+	Violations []Violation
+}
+
+// Print writes the throughput and latencies of each endpoint, then the
+// invariants violated
+func (r *Report) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Target\t%s, %.1f req/s for %s, %d users, %d connections\n", r.Config.URL, r.Config.Rate, r.Config.Duration, len(r.Config.Users), r.Config.Concurrency)
+	fmt.Fprintf(tw, "Skipped\t%d, every user or connection being busy\n", r.Skipped)
+	tw.Flush()
+	fmt.Fprintln(w)
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Endpoint\tRequests\tReq/s\tp50\tp90\tp95\tp99\tMax\tStatuses")
+	for _, endpoint := range []string{EndpointAssign, EndpointUnassign} {
+		result := r.Results[endpoint]
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\t%s\n", endpoint, result.Requests(),
+			float64(result.Requests())/r.Elapsed.Seconds(),
+			round(result.Percentile(50)), round(result.Percentile(90)), round(result.Percentile(95)),
+			round(result.Percentile(99)), round(result.Percentile(100)), statuses(result.Statuses))
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+
+	if len(r.Violations) == 0 {
+		fmt.Fprintln(w, "Invariants hold: no bike in two open assignments, usage counts match the assignments")
+		return
+	}
+	fmt.Fprintf(w, "%d invariant violations:\n", len(r.Violations))
+	for _, violation := range r.Violations {
+		fmt.Fprintf(w, "  %s\n", violation)
+	}
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(10 * time.Microsecond)
+}
+
+// statuses lists the status codes of the responses and their count, e.g.
+// "200 OK: 120, 404 Not Found: 3"
+func statuses(counts map[int]int) string {
+	codes := make([]int, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	parts := make([]string, 0, len(codes))
+	for _, code := range codes {
+		text := "error"
+		if code != 0 {
+			text = fmt.Sprintf("%d %s", code, http.StatusText(code))
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d", text, counts[code]))
+	}
+	return strings.Join(parts, ", ")
+}
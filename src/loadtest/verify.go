@@ -0,0 +1,90 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+// Violation is an invariant of the fleet not holding after the run
+type Violation struct {
+	BikeID  string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("bike %s: %s", v.BikeID, v.Message)
+}
+
+// Check returns the bikes breaking the invariants the assignments must
+// keep under concurrent requests: a bike is in one open assignment at most,
+// and its usage count is the number of its assignments
+func Check(assignments []models.Assignment, bikes []models.Bike) []Violation {
+	open := map[string]int{}
+	total := map[string]int{}
+	for _, assignment := range assignments {
+		total[assignment.BikeID]++
+		if !assignment.UnassignedAt.Valid {
+			open[assignment.BikeID]++
+		}
+	}
+
+	var violations []Violation
+	for bikeID, count := range open {
+		if count > 1 {
+			violations = append(violations, Violation{BikeID: bikeID, Message: fmt.Sprintf("in %d open assignments", count)})
+		}
+	}
+	for _, bike := range bikes {
+		if bike.UsageCount != total[bike.ID] {
+			violations = append(violations, Violation{BikeID: bike.ID, Message: fmt.Sprintf("usage count %d but %d assignments", bike.UsageCount, total[bike.ID])})
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].BikeID == violations[j].BikeID {
+			return violations[i].Message < violations[j].Message
+		}
+		return violations[i].BikeID < violations[j].BikeID
+	})
+	return violations
+}
+
+// verify fetches the assignments and the bikes from the server and checks
+// them
+func (r *runner) verify(ctx context.Context) ([]Violation, error) {
+	var assignments []models.Assignment
+	if err := r.get(ctx, "/v1/assignments", &assignments); err != nil {
+		return nil, err
+	}
+	var bikes []models.Bike
+	if err := r.get(ctx, "/v1/bikes", &bikes); err != nil {
+		return nil, err
+	}
+	return Check(assignments, bikes), nil
+}
+
+func (r *runner) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.config.URL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package loadtest
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func TestCheck(t *testing.T) {
+	bikeID := "331e7ffb-e583-4535-ba41-4c28dc34016d"
+	otherBikeID := "e4ef2d9b-5d5a-4f85-bb3a-b2df8bf42ac1"
+	returned := sql.NullTime{Time: time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC), Valid: true}
+
+	assignments := []models.Assignment{
+		{ID: 1, BikeID: bikeID, UnassignedAt: returned},
+		{ID: 2, BikeID: bikeID},
+		{ID: 3, BikeID: otherBikeID, UnassignedAt: returned},
+	}
+	bikes := []models.Bike{{ID: bikeID, UsageCount: 2}, {ID: otherBikeID, UsageCount: 1}}
+	assert.Empty(t, Check(assignments, bikes))
+
+	// Assigned twice concurrently, the usage count being increased once
+	assignments = append(assignments, models.Assignment{ID: 4, BikeID: bikeID})
+	assert.Equal(t, []Violation{
+		{BikeID: bikeID, Message: "in 2 open assignments"},
+		{BikeID: bikeID, Message: "usage count 2 but 3 assignments"},
+	}, Check(assignments, bikes))
+}
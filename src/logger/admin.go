@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+type levelsResponse struct {
+	Level    string            `json:"level"`
+	Packages map[string]string `json:"packages"`
+}
+
+// SetLevelRequest changes the default level, or the level of a single
+// package when Package is set. An empty Level drops a package override.
+type SetLevelRequest struct {
+	Package string `json:"package"`
+	Level   string `json:"level"`
+}
+
+func writeLevels(w http.ResponseWriter) {
+	level, packages := Levels()
+
+	response := levelsResponse{Level: level.String(), Packages: map[string]string{}}
+	for pkg, pkgLevel := range packages {
+		response.Packages[pkg] = pkgLevel.String()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode log levels to JSON", http.StatusInternalServerError)
+	}
+}
+
+// GetLevels returns the current log levels
+func GetLevels(w http.ResponseWriter, r *http.Request) {
+	writeLevels(w)
+}
+
+// UpdateLevel changes a log level without restarting the server
+func UpdateLevel(w http.ResponseWriter, r *http.Request) {
+	var req SetLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Level == "" {
+		if req.Package == "" {
+			http.Error(w, "level is required", http.StatusBadRequest)
+			return
+		}
+		ResetLevel(req.Package)
+		writeLevels(w)
+		return
+	}
+
+	level, err := ParseLevel(req.Level)
+	if err != nil || level == zerolog.NoLevel {
+		http.Error(w, "Unknown log level", http.StatusBadRequest)
+		return
+	}
+
+	SetLevel(req.Package, level)
+	writeLevels(w)
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateLevel(t *testing.T) {
+	defer SetLevel("", zerolog.InfoLevel)
+	defer ResetLevel("cronjobs")
+
+	// Lower the level of a single package
+	req := httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"package":"cronjobs","level":"debug"}`))
+	rr := httptest.NewRecorder()
+	UpdateLevel(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response levelsResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, "info", response.Level)
+	assert.Equal(t, "debug", response.Packages["cronjobs"])
+
+	// The package logger honours the override, the others keep the default
+	assert.Equal(t, zerolog.DebugLevel, For("cronjobs").GetLevel())
+	assert.Equal(t, zerolog.InfoLevel, For("controllers").GetLevel())
+	assert.Equal(t, zerolog.DebugLevel, zerolog.GlobalLevel())
+
+	// Dropping the override restores the default
+	req = httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"package":"cronjobs"}`))
+	rr = httptest.NewRecorder()
+	UpdateLevel(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, zerolog.InfoLevel, For("cronjobs").GetLevel())
+	assert.Equal(t, zerolog.InfoLevel, zerolog.GlobalLevel())
+}
+
+func TestUpdateLevel_UnknownLevel(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"verbose"}`))
+	rr := httptest.NewRecorder()
+	UpdateLevel(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Unknown log level\n", rr.Body.String())
+}
@@ -1,36 +1,217 @@
 package logger
 
 import (
+	"io"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-var once sync.Once
+// Config holds the [logging] section of the configuration file
+type Config struct {
+	Format   string            `toml:"format"`   // "console" (default) or "json"
+	Level    string            `toml:"level"`    // default level, e.g. "info"
+	Packages map[string]string `toml:"packages"` // per-package level overrides
+	Sampling SamplingConfig    `toml:"sampling"`
+	File     FileConfig        `toml:"file"`
+}
+
+// SamplingConfig controls sampling of the per-request logs
+type SamplingConfig struct {
+	Enabled bool          `toml:"enabled"`
+	Burst   uint32        `toml:"burst"`  // requests logged in full per period
+	Period  time.Duration `toml:"period"` // e.g. "1s"
+	Every   uint32        `toml:"every"`  // past the burst, log 1 in every N requests
+}
+
+// FileConfig enables an additional JSON log file with size based rotation
+type FileConfig struct {
+	Path       string `toml:"path"`
+	MaxSizeMB  int    `toml:"max_size_mb"`
+	MaxBackups int    `toml:"max_backups"`
+	MaxAgeDays int    `toml:"max_age_days"`
+	Compress   bool   `toml:"compress"`
+}
 
-// Init initializes the global logger
-func Init() {
+var (
+	once sync.Once
+
+	mu            sync.RWMutex
+	base          zerolog.Logger
+	defaultLevel  = zerolog.InfoLevel
+	packageLevels = map[string]zerolog.Level{}
+	requestLogger zerolog.Logger
+)
+
+// Init initializes the global logger. A nil config keeps the historical
+// console output at info level.
+func Init(config *Config) {
 	once.Do(func() {
+		if config == nil {
+			config = &Config{}
+		}
+
+		level, err := ParseLevel(config.Level)
+		if err != nil {
+			level = zerolog.InfoLevel
+		}
+
+		levels := map[string]zerolog.Level{}
+		for pkg, value := range config.Packages {
+			pkgLevel, err := ParseLevel(value)
+			if err != nil {
+				continue
+			}
+			levels[pkg] = pkgLevel
+		}
+
 		// Configure the global logger
-		output := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02 15:04:05"}
-		log.Logger = zerolog.New(output).With().Timestamp().Caller().Logger()
+		base = zerolog.New(newWriter(config)).With().Timestamp().Caller().Logger().Hook(traceHook{})
+
+		// Request logs can be very noisy, so they may be sampled
+		requestLogger = base
+		if config.Sampling.Enabled {
+			requestLogger = base.Sample(newSampler(&config.Sampling))
+		}
+
+		mu.Lock()
+		defaultLevel = level
+		packageLevels = levels
+		apply()
+		mu.Unlock()
 
-		// Set the global log level (optional)
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+		if err != nil {
+			log.Warn().Str("level", config.Level).Msg("Unknown log level, falling back to info")
+		}
 	})
 }
 
+func newWriter(config *Config) io.Writer {
+	var stdout io.Writer = os.Stdout
+	if !strings.EqualFold(config.Format, "json") {
+		stdout = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02 15:04:05"}
+	}
+
+	if config.File.Path == "" {
+		return stdout
+	}
+
+	// The file output is always JSON so it can be shipped as is
+	file := &lumberjack.Logger{
+		Filename:   config.File.Path,
+		MaxSize:    config.File.MaxSizeMB,
+		MaxBackups: config.File.MaxBackups,
+		MaxAge:     config.File.MaxAgeDays,
+		Compress:   config.File.Compress,
+	}
+	return zerolog.MultiLevelWriter(stdout, file)
+}
+
+func newSampler(config *SamplingConfig) zerolog.Sampler {
+	var next zerolog.Sampler
+	if config.Every > 1 {
+		next = &zerolog.BasicSampler{N: config.Every}
+	}
+
+	period := config.Period
+	if period <= 0 {
+		period = time.Second
+	}
+
+	return &zerolog.BurstSampler{Burst: config.Burst, Period: period, NextSampler: next}
+}
+
+// ParseLevel parses a level name, an empty name meaning info
+func ParseLevel(value string) (zerolog.Level, error) {
+	if value == "" {
+		return zerolog.InfoLevel, nil
+	}
+	return zerolog.ParseLevel(strings.ToLower(value))
+}
+
+// apply propagates the configured levels to zerolog. The global level is
+// set to the most verbose configured level so package overrides can go
+// below the default one. Callers must hold mu.
+func apply() {
+	lowest := defaultLevel
+	for _, level := range packageLevels {
+		if level < lowest {
+			lowest = level
+		}
+	}
+	zerolog.SetGlobalLevel(lowest)
+	log.Logger = base.Level(defaultLevel)
+}
+
+// For returns the logger of a package, honouring its level override
+func For(pkg string) *zerolog.Logger {
+	mu.RLock()
+	level, ok := packageLevels[pkg]
+	if !ok {
+		level = defaultLevel
+	}
+	logger := log.Logger.With().Str("pkg", pkg).Logger().Level(level)
+	mu.RUnlock()
+
+	return &logger
+}
+
+// Levels returns the default level and the per-package overrides
+func Levels() (zerolog.Level, map[string]zerolog.Level) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	levels := make(map[string]zerolog.Level, len(packageLevels))
+	for pkg, level := range packageLevels {
+		levels[pkg] = level
+	}
+	return defaultLevel, levels
+}
+
+// SetLevel changes the level at runtime. An empty package changes the
+// default level.
+func SetLevel(pkg string, level zerolog.Level) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if pkg == "" {
+		defaultLevel = level
+	} else {
+		packageLevels[pkg] = level
+	}
+	apply()
+}
+
+// ResetLevel drops the override of a package
+func ResetLevel(pkg string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(packageLevels, pkg)
+	apply()
+}
+
 func LoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		mu.RLock()
+		level, ok := packageLevels["http"]
+		if !ok {
+			level = defaultLevel
+		}
+		mu.RUnlock()
+
 		// Create a sub-logger with request-specific fields
-		logger := log.With().
+		logger := requestLogger.Level(level).With().
+			Ctx(r.Context()).
 			Str("method", r.Method).
 			Str("url", r.URL.String()).
 			Str("remote_addr", r.RemoteAddr).
@@ -0,0 +1,20 @@
+package logger
+
+import (
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceHook adds the trace and span IDs of the event context, set with
+// Ctx(), so logs can be correlated with traces
+type traceHook struct{}
+
+func (traceHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	spanContext := trace.SpanContextFromContext(e.GetCtx())
+	if !spanContext.IsValid() {
+		return
+	}
+
+	e.Str("trace_id", spanContext.TraceID().String()).
+		Str("span_id", spanContext.SpanID().String())
+}
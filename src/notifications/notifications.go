@@ -0,0 +1,33 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+// Notification kinds
+const (
+	KindWaitlistBikeHeld    = "waitlist.bike_held"
+	KindSubscriptionExpired = "subscription.expired"
+	KindAssignmentDueSoon   = "assignment.due_soon"
+	KindAssignmentOverdue   = "assignment.overdue"
+)
+
+// Execer is satisfied by both *sql.DB and *sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Enqueue adds a notification to the outbox, to be delivered to the user
+// by the notification service
+func Enqueue(ctx context.Context, db Execer, userID, kind string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO notifications (user_id, kind, payload) VALUES ($1, $2, $3)"
+	_, err = db.ExecContext(ctx, query, userID, kind, string(data))
+	return err
+}
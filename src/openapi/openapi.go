@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// UUIDPattern is the UUID format accepted by Postgres in its canonical
+// form, shared with the handlers checking the identifiers of the URLs
+const UUIDPattern = `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`
+
+//go:embed openapi.json
+var spec []byte
+
+//go:embed swagger.html
+var swaggerUI []byte
+
+func init() {
+	openapi3.DefineStringFormatValidator("uuid", openapi3.NewRegexpFormatValidator(UUIDPattern))
+}
+
+// Load parses and validates the OpenAPI document
+func Load() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the OpenAPI document: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return doc, nil
+}
+
+// ServeSpec serves the OpenAPI document
+func ServeSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
+
+// ServeSwaggerUI serves a Swagger UI page browsing the OpenAPI document
+func ServeSwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(swaggerUI)
+}
+
+// Rejection tells why the validator rejected a request: its status, a
+// message, and the offending parameter or body field when known
+type Rejection struct {
+	Status  int
+	Message string
+	Field   string
+	Reason  string
+}
+
+// NewValidator returns a middleware rejecting requests that do not match
+// the OpenAPI document before they reach the handlers, the response being
+// written by reject. Routes missing from the document are let through.
+func NewValidator(doc *openapi3.T, reject func(http.ResponseWriter, Rejection)) (func(http.Handler) http.Handler, error) {
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the OpenAPI router: %w", err)
+	}
+
+	options := &openapi3filter.Options{
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		MultiError:         false,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				var routeErr *routers.RouteError
+				if errors.As(err, &routeErr) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				reject(w, Rejection{Status: http.StatusBadRequest, Message: "Invalid request"})
+				return
+			}
+
+			// The bodies are JSON only
+			if body := route.Operation.RequestBody; body != nil && body.Value != nil && body.Value.Content.Get(r.Header.Get("Content-Type")) == nil {
+				reject(w, Rejection{Status: http.StatusUnsupportedMediaType, Message: "Content-Type must be application/json"})
+				return
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			}
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				reject(w, describe(err))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// describe turns a validation error into a rejection naming the offending
+// parameter or body field
+func describe(err error) Rejection {
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return Rejection{Status: http.StatusBadRequest, Message: "Invalid request", Reason: err.Error()}
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(requestErr.Err, &maxBytesErr) {
+		return Rejection{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit)}
+	}
+
+	rejection := Rejection{Status: http.StatusBadRequest, Message: "Invalid request"}
+	if requestErr.RequestBody != nil {
+		rejection.Message = "Invalid request payload"
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(requestErr.Err, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			rejection.Field = pointer[len(pointer)-1]
+		}
+		if requestErr.Parameter != nil {
+			rejection.Field = requestErr.Parameter.Name
+		}
+		rejection.Reason = schemaErr.Reason
+
+		// Word the common errors as the handlers do
+		switch {
+		case schemaErr.SchemaField == "format" && schemaErr.Schema.Format == "uuid":
+			rejection.Reason = "must be a UUID"
+		case schemaErr.SchemaField == "required":
+			rejection.Reason = "is required"
+		case schemaErr.SchemaField == "properties" && strings.HasSuffix(schemaErr.Reason, " is unsupported"):
+			// The reason is: property "name" is unsupported
+			name := strings.TrimSuffix(strings.TrimPrefix(schemaErr.Reason, "property "), " is unsupported")
+			if field, err := strconv.Unquote(name); err == nil {
+				rejection.Field, rejection.Reason = field, "is not allowed"
+			}
+		}
+		return rejection
+	}
+
+	switch {
+	case requestErr.Parameter != nil && requestErr.Parameter.In == openapi3.ParameterInHeader && errors.Is(requestErr.Err, openapi3filter.ErrInvalidRequired):
+		// The required headers identify the operator
+		return Rejection{Status: http.StatusUnauthorized, Message: requestErr.Parameter.Name + " header is required"}
+	case requestErr.Parameter != nil && errors.Is(requestErr.Err, openapi3filter.ErrInvalidRequired):
+		rejection.Field, rejection.Reason = requestErr.Parameter.Name, "is required"
+	case requestErr.Parameter != nil:
+		rejection.Field, rejection.Reason = requestErr.Parameter.Name, requestErr.Reason
+	case requestErr.Err != nil:
+		rejection.Reason = requestErr.Err.Error()
+	default:
+		rejection.Reason = requestErr.Reason
+	}
+	return rejection
+}
@@ -0,0 +1,135 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHandler(t *testing.T) http.Handler {
+	doc, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load the OpenAPI document: %v", err)
+	}
+	validator, err := NewValidator(doc, func(w http.ResponseWriter, rejection Rejection) {
+		http.Error(w, rejection.Message+" ("+rejection.Field+": "+rejection.Reason+")", rejection.Status)
+	})
+	if err != nil {
+		t.Fatalf("Failed to build the validator: %v", err)
+	}
+
+	// The handler echoes the body to check it is still readable
+	return validator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+}
+
+func TestValidator(t *testing.T) {
+	handler := newTestHandler(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		header map[string]string
+		status int
+		error  string
+	}{
+		{
+			name:   "valid assign",
+			method: http.MethodPost,
+			path:   "/v1/bikes/assign",
+			body:   `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "malformed user UUID",
+			method: http.MethodPost,
+			path:   "/v1/bikes/assign",
+			body:   `{"user_uuid":"not-a-uuid"}`,
+			status: http.StatusBadRequest,
+			error:  "user_uuid: must be a UUID",
+		},
+		{
+			name:   "malformed user UUID on a legacy route",
+			method: http.MethodPost,
+			path:   "/bikes/assign",
+			body:   `{"user_uuid":"not-a-uuid"}`,
+			status: http.StatusBadRequest,
+			error:  "user_uuid",
+		},
+		{
+			name:   "unknown field",
+			method: http.MethodPost,
+			path:   "/v1/bikes/assign",
+			body:   `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96","bike_uuid":"331e7ffb-e583-4535-ba41-4c28dc34016d"}`,
+			status: http.StatusBadRequest,
+			error:  "bike_uuid: is not allowed",
+		},
+		{
+			name:   "missing bike UUID",
+			method: http.MethodPost,
+			path:   "/v1/bikes/unassign",
+			body:   `{"user_uuid":"d0ab33d7-8fcc-463d-bade-fefd53b77a96"}`,
+			status: http.StatusBadRequest,
+			error:  "bike_uuid",
+		},
+		{
+			name:   "malformed operator header",
+			method: http.MethodGet,
+			path:   "/v1/audit",
+			header: map[string]string{"X-Operator-UUID": "1234"},
+			status: http.StatusBadRequest,
+			error:  "X-Operator-UUID",
+		},
+		{
+			name:   "missing operator header",
+			method: http.MethodGet,
+			path:   "/v1/audit",
+			status: http.StatusUnauthorized,
+			error:  "X-Operator-UUID header is required",
+		},
+		{
+			name:   "invalid limit",
+			method: http.MethodGet,
+			path:   "/v1/audit?limit=0",
+			header: map[string]string{"X-Operator-UUID": "da690323-5a78-4d46-a214-943b2ec9d49e"},
+			status: http.StatusBadRequest,
+			error:  "limit",
+		},
+		{
+			name:   "route missing from the document",
+			method: http.MethodGet,
+			path:   "/docs",
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code, rr.Body.String())
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.body, rr.Body.String())
+			} else {
+				assert.Contains(t, rr.Body.String(), tt.error)
+			}
+		})
+	}
+}
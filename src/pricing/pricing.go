@@ -0,0 +1,85 @@
+package pricing
+
+import (
+	"time"
+)
+
+// defaultCurrency is used when the tariff does not set one
+const defaultCurrency = "EUR"
+
+const minutesPerDay = 24 * 60
+
+// Tariff holds the [api.pricing] section of the configuration file.
+// Amounts are in cents of the currency.
+type Tariff struct {
+	Currency       string `toml:"currency"`
+	UnlockFee      int64  `toml:"unlock_fee"`      // charged once per ride
+	PerMinute      int64  `toml:"per_minute"`      // charged per started minute
+	DailyCap       int64  `toml:"daily_cap"`       // caps the minutes charged per started day of a ride, 0 for none
+	FreeMinutes    int64  `toml:"free_minutes"`    // not charged at the start of a ride
+	OverduePenalty int64  `toml:"overdue_penalty"` // charged when the ride becomes overdue after 24 hours
+	ReplacementFee int64  `toml:"replacement_fee"` // charged when the bike ridden is declared lost or stolen
+}
+
+// Fare is the price of a ride and its breakdown
+type Fare struct {
+	Currency string `json:"currency"`
+	Minutes  int64  `json:"minutes"` // duration of the ride, started minutes counting as full ones
+	Unlock   int64  `json:"unlock"`
+	Time     int64  `json:"time"`
+	Penalty  int64  `json:"penalty"`
+	Total    int64  `json:"total"`
+}
+
+// Calculate prices a ride from assignedAt to unassignedAt. Overdue rides,
+// flagged by the escalation job, are charged the penalty on top. A nil
+// tariff makes every ride free.
+func (t *Tariff) Calculate(assignedAt, unassignedAt time.Time, overdue bool) Fare {
+	if t == nil {
+		return Fare{Currency: defaultCurrency}
+	}
+
+	fare := Fare{Currency: t.Currency, Unlock: t.UnlockFee}
+	if fare.Currency == "" {
+		fare.Currency = defaultCurrency
+	}
+
+	if duration := unassignedAt.Sub(assignedAt); duration > 0 {
+		fare.Minutes = int64((duration + time.Minute - 1) / time.Minute)
+	}
+
+	// The free minutes are taken off the first day(s), the cap applies to
+	// every started day
+	free := t.FreeMinutes
+	for remaining := fare.Minutes; remaining > 0; remaining -= minutesPerDay {
+		day := min(remaining, minutesPerDay)
+		charged := max(day-free, 0)
+		free = max(free-day, 0)
+
+		amount := charged * t.PerMinute
+		if t.DailyCap > 0 && amount > t.DailyCap {
+			amount = t.DailyCap
+		}
+		fare.Time += amount
+	}
+
+	if overdue {
+		fare.Penalty = t.OverduePenalty
+	}
+
+	fare.Total = fare.Unlock + fare.Time + fare.Penalty
+	return fare
+}
+
+// Subscribed returns the tariff of a subscriber whose plan includes
+// includedMinutes: no unlock fee, and that many minutes free on every ride.
+// The overdue penalty still applies.
+func (t *Tariff) Subscribed(includedMinutes int64) *Tariff {
+	if t == nil {
+		return nil
+	}
+	subscribed := *t
+	subscribed.UnlockFee = 0
+	subscribed.FreeMinutes = max(t.FreeMinutes, includedMinutes)
+	return &subscribed
+}
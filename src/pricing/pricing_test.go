@@ -0,0 +1,142 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	tariff := &Tariff{
+		Currency:       "EUR",
+		UnlockFee:      100,
+		PerMinute:      20,
+		DailyCap:       1500,
+		FreeMinutes:    10,
+		OverduePenalty: 5000,
+	}
+	start := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		tariff   *Tariff
+		duration time.Duration
+		overdue  bool
+		want     Fare
+	}{
+		{
+			name:     "instant return",
+			tariff:   tariff,
+			duration: 0,
+			want:     Fare{Currency: "EUR", Minutes: 0, Unlock: 100, Total: 100},
+		},
+		{
+			name:     "clock skew",
+			tariff:   tariff,
+			duration: -time.Minute,
+			want:     Fare{Currency: "EUR", Minutes: 0, Unlock: 100, Total: 100},
+		},
+		{
+			name:     "within the free minutes",
+			tariff:   tariff,
+			duration: 9*time.Minute + 30*time.Second,
+			want:     Fare{Currency: "EUR", Minutes: 10, Unlock: 100, Total: 100},
+		},
+		{
+			name:     "started minute after the free ones",
+			tariff:   tariff,
+			duration: 10*time.Minute + time.Second,
+			want:     Fare{Currency: "EUR", Minutes: 11, Unlock: 100, Time: 20, Total: 120},
+		},
+		{
+			name:     "half an hour",
+			tariff:   tariff,
+			duration: 30 * time.Minute,
+			want:     Fare{Currency: "EUR", Minutes: 30, Unlock: 100, Time: 400, Total: 500},
+		},
+		{
+			name:     "just under the daily cap",
+			tariff:   tariff,
+			duration: 84 * time.Minute,
+			want:     Fare{Currency: "EUR", Minutes: 84, Unlock: 100, Time: 1480, Total: 1580},
+		},
+		{
+			name:     "capped day",
+			tariff:   tariff,
+			duration: 5 * time.Hour,
+			want:     Fare{Currency: "EUR", Minutes: 300, Unlock: 100, Time: 1500, Total: 1600},
+		},
+		{
+			name:     "exactly one day",
+			tariff:   tariff,
+			duration: 24 * time.Hour,
+			want:     Fare{Currency: "EUR", Minutes: 1440, Unlock: 100, Time: 1500, Total: 1600},
+		},
+		{
+			name:     "one minute into the second day",
+			tariff:   tariff,
+			duration: 24*time.Hour + time.Minute,
+			want:     Fare{Currency: "EUR", Minutes: 1441, Unlock: 100, Time: 1520, Total: 1620},
+		},
+		{
+			name:     "overdue after a day and a half",
+			tariff:   tariff,
+			duration: 36 * time.Hour,
+			overdue:  true,
+			want:     Fare{Currency: "EUR", Minutes: 2160, Unlock: 100, Time: 3000, Penalty: 5000, Total: 8100},
+		},
+		{
+			name:     "free minutes spanning days",
+			tariff:   &Tariff{PerMinute: 1, FreeMinutes: 1500},
+			duration: 25 * time.Hour,
+			want:     Fare{Currency: "EUR", Minutes: 1500},
+		},
+		{
+			name:     "no daily cap",
+			tariff:   &Tariff{Currency: "GBP", PerMinute: 10},
+			duration: 48 * time.Hour,
+			want:     Fare{Currency: "GBP", Minutes: 2880, Time: 28800, Total: 28800},
+		},
+		{
+			name:     "overdue without penalty",
+			tariff:   &Tariff{UnlockFee: 50},
+			duration: 30 * time.Hour,
+			overdue:  true,
+			want:     Fare{Currency: "EUR", Minutes: 1800, Unlock: 50, Total: 50},
+		},
+		{
+			name:     "no tariff",
+			tariff:   nil,
+			duration: 3 * time.Hour,
+			overdue:  true,
+			want:     Fare{Currency: "EUR"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.tariff.Calculate(start, start.Add(tt.duration), tt.overdue)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	start := time.Date(2024, 8, 21, 7, 0, 0, 0, time.UTC)
+	tariff := &Tariff{Currency: "EUR", UnlockFee: 100, PerMinute: 15, DailyCap: 1500, OverduePenalty: 5000}
+	subscribed := tariff.Subscribed(45)
+
+	// Rides within the included minutes are free
+	assert.Equal(t, Fare{Currency: "EUR", Minutes: 45}, subscribed.Calculate(start, start.Add(45*time.Minute), false))
+
+	// Longer ones are charged the minutes beyond, without unlock fee
+	assert.Equal(t, Fare{Currency: "EUR", Minutes: 60, Time: 225, Total: 225}, subscribed.Calculate(start, start.Add(time.Hour), false))
+
+	// The penalty is not waived
+	assert.Equal(t, int64(5000), subscribed.Calculate(start, start.Add(25*time.Hour), true).Penalty)
+
+	// The tariff itself is left unchanged
+	assert.Equal(t, int64(100), tariff.UnlockFee)
+	assert.Nil(t, (*Tariff)(nil).Subscribed(45))
+}
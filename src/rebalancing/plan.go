@@ -0,0 +1,104 @@
+package rebalancing
+
+import (
+	"sort"
+)
+
+// Station is the state of a station a plan is computed from
+type Station struct {
+	ID     string
+	Docks  int      // usable dock slots
+	Parked int      // bikes docked, including the ones cooling down or reserved
+	Bikes  []string // bikes that can be moved, in the order they should be picked
+	Demand int      // bikes taken from the station over the demand window
+}
+
+// Move is a suggested relocation of bikes by a van crew
+type Move struct {
+	FromStationID string   `json:"from_station_id"`
+	ToStationID   string   `json:"to_station_id"`
+	BikeIDs       []string `json:"bike_ids"`
+}
+
+// target returns the number of bikes each station should hold: the fleet
+// is shared in proportion to the demand, or to the docks when there is no
+// demand history, and capped by the docks
+func target(stations []Station) []int {
+	var fleet, demand, docks int
+	for _, station := range stations {
+		fleet += station.Parked
+		demand += station.Demand
+		docks += station.Docks
+	}
+
+	targets := make([]int, len(stations))
+	for i, station := range stations {
+		switch {
+		case demand > 0:
+			targets[i] = fleet * station.Demand / demand
+		case docks > 0:
+			targets[i] = fleet * station.Docks / docks
+		}
+		if targets[i] > station.Docks {
+			targets[i] = station.Docks
+		}
+	}
+	return targets
+}
+
+// Plan suggests the moves bringing each station closer to its share of
+// the fleet. Bikes are taken from the stations holding the most bikes over
+// their share to the ones missing the most, never beyond their free docks.
+func Plan(stations []Station) []Move {
+	type balance struct {
+		station *Station
+		count   int
+	}
+
+	targets := target(stations)
+	var surpluses, deficits []balance
+	for i := range stations {
+		station := &stations[i]
+		if surplus := min(station.Parked-targets[i], len(station.Bikes)); surplus > 0 {
+			surpluses = append(surpluses, balance{station, surplus})
+		}
+		if deficit := min(targets[i]-station.Parked, station.Docks-station.Parked); deficit > 0 {
+			deficits = append(deficits, balance{station, deficit})
+		}
+	}
+
+	// Largest imbalances first, ties broken by station for a stable plan
+	byCount := func(balances []balance) func(i, j int) bool {
+		return func(i, j int) bool {
+			if balances[i].count != balances[j].count {
+				return balances[i].count > balances[j].count
+			}
+			return balances[i].station.ID < balances[j].station.ID
+		}
+	}
+	sort.Slice(surpluses, byCount(surpluses))
+	sort.Slice(deficits, byCount(deficits))
+
+	moves := []Move{}
+	picked := map[string]int{}
+	for i, j := 0, 0; i < len(surpluses) && j < len(deficits); {
+		from, to := &surpluses[i], &deficits[j]
+		count := min(from.count, to.count)
+
+		first := picked[from.station.ID]
+		moves = append(moves, Move{
+			FromStationID: from.station.ID,
+			ToStationID:   to.station.ID,
+			BikeIDs:       from.station.Bikes[first : first+count],
+		})
+		picked[from.station.ID] += count
+
+		if from.count -= count; from.count == 0 {
+			i++
+		}
+		if to.count -= count; to.count == 0 {
+			j++
+		}
+	}
+	return moves
+}
@@ -0,0 +1,50 @@
+package rebalancing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlan(t *testing.T) {
+	stations := []Station{
+		// Full, but rarely used
+		{ID: "a", Docks: 6, Parked: 6, Bikes: []string{"bike-1", "bike-2", "bike-3", "bike-4", "bike-5"}, Demand: 1},
+		// Drained by commuters
+		{ID: "b", Docks: 4, Parked: 0, Demand: 6},
+		// Busy, with a single free dock
+		{ID: "c", Docks: 3, Parked: 2, Bikes: []string{"bike-6", "bike-7"}, Demand: 3},
+	}
+
+	moves := Plan(stations)
+
+	// a should hold 8*1/10 = 0 bikes, b 4, c 2
+	assert.Equal(t, []Move{
+		{FromStationID: "a", ToStationID: "b", BikeIDs: []string{"bike-1", "bike-2", "bike-3", "bike-4"}},
+	}, moves)
+}
+
+func TestPlan_SplitsSurplus(t *testing.T) {
+	stations := []Station{
+		{ID: "a", Docks: 10, Parked: 6, Bikes: []string{"bike-1", "bike-2", "bike-3", "bike-4", "bike-5", "bike-6"}},
+		{ID: "b", Docks: 10, Parked: 0},
+		{ID: "c", Docks: 5, Parked: 0},
+	}
+
+	// Without demand history the fleet is shared by docks: a 2, b 2, c 1
+	moves := Plan(stations)
+
+	assert.Equal(t, []Move{
+		{FromStationID: "a", ToStationID: "b", BikeIDs: []string{"bike-1", "bike-2"}},
+		{FromStationID: "a", ToStationID: "c", BikeIDs: []string{"bike-3"}},
+	}, moves)
+}
+
+func TestPlan_Balanced(t *testing.T) {
+	stations := []Station{
+		{ID: "a", Docks: 4, Parked: 2, Bikes: []string{"bike-1", "bike-2"}, Demand: 5},
+		{ID: "b", Docks: 4, Parked: 2, Bikes: []string{"bike-3", "bike-4"}, Demand: 5},
+	}
+
+	assert.Empty(t, Plan(stations))
+}
@@ -0,0 +1,327 @@
+// Package reconcile finds the bikes and assignments left disagreeing by a
+// request failing between its statements, and repairs them.
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/audit"
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/logger"
+	"github.com/yourusername/bike-rental/src/subscriptions"
+)
+
+// Kinds of inconsistencies
+const (
+	KindBikeWithoutAssignment = "bike_without_assignment" // assigned bike without open assignment
+	KindAssignmentOnFreeBike  = "assignment_on_free_bike" // open assignment of a bike not assigned
+	KindUsageCountMismatch    = "usage_count_mismatch"    // usage count of a bike not matching its assignments
+	KindUserOverPlan          = "user_over_plan"          // user riding more bikes than their plan allows
+)
+
+// Config tells how the inconsistencies are handled
+type Config struct {
+	Repair bool          `toml:"repair"` // only report the inconsistencies when false
+	Settle time.Duration `toml:"settle"` // wait before checking again, e.g. "10s", the inconsistencies of requests in flight being left out
+}
+
+// Inconsistency is a bike, an assignment or a user breaking an invariant
+type Inconsistency struct {
+	Kind         string `json:"kind"`
+	BikeID       string `json:"bike_id,omitempty"`
+	UserID       string `json:"user_id,omitempty"`
+	AssignmentID uint   `json:"assignment_id,omitempty"`
+	Found        int    `json:"found,omitempty"`    // the usage count, or the open assignments of the user
+	Expected     int    `json:"expected,omitempty"` // the assignments of the bike, or the rentals the plan allows
+	Repaired     bool   `json:"repaired"`
+}
+
+func (i Inconsistency) String() string {
+	switch i.Kind {
+	case KindBikeWithoutAssignment:
+		return fmt.Sprintf("bike %s is assigned without open assignment", i.BikeID)
+	case KindAssignmentOnFreeBike:
+		return fmt.Sprintf("assignment %d of user %s is open but bike %s is not assigned", i.AssignmentID, i.UserID, i.BikeID)
+	case KindUsageCountMismatch:
+		return fmt.Sprintf("bike %s has a usage count of %d but %d assignments", i.BikeID, i.Found, i.Expected)
+	case KindUserOverPlan:
+		return fmt.Sprintf("user %s rides %d bikes but their plan allows %d", i.UserID, i.Found, i.Expected)
+	}
+	return i.Kind
+}
+
+// key identifies the entity an inconsistency is about, its counts changing
+// with the rides
+func (i Inconsistency) key() string {
+	return fmt.Sprintf("%s/%s/%s/%d", i.Kind, i.BikeID, i.UserID, i.AssignmentID)
+}
+
+// Run checks the bikes and the assignments, and repairs the inconsistencies
+// when config allows it. The API assigning and unassigning bikes leaves the
+// rows disagreeing between its statements, so only the inconsistencies still
+// found once config.Settle has passed are returned.
+func Run(ctx context.Context, db *sql.DB, clk clock.Clock, config *Config) ([]Inconsistency, error) {
+	found, err := Check(ctx, db, clk.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(found) > 0 && config.Settle > 0 {
+		select {
+		case <-clk.After(config.Settle):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		again, err := Check(ctx, db, clk.Now())
+		if err != nil {
+			return nil, err
+		}
+		found = settled(found, again)
+	}
+
+	if !config.Repair {
+		return found, nil
+	}
+	for i := range found {
+		repaired, err := Repair(ctx, db, found[i], clk.Now())
+		if err != nil {
+			logger.For("reconcile").Err(err).Ctx(ctx).Str("kind", found[i].Kind).Msg("Failed to repair inconsistency")
+			continue
+		}
+		found[i].Repaired = repaired
+	}
+	return found, nil
+}
+
+// settled returns the inconsistencies of again found before already
+func settled(before, again []Inconsistency) []Inconsistency {
+	seen := map[string]bool{}
+	for _, inconsistency := range before {
+		seen[inconsistency.key()] = true
+	}
+
+	var found []Inconsistency
+	for _, inconsistency := range again {
+		if seen[inconsistency.key()] {
+			found = append(found, inconsistency)
+		}
+	}
+	return found
+}
+
+// Check returns the inconsistencies between the bikes and the assignments
+// at now, grouped by kind
+func Check(ctx context.Context, db *sql.DB, now time.Time) ([]Inconsistency, error) {
+	var found []Inconsistency
+
+	// The bike was marked as assigned, but the assignment was never created
+	query := `SELECT b.id FROM bikes b
+	          WHERE b.is_assigned AND NOT EXISTS (SELECT 1 FROM assignments a WHERE a.bike_id = b.id AND a.unassigned_at IS NULL)
+	          ORDER BY b.id`
+	err := scan(ctx, db, query, func(rows *sql.Rows) error {
+		inconsistency := Inconsistency{Kind: KindBikeWithoutAssignment}
+		if err := rows.Scan(&inconsistency.BikeID); err != nil {
+			return err
+		}
+		found = append(found, inconsistency)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check assigned bikes: %w", err)
+	}
+
+	// The bike was returned, but the assignment was never closed
+	query = `SELECT a.id, a.user_id, a.bike_id FROM assignments a
+	         INNER JOIN bikes b ON b.id = a.bike_id
+	         WHERE a.unassigned_at IS NULL AND NOT b.is_assigned
+	         ORDER BY a.id`
+	err = scan(ctx, db, query, func(rows *sql.Rows) error {
+		inconsistency := Inconsistency{Kind: KindAssignmentOnFreeBike}
+		if err := rows.Scan(&inconsistency.AssignmentID, &inconsistency.UserID, &inconsistency.BikeID); err != nil {
+			return err
+		}
+		found = append(found, inconsistency)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check open assignments: %w", err)
+	}
+
+	query = `SELECT b.id, b.usage_count, COUNT(a.id) FROM bikes b
+	         LEFT JOIN assignments a ON a.bike_id = b.id
+	         GROUP BY b.id, b.usage_count
+	         HAVING b.usage_count <> COUNT(a.id)
+	         ORDER BY b.id`
+	err = scan(ctx, db, query, func(rows *sql.Rows) error {
+		inconsistency := Inconsistency{Kind: KindUsageCountMismatch}
+		if err := rows.Scan(&inconsistency.BikeID, &inconsistency.Found, &inconsistency.Expected); err != nil {
+			return err
+		}
+		found = append(found, inconsistency)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check usage counts: %w", err)
+	}
+
+	// Concurrent assignments to a user, some plans allowing several bikes
+	var riders []Inconsistency
+	query = `SELECT user_id, COUNT(*) FROM assignments
+	         WHERE unassigned_at IS NULL
+	         GROUP BY user_id
+	         HAVING COUNT(*) > 1
+	         ORDER BY user_id`
+	err = scan(ctx, db, query, func(rows *sql.Rows) error {
+		inconsistency := Inconsistency{Kind: KindUserOverPlan}
+		if err := rows.Scan(&inconsistency.UserID, &inconsistency.Found); err != nil {
+			return err
+		}
+		riders = append(riders, inconsistency)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check open assignments per user: %w", err)
+	}
+	for _, inconsistency := range riders {
+		plan, err := subscriptions.ActivePlan(ctx, db, inconsistency.UserID, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch subscription of user %s: %w", inconsistency.UserID, err)
+		}
+		if inconsistency.Expected = subscriptions.MaxRentals(plan); inconsistency.Found > inconsistency.Expected {
+			found = append(found, inconsistency)
+		}
+	}
+
+	return found, nil
+}
+
+// scan runs query, calling row on each of its rows
+func scan(ctx context.Context, db *sql.DB, query string, row func(*sql.Rows) error) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := row(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Repair fixes an inconsistency at now and records it in the audit log. It
+// returns false when the inconsistency is gone already, or when it cannot
+// be repaired: which of the bikes of a user over their plan to end is left
+// to the supervisors.
+func Repair(ctx context.Context, db *sql.DB, inconsistency Inconsistency, now time.Time) (bool, error) {
+	if inconsistency.Kind == KindUserOverPlan {
+		return false, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	event := audit.Event{
+		OccurredAt: now,
+		Actor:      audit.System("reconcile"),
+		Action:     audit.ActionBikeReconciled,
+		EntityType: audit.EntityBike,
+		EntityID:   inconsistency.BikeID,
+		Reason:     inconsistency.String(),
+	}
+
+	switch inconsistency.Kind {
+	case KindBikeWithoutAssignment:
+		// The ride never started, the bike is available again
+		var stationID sql.NullString
+		var slot sql.NullInt64
+		query := `UPDATE bikes SET is_assigned = false
+		          WHERE id = $1 AND is_assigned AND NOT EXISTS (SELECT 1 FROM assignments WHERE bike_id = $1 AND unassigned_at IS NULL)
+		          RETURNING station_id, (SELECT slot FROM docks WHERE bike_id = $1)`
+		err := tx.QueryRowContext(ctx, query, inconsistency.BikeID).Scan(&stationID, &slot)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		event.Before = map[string]interface{}{"is_assigned": true, "station_id": stationID.String, "dock_slot": nullSlot(slot)}
+
+		// Its dock was released on assignment: it is locked in the first free
+		// one of its station again, if any
+		if stationID.Valid && !slot.Valid {
+			query = `UPDATE docks SET state = 'occupied', bike_id = $2, updated_at = $3
+			         WHERE id = (SELECT id FROM docks WHERE station_id = $1 AND state = 'free' ORDER BY slot LIMIT 1 FOR UPDATE SKIP LOCKED)
+			         RETURNING slot`
+			err := tx.QueryRowContext(ctx, query, stationID.String, inconsistency.BikeID, now).Scan(&slot)
+			if err != nil && err != sql.ErrNoRows {
+				return false, err
+			}
+		}
+		event.After = map[string]interface{}{"is_assigned": false, "station_id": stationID.String, "dock_slot": nullSlot(slot)}
+
+	case KindAssignmentOnFreeBike:
+		// The ride ended when the bike was returned. It is not charged, the
+		// fare being unknown to the job.
+		var endedAt time.Time
+		var endStation sql.NullString
+		query := `UPDATE assignments AS a SET unassigned_at = COALESCE(b.last_unassigned, $2), end_station_id = b.station_id
+		          FROM bikes b
+		          WHERE a.id = $1 AND a.unassigned_at IS NULL AND b.id = a.bike_id AND NOT b.is_assigned
+		          RETURNING a.unassigned_at, a.end_station_id`
+		err := tx.QueryRowContext(ctx, query, inconsistency.AssignmentID, now).Scan(&endedAt, &endStation)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		event.Before = map[string]interface{}{"assignment_id": inconsistency.AssignmentID, "user_id": inconsistency.UserID, "unassigned_at": nil}
+		event.After = map[string]interface{}{"assignment_id": inconsistency.AssignmentID, "unassigned_at": endedAt, "end_station_id": endStation.String, "fare": nil}
+
+	case KindUsageCountMismatch:
+		var usageCount int
+		query := `UPDATE bikes SET usage_count = (SELECT COUNT(*) FROM assignments WHERE bike_id = $1)
+		          WHERE id = $1 AND usage_count <> (SELECT COUNT(*) FROM assignments WHERE bike_id = $1)
+		          RETURNING usage_count`
+		err := tx.QueryRowContext(ctx, query, inconsistency.BikeID).Scan(&usageCount)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		event.Before = map[string]interface{}{"usage_count": inconsistency.Found}
+		event.After = map[string]interface{}{"usage_count": usageCount}
+
+	default:
+		return false, nil
+	}
+
+	// Recorded with the repair, for the supervisors to follow up on
+	if err := audit.Record(ctx, tx, event); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// nullSlot returns the dock slot of a bike for the audit log, nil when the
+// bike is not docked
+func nullSlot(slot sql.NullInt64) interface{} {
+	if !slot.Valid {
+		return nil
+	}
+	return slot.Int64
+}
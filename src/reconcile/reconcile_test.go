@@ -0,0 +1,233 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/bike-rental/src/clock"
+)
+
+var fixedTime = time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+
+// expectCheck expects the queries of Check, returning found for each kind
+// and no plan to the users riding several bikes
+func expectCheck(mock sqlmock.Sqlmock, found []Inconsistency) {
+	bikes := sqlmock.NewRows([]string{"id"})
+	assignments := sqlmock.NewRows([]string{"id", "user_id", "bike_id"})
+	usageCounts := sqlmock.NewRows([]string{"id", "usage_count", "count"})
+	riders := sqlmock.NewRows([]string{"user_id", "count"})
+	var users []string
+	for _, inconsistency := range found {
+		switch inconsistency.Kind {
+		case KindBikeWithoutAssignment:
+			bikes.AddRow(inconsistency.BikeID)
+		case KindAssignmentOnFreeBike:
+			assignments.AddRow(inconsistency.AssignmentID, inconsistency.UserID, inconsistency.BikeID)
+		case KindUsageCountMismatch:
+			usageCounts.AddRow(inconsistency.BikeID, inconsistency.Found, inconsistency.Expected)
+		case KindUserOverPlan:
+			riders.AddRow(inconsistency.UserID, inconsistency.Found)
+			users = append(users, inconsistency.UserID)
+		}
+	}
+
+	mock.ExpectQuery(`SELECT b.id FROM bikes b WHERE b.is_assigned AND NOT EXISTS`).WillReturnRows(bikes)
+	mock.ExpectQuery(`SELECT a.id, a.user_id, a.bike_id FROM assignments a INNER JOIN bikes b ON b.id = a.bike_id WHERE a.unassigned_at IS NULL AND NOT b.is_assigned`).WillReturnRows(assignments)
+	mock.ExpectQuery(`SELECT b.id, b.usage_count, COUNT\(a.id\) FROM bikes b LEFT JOIN assignments a (.+) HAVING b.usage_count <> COUNT\(a.id\)`).WillReturnRows(usageCounts)
+	mock.ExpectQuery(`SELECT user_id, COUNT\(\*\) FROM assignments WHERE unassigned_at IS NULL GROUP BY user_id HAVING COUNT\(\*\) > 1`).WillReturnRows(riders)
+	for _, user := range users {
+		mock.ExpectQuery(`FROM subscriptions s`).
+			WithArgs(user, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "period", "price_cents", "included_minutes", "max_rentals"}))
+	}
+}
+
+func TestCheck(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT b.id FROM bikes b WHERE b.is_assigned AND NOT EXISTS`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("bike-1"))
+	mock.ExpectQuery(`SELECT a.id, a.user_id, a.bike_id FROM assignments a`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bike_id"}).AddRow(12, "user-1", "bike-2"))
+	mock.ExpectQuery(`SELECT b.id, b.usage_count, COUNT\(a.id\) FROM bikes b`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "usage_count", "count"}).AddRow("bike-3", 4, 3))
+	mock.ExpectQuery(`SELECT user_id, COUNT\(\*\) FROM assignments`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "count"}).AddRow("user-2", 2).AddRow("user-3", 2))
+
+	// The first user has no plan, the second one may ride two bikes
+	mock.ExpectQuery(`FROM subscriptions s`).
+		WithArgs("user-2", fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "period", "price_cents", "included_minutes", "max_rentals"}))
+	mock.ExpectQuery(`FROM subscriptions s`).
+		WithArgs("user-3", fixedTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "period", "price_cents", "included_minutes", "max_rentals"}).
+			AddRow(2, "Family", "month", 2500, 0, 2))
+
+	found, err := Check(context.Background(), db, fixedTime)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Inconsistency{
+		{Kind: KindBikeWithoutAssignment, BikeID: "bike-1"},
+		{Kind: KindAssignmentOnFreeBike, BikeID: "bike-2", UserID: "user-1", AssignmentID: 12},
+		{Kind: KindUsageCountMismatch, BikeID: "bike-3", Found: 4, Expected: 3},
+		{Kind: KindUserOverPlan, UserID: "user-2", Found: 2, Expected: 1},
+	}, found)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name          string
+		inconsistency Inconsistency
+		expect        func(mock sqlmock.Sqlmock)
+		repaired      bool
+		after         string // the state recorded in the audit log, if checked
+	}{
+		{
+			name:          "bike without assignment",
+			inconsistency: Inconsistency{Kind: KindBikeWithoutAssignment, BikeID: "bike-1"},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE bikes SET is_assigned = false WHERE id = \$1 AND is_assigned AND NOT EXISTS (.+) RETURNING station_id, \(SELECT slot FROM docks WHERE bike_id = \$1\)`).
+					WithArgs("bike-1").
+					WillReturnRows(sqlmock.NewRows([]string{"station_id", "slot"}).AddRow("station-1", nil))
+
+				// Its dock was released on assignment
+				mock.ExpectQuery(`UPDATE docks SET state = 'occupied', bike_id = \$2, updated_at = \$3 WHERE id = \(SELECT id FROM docks WHERE station_id = \$1 AND state = 'free' (.+)\) RETURNING slot`).
+					WithArgs("station-1", "bike-1", fixedTime).
+					WillReturnRows(sqlmock.NewRows([]string{"slot"}).AddRow(3))
+			},
+			repaired: true,
+			after:    `{"dock_slot":3,"is_assigned":false,"station_id":"station-1"}`,
+		},
+		{
+			name:          "bike without assignment still docked",
+			inconsistency: Inconsistency{Kind: KindBikeWithoutAssignment, BikeID: "bike-1"},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE bikes SET is_assigned = false`).
+					WithArgs("bike-1").
+					WillReturnRows(sqlmock.NewRows([]string{"station_id", "slot"}).AddRow("station-1", 2))
+			},
+			repaired: true,
+			after:    `{"dock_slot":2,"is_assigned":false,"station_id":"station-1"}`,
+		},
+		{
+			name:          "bike assigned since",
+			inconsistency: Inconsistency{Kind: KindBikeWithoutAssignment, BikeID: "bike-1"},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE bikes SET is_assigned = false`).
+					WithArgs("bike-1").
+					WillReturnRows(sqlmock.NewRows([]string{"station_id", "slot"}))
+			},
+		},
+		{
+			name:          "assignment on free bike",
+			inconsistency: Inconsistency{Kind: KindAssignmentOnFreeBike, BikeID: "bike-2", UserID: "user-1", AssignmentID: 12},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE assignments AS a SET unassigned_at = COALESCE\(b.last_unassigned, \$2\), end_station_id = b.station_id FROM bikes b (.+) RETURNING a.unassigned_at, a.end_station_id`).
+					WithArgs(12, fixedTime).
+					WillReturnRows(sqlmock.NewRows([]string{"unassigned_at", "end_station_id"}).AddRow(fixedTime.Add(-time.Hour), "station-1"))
+			},
+			repaired: true,
+		},
+		{
+			name:          "usage count",
+			inconsistency: Inconsistency{Kind: KindUsageCountMismatch, BikeID: "bike-3", Found: 4, Expected: 3},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE bikes SET usage_count = \(SELECT COUNT\(\*\) FROM assignments WHERE bike_id = \$1\) (.+) RETURNING usage_count`).
+					WithArgs("bike-3").
+					WillReturnRows(sqlmock.NewRows([]string{"usage_count"}).AddRow(3))
+			},
+			repaired: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.expect(mock)
+			if tt.repaired {
+				var after interface{} = sqlmock.AnyArg()
+				if tt.after != "" {
+					after = tt.after
+				}
+				mock.ExpectExec(`INSERT INTO audit_events`).
+					WithArgs("system", "reconcile", "bike.reconciled", "bike", tt.inconsistency.BikeID, sqlmock.AnyArg(), after, tt.inconsistency.String(), sqlmock.AnyArg(), fixedTime).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			repaired, err := Repair(context.Background(), db, tt.inconsistency, fixedTime)
+			require.NoError(t, err)
+			assert.Equal(t, tt.repaired, repaired)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRun_Settle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The first bike was being assigned, the second one is stuck
+	expectCheck(mock, []Inconsistency{
+		{Kind: KindBikeWithoutAssignment, BikeID: "bike-1"},
+		{Kind: KindBikeWithoutAssignment, BikeID: "bike-2"},
+		{Kind: KindUsageCountMismatch, BikeID: "bike-1", Found: 5, Expected: 4},
+	})
+	expectCheck(mock, []Inconsistency{
+		{Kind: KindBikeWithoutAssignment, BikeID: "bike-2"},
+		{Kind: KindUserOverPlan, UserID: "user-1", Found: 2},
+	})
+
+	// The second check is made once the delay has passed
+	clk := clock.NewFake(fixedTime)
+	config := &Config{Settle: 10 * time.Second}
+	found, err := Run(context.Background(), db, clk, config)
+	require.NoError(t, err)
+	assert.Equal(t, fixedTime.Add(10*time.Second), clk.Now())
+
+	assert.Equal(t, []Inconsistency{{Kind: KindBikeWithoutAssignment, BikeID: "bike-2"}}, found)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRun_Repair(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	expectCheck(mock, []Inconsistency{
+		{Kind: KindBikeWithoutAssignment, BikeID: "bike-1"},
+		{Kind: KindUserOverPlan, UserID: "user-1", Found: 2},
+	})
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE bikes SET is_assigned = false`).
+		WithArgs("bike-1").
+		WillReturnRows(sqlmock.NewRows([]string{"station_id", "slot"}).AddRow(nil, nil))
+	mock.ExpectExec(`INSERT INTO audit_events`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// The user over their plan is left to the supervisors
+	config := &Config{Repair: true}
+	found, err := Run(context.Background(), db, clock.NewFake(fixedTime), config)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Inconsistency{
+		{Kind: KindBikeWithoutAssignment, BikeID: "bike-1", Repaired: true},
+		{Kind: KindUserOverPlan, UserID: "user-1", Found: 2, Expected: 1},
+	}, found)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,119 @@
+package simulation
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Report is the outcome of a simulation
+type Report struct {
+	Config Config
+
+	Commuters int             // arrived at a station
+	Served    int             // handed a bike
+	Rejected  int             // gave up waiting
+	Waiting   int             // still waiting at the end
+	Waits     []time.Duration // of the commuters served, in order of service
+
+	Rides    int // ended, the others going on at the end
+	Rerouted int // docked at another station, the destination being full
+	Overdue  int // became overdue
+
+	Usage []int // usage count of each bike at the end
+}
+
+// RejectionRate is the share of the commuters who gave up waiting
+func (r *Report) RejectionRate() float64 {
+	if r.Commuters == 0 {
+		return 0
+	}
+	return float64(r.Rejected) / float64(r.Commuters)
+}
+
+// WaitPercentile returns the wait under which p percent of the commuters
+// served were handed a bike
+func (r *Report) WaitPercentile(p float64) time.Duration {
+	if len(r.Waits) == 0 {
+		return 0
+	}
+	waits := append([]time.Duration(nil), r.Waits...)
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+
+	// Nearest rank
+	rank := int(math.Ceil(p / 100 * float64(len(waits))))
+	if rank < 1 {
+		rank = 1
+	}
+	return waits[rank-1]
+}
+
+// MeanWait returns the average wait of the commuters served
+func (r *Report) MeanWait() time.Duration {
+	if len(r.Waits) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, wait := range r.Waits {
+		total += wait
+	}
+	return total / time.Duration(len(r.Waits))
+}
+
+// UsageSpread summarizes the usage counts of the bikes
+type UsageSpread struct {
+	Min    int
+	Max    int
+	Mean   float64
+	StdDev float64
+}
+
+// UsageSpread returns how evenly the rides were spread across the bikes
+func (r *Report) UsageSpread() UsageSpread {
+	if len(r.Usage) == 0 {
+		return UsageSpread{}
+	}
+
+	spread := UsageSpread{Min: r.Usage[0], Max: r.Usage[0]}
+	total := 0
+	for _, usage := range r.Usage {
+		spread.Min = min(spread.Min, usage)
+		spread.Max = max(spread.Max, usage)
+		total += usage
+	}
+	spread.Mean = float64(total) / float64(len(r.Usage))
+
+	variance := 0.0
+	for _, usage := range r.Usage {
+		variance += math.Pow(float64(usage)-spread.Mean, 2)
+	}
+	spread.StdDev = math.Sqrt(variance / float64(len(r.Usage)))
+	return spread
+}
+
+// Print writes the report as a table
+func (r *Report) Print(w io.Writer) {
+	config := r.Config
+	spread := r.UsageSpread()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Fleet\t%d bikes, %d stations of %d docks\n", config.Bikes, config.Stations, config.Docks)
+	fmt.Fprintf(tw, "Simulated\t%s from %s\n", config.Duration, config.Start.Format(time.RFC3339))
+	fmt.Fprintf(tw, "Commuters\t%d, %s arrivals at %.1f/h, rides of %s on average\n", r.Commuters, config.Arrivals, config.Rate, config.RideMean)
+	fmt.Fprintf(tw, "Rule\t%s, cooldown %s, patience %s\n", config.Rule, config.Cooldown, config.Patience)
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "Served\t%d\n", r.Served)
+	fmt.Fprintf(tw, "Rejected\t%d (%.2f%%)\n", r.Rejected, 100*r.RejectionRate())
+	fmt.Fprintf(tw, "Still waiting\t%d\n", r.Waiting)
+	fmt.Fprintf(tw, "Wait\tmean %s, p50 %s, p90 %s, p99 %s, max %s\n",
+		r.MeanWait().Round(time.Second), r.WaitPercentile(50).Round(time.Second), r.WaitPercentile(90).Round(time.Second),
+		r.WaitPercentile(99).Round(time.Second), r.WaitPercentile(100).Round(time.Second))
+	fmt.Fprintf(tw, "Rides ended\t%d, %d going on\n", r.Rides, r.Served-r.Rides)
+	fmt.Fprintf(tw, "Rerouted returns\t%d\n", r.Rerouted)
+	fmt.Fprintf(tw, "Overdue\t%d\n", r.Overdue)
+	fmt.Fprintf(tw, "Usage per bike\tmin %d, max %d, mean %.1f, stddev %.2f\n", spread.Min, spread.Max, spread.Mean, spread.StdDev)
+	tw.Flush()
+}
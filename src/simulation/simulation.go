@@ -0,0 +1,393 @@
+// Package simulation replays the assignment rules on a synthetic fleet:
+// commuters arrive at stations, are handed the least used bike ready there
+// and dock it at another station, where it cools down before it can be
+// handed over again. Time is read from a fake clock moved from event to
+// event, so weeks are simulated in milliseconds.
+package simulation
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/clock"
+	"github.com/yourusername/bike-rental/src/cronjobs"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/handover"
+)
+
+// Arrival distributions of the commuters
+const (
+	ArrivalsPoisson = "poisson" // independent arrivals at a constant rate
+	ArrivalsUniform = "uniform" // one commuter every 1/rate hours
+	ArrivalsCommute = "commute" // poisson, with rush hours around 8:00 and 18:00
+)
+
+// Rules choosing the bike handed over among the ones ready at a station
+const (
+	RuleLeastUsed = "least-used" // handover.LeastUsed, the rule of the API
+	RuleRandom    = "random"     // baseline to compare it with
+)
+
+// commuteProfile weighs the arrival rate by hour of the day. Its mean is 1,
+// so that the rate stays the daily average.
+var commuteProfile = [24]float64{
+	0.1, 0.05, 0.05, 0.05, 0.1, 0.3, 0.8, 2.0, 2.9, 2.0, 0.9, 0.8,
+	1.0, 0.9, 0.8, 0.9, 1.5, 2.45, 2.7, 1.8, 0.9, 0.5, 0.3, 0.2,
+}
+
+// Config describes the fleet and the commuters of a simulation
+type Config struct {
+	Stations int
+	Docks    int // per station
+	Bikes    int // spread evenly over the stations at the start
+
+	Start    time.Time
+	Duration time.Duration
+
+	Arrivals    string        // ArrivalsPoisson, ArrivalsUniform or ArrivalsCommute
+	Rate        float64       // commuters per hour over the whole network, on average
+	RideMean    time.Duration // mean duration of a ride
+	RideSigma   float64       // of the log-normal ride durations, 0 for rides all lasting RideMean
+	OverdueRate float64       // share of the bikes kept longer than OverdueAfter
+	Patience    time.Duration // how long a commuter waits for a bike before giving up
+
+	Cooldown time.Duration
+	Rule     string // RuleLeastUsed or RuleRandom
+	Seed     int64
+}
+
+// DefaultConfig returns a week of a small network, with the cooldown of the
+// API
+func DefaultConfig() Config {
+	return Config{
+		Stations:    10,
+		Docks:       20,
+		Bikes:       100,
+		Start:       time.Date(2024, 8, 19, 0, 0, 0, 0, time.UTC), // a Monday
+		Duration:    7 * 24 * time.Hour,
+		Arrivals:    ArrivalsCommute,
+		Rate:        60,
+		RideMean:    20 * time.Minute,
+		RideSigma:   0.5,
+		OverdueRate: 0.002,
+		Patience:    10 * time.Minute,
+		Cooldown:    handover.Cooldown,
+		Rule:        RuleLeastUsed,
+		Seed:        1,
+	}
+}
+
+// Validate checks the config describes a fleet that fits in its docks
+func (config *Config) Validate() error {
+	switch {
+	case config.Stations < 1:
+		return errors.New("stations must be at least 1")
+	case config.Docks < 1:
+		return errors.New("docks must be at least 1")
+	case config.Bikes < 0 || config.Bikes > config.Stations*config.Docks:
+		return fmt.Errorf("bikes must be between 0 and the %d docks", config.Stations*config.Docks)
+	case config.Duration <= 0:
+		return errors.New("duration must be positive")
+	case config.Arrivals != ArrivalsPoisson && config.Arrivals != ArrivalsUniform && config.Arrivals != ArrivalsCommute:
+		return fmt.Errorf("arrivals must be %s, %s or %s", ArrivalsPoisson, ArrivalsUniform, ArrivalsCommute)
+	case config.Rate <= 0:
+		return errors.New("rate must be positive")
+	case config.RideMean <= 0:
+		return errors.New("ride mean must be positive")
+	case config.RideSigma < 0:
+		return errors.New("ride sigma must not be negative")
+	case config.OverdueRate < 0 || config.OverdueRate > 1:
+		return errors.New("overdue rate must be between 0 and 1")
+	case config.Patience < 0:
+		return errors.New("patience must not be negative")
+	case config.Cooldown < 0:
+		return errors.New("cooldown must not be negative")
+	case config.Rule != RuleLeastUsed && config.Rule != RuleRandom:
+		return fmt.Errorf("rule must be %s or %s", RuleLeastUsed, RuleRandom)
+	}
+	return nil
+}
+
+// bike is a bike of the fleet, its AvailableFrom telling when it can be
+// handed over once docked
+type bike struct {
+	models.Bike
+}
+
+type station struct {
+	docked []*bike
+	queue  []*commuter // waiting for a bike, first come first served
+}
+
+type commuter struct {
+	arrivedAt   time.Time
+	origin      int
+	destination int
+	served      bool
+	gone        bool
+}
+
+type eventKind int
+
+const (
+	arrival   eventKind = iota
+	bikeReady           // a bike docked at the station is cooled down
+	rideEnd             // the bike is docked at the destination
+	giveUp              // the commuter stops waiting
+)
+
+type event struct {
+	at       time.Time
+	seq      int // events at the same time are handled in scheduling order
+	kind     eventKind
+	station  int
+	bike     *bike
+	commuter *commuter
+}
+
+// events is a priority queue of events, the earliest first
+type events []*event
+
+func (q events) Len() int { return len(q) }
+func (q events) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+func (q events) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *events) Push(x interface{}) { *q = append(*q, x.(*event)) }
+func (q *events) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+type simulation struct {
+	config   Config
+	clock    *clock.Fake
+	end      time.Time
+	rng      *rand.Rand
+	stations []*station
+	bikes    []*bike
+	events   events
+	seq      int
+	report   *Report
+}
+
+// Run simulates config and reports how the commuters were served
+func Run(config Config) (*Report, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &simulation{
+		config: config,
+		clock:  clock.NewFake(config.Start),
+		end:    config.Start.Add(config.Duration),
+		rng:    rand.New(rand.NewSource(config.Seed)),
+		report: &Report{Config: config},
+	}
+	for i := 0; i < config.Stations; i++ {
+		s.stations = append(s.stations, &station{})
+	}
+	for i := 0; i < config.Bikes; i++ {
+		b := &bike{models.Bike{ID: fmt.Sprintf("bike-%06d", i), AvailableFrom: config.Start}}
+		s.bikes = append(s.bikes, b)
+		s.stations[i%config.Stations].docked = append(s.stations[i%config.Stations].docked, b)
+	}
+
+	s.schedule(&event{at: s.nextArrival(config.Start, true), kind: arrival})
+	for s.events.Len() > 0 {
+		e := heap.Pop(&s.events).(*event)
+		if e.at.After(s.end) {
+			break
+		}
+		s.clock.Set(e.at)
+
+		switch e.kind {
+		case arrival:
+			s.arrive()
+			s.schedule(&event{at: s.nextArrival(e.at, false), kind: arrival})
+		case bikeReady:
+			s.serve(e.station)
+		case rideEnd:
+			s.dock(e.bike, e.station)
+		case giveUp:
+			s.leave(e.commuter)
+		}
+	}
+
+	for _, st := range s.stations {
+		s.report.Waiting += len(st.queue)
+	}
+	for _, b := range s.bikes {
+		s.report.Usage = append(s.report.Usage, b.UsageCount)
+	}
+	return s.report, nil
+}
+
+func (s *simulation) schedule(e *event) {
+	s.seq++
+	e.seq = s.seq
+	heap.Push(&s.events, e)
+}
+
+// nextArrival returns when the commuter after the one arrived at from comes
+func (s *simulation) nextArrival(from time.Time, first bool) time.Time {
+	hours := func(h float64) time.Duration { return time.Duration(h * float64(time.Hour)) }
+
+	switch s.config.Arrivals {
+	case ArrivalsUniform:
+		if first {
+			return from
+		}
+		return from.Add(hours(1 / s.config.Rate))
+	case ArrivalsCommute:
+		// Thinning of a poisson process at the peak rate
+		peak := 0.0
+		for _, weight := range commuteProfile {
+			peak = math.Max(peak, weight)
+		}
+		at := from
+		for {
+			at = at.Add(hours(s.rng.ExpFloat64() / (s.config.Rate * peak)))
+			if at.After(s.end) || s.rng.Float64() < commuteProfile[at.UTC().Hour()]/peak {
+				return at
+			}
+		}
+	default:
+		return from.Add(hours(s.rng.ExpFloat64() / s.config.Rate))
+	}
+}
+
+// arrive queues a commuter at a random station, bound for another one when
+// there are several
+func (s *simulation) arrive() {
+	c := &commuter{arrivedAt: s.clock.Now(), origin: s.rng.Intn(len(s.stations))}
+	c.destination = c.origin
+	if len(s.stations) > 1 {
+		c.destination = (c.origin + 1 + s.rng.Intn(len(s.stations)-1)) % len(s.stations)
+	}
+	s.report.Commuters++
+
+	st := s.stations[c.origin]
+	st.queue = append(st.queue, c)
+	s.schedule(&event{at: c.arrivedAt.Add(s.config.Patience), kind: giveUp, commuter: c})
+	s.serve(c.origin)
+}
+
+// serve hands the bikes ready at a station over to the commuters waiting
+// there, in their order of arrival
+func (s *simulation) serve(index int) {
+	st := s.stations[index]
+	for len(st.queue) > 0 {
+		b := s.pick(st)
+		if b == nil {
+			return
+		}
+		c := st.queue[0]
+		st.queue = st.queue[1:]
+		s.assign(st, b, c)
+	}
+}
+
+// pick returns the bike to hand over at a station, nil when none is ready
+func (s *simulation) pick(st *station) *bike {
+	now := s.clock.Now()
+	if s.config.Rule == RuleRandom {
+		var ready []*bike
+		for _, b := range st.docked {
+			if handover.Ready(b.Bike, now) {
+				ready = append(ready, b)
+			}
+		}
+		if len(ready) == 0 {
+			return nil
+		}
+		return ready[s.rng.Intn(len(ready))]
+	}
+
+	bikes := make([]models.Bike, len(st.docked))
+	for i, b := range st.docked {
+		bikes[i] = b.Bike
+	}
+	if i := handover.LeastUsed(bikes, now); i >= 0 {
+		return st.docked[i]
+	}
+	return nil
+}
+
+func (s *simulation) assign(st *station, b *bike, c *commuter) {
+	now := s.clock.Now()
+	for i, docked := range st.docked {
+		if docked == b {
+			st.docked = append(st.docked[:i], st.docked[i+1:]...)
+			break
+		}
+	}
+	b.UsageCount++
+	c.served = true
+	s.report.Served++
+	s.report.Waits = append(s.report.Waits, now.Sub(c.arrivedAt))
+
+	duration := s.rideDuration()
+	if duration > cronjobs.OverdueAfter && now.Add(cronjobs.OverdueAfter).Before(s.end) {
+		s.report.Overdue++
+	}
+	s.schedule(&event{at: now.Add(duration), kind: rideEnd, station: c.destination, bike: b})
+}
+
+// rideDuration draws the duration of a ride: log-normal around RideMean,
+// or beyond OverdueAfter for the bikes not returned in time
+func (s *simulation) rideDuration() time.Duration {
+	if s.rng.Float64() < s.config.OverdueRate {
+		return cronjobs.OverdueAfter + time.Duration(1+s.rng.Int63n(int64(cronjobs.OverdueAfter)))
+	}
+	sigma := s.config.RideSigma
+	factor := math.Exp(sigma*s.rng.NormFloat64() - sigma*sigma/2)
+	return time.Duration(float64(s.config.RideMean) * factor)
+}
+
+// dock returns a bike at the destination of its ride, or at the station
+// with the most free docks when it is full
+func (s *simulation) dock(b *bike, index int) {
+	now := s.clock.Now()
+	s.report.Rides++
+
+	if len(s.stations[index].docked) >= s.config.Docks {
+		s.report.Rerouted++
+		for i, st := range s.stations {
+			if len(st.docked) < len(s.stations[index].docked) {
+				index = i
+			}
+		}
+	}
+
+	st := s.stations[index]
+	b.AvailableFrom = now.Add(s.config.Cooldown)
+	st.docked = append(st.docked, b)
+	s.schedule(&event{at: b.AvailableFrom, kind: bikeReady, station: index})
+}
+
+// leave drops a commuter who waited too long from the queue
+func (s *simulation) leave(c *commuter) {
+	if c.served || c.gone {
+		return
+	}
+	c.gone = true
+	s.report.Rejected++
+
+	st := s.stations[c.origin]
+	for i, waiting := range st.queue {
+		if waiting == c {
+			st.queue = append(st.queue[:i], st.queue[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package simulation
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/bike-rental/src/handover"
+)
+
+// singleStation is a station with one bike, a commuter every 5 minutes and
+// rides of a minute
+func singleStation() Config {
+	config := DefaultConfig()
+	config.Stations = 1
+	config.Docks = 2
+	config.Bikes = 1
+	config.Duration = 20 * time.Minute
+	config.Arrivals = ArrivalsUniform
+	config.Rate = 12
+	config.RideMean = time.Minute
+	config.RideSigma = 0
+	config.OverdueRate = 0
+	return config
+}
+
+func TestCommuteProfile(t *testing.T) {
+	total := 0.0
+	for _, weight := range commuteProfile {
+		total += weight
+	}
+	assert.InDelta(t, 1, total/24, 1e-9)
+}
+
+func TestRun_Cooldown(t *testing.T) {
+	report, err := Run(singleStation())
+	require.NoError(t, err)
+
+	// Commuters at 0, 5, 10, 15 and 20 minutes. The bike is back a minute
+	// after each ride, and ready 5 minutes later.
+	assert.Equal(t, 5, report.Commuters)
+	assert.Equal(t, 4, report.Served)
+	assert.Equal(t, 1, report.Waiting)
+	assert.Equal(t, []time.Duration{0, time.Minute, 2 * time.Minute, 3 * time.Minute}, report.Waits)
+	assert.Equal(t, []int{4}, report.Usage)
+	assert.Equal(t, 4, report.Rides)
+
+	// Ready a minute after each ride without cooldown
+	config := singleStation()
+	config.Cooldown = 0
+	report, err = Run(config)
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Served)
+	assert.Equal(t, time.Duration(0), report.WaitPercentile(100))
+}
+
+func TestRun_Patience(t *testing.T) {
+	config := singleStation()
+	config.Patience = 90 * time.Second
+	report, err := Run(config)
+	require.NoError(t, err)
+
+	// The commuter of 10 minutes would wait until 12 and gives up, so the
+	// bike is ready for the next one. The last one still waits at the end.
+	assert.Equal(t, 3, report.Served)
+	assert.Equal(t, 1, report.Rejected)
+	assert.Equal(t, 1, report.Waiting)
+	assert.Equal(t, []time.Duration{0, time.Minute, 0}, report.Waits)
+	assert.InDelta(t, 0.2, report.RejectionRate(), 1e-9)
+}
+
+func TestRun_LeastUsed(t *testing.T) {
+	config := singleStation()
+	config.Bikes = 4
+	config.Docks = 4
+	config.Duration = 24 * time.Hour
+
+	report, err := Run(config)
+	require.NoError(t, err)
+
+	// The bikes take turns
+	spread := report.UsageSpread()
+	assert.LessOrEqual(t, spread.Max-spread.Min, 1)
+	assert.Empty(t, report.Rejected)
+}
+
+func TestRun_Overdue(t *testing.T) {
+	config := singleStation()
+	config.Bikes = 60
+	config.Docks = 60
+	config.Rate = 1
+	config.Duration = 48 * time.Hour
+	config.OverdueRate = 1
+
+	report, err := Run(config)
+	require.NoError(t, err)
+
+	// Started hourly, the rides of the first 24 hours become overdue
+	// before the end
+	assert.Equal(t, 49, report.Served)
+	assert.Equal(t, 24, report.Overdue)
+}
+
+func TestRun_Rerouted(t *testing.T) {
+	config := singleStation()
+	config.Stations = 2
+	config.Docks = 1
+	config.Bikes = 2
+
+	// Both stations are full: a bike is taken at one and docked at the
+	// other, whose bike cannot have left yet
+	report, err := Run(config)
+	require.NoError(t, err)
+	assert.Equal(t, report.Rides, report.Rerouted)
+}
+
+func TestRun_Reproducible(t *testing.T) {
+	first, err := Run(DefaultConfig())
+	require.NoError(t, err)
+	second, err := Run(DefaultConfig())
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	assert.Equal(t, handover.Cooldown, first.Config.Cooldown)
+	var out bytes.Buffer
+	first.Print(&out)
+	assert.Contains(t, out.String(), "least-used, cooldown 5m0s")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	config := DefaultConfig()
+	assert.NoError(t, config.Validate())
+
+	config.Bikes = config.Stations*config.Docks + 1
+	assert.EqualError(t, config.Validate(), "bikes must be between 0 and the 200 docks")
+
+	config = DefaultConfig()
+	config.Arrivals = "bursty"
+	assert.Error(t, config.Validate())
+
+	config = DefaultConfig()
+	config.Rule = "most-used"
+	_, err := Run(config)
+	assert.Error(t, err)
+}
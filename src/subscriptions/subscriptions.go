@@ -0,0 +1,164 @@
+package subscriptions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/pricing"
+	"github.com/yourusername/bike-rental/src/wallet"
+)
+
+// ErrInsufficientBalance is returned when the wallet of the user cannot pay
+// for the plan
+var ErrInsufficientBalance = errors.New("subscriptions: insufficient wallet balance")
+
+// ActivePlan returns the plan of the subscription covering at, nil if the
+// user has none
+func ActivePlan(ctx context.Context, db wallet.Queryer, userID string, at time.Time) (*models.Plan, error) {
+	var plan models.Plan
+	query := `SELECT p.id, p.name, p.period, p.price_cents, p.included_minutes, p.max_rentals
+	          FROM subscriptions s
+	          INNER JOIN plans p ON p.id = s.plan_id
+	          WHERE s.user_id = $1 AND s.started_at <= $2 AND s.expires_at > $2
+	          ORDER BY s.started_at DESC
+	          LIMIT 1`
+	err := db.QueryRowContext(ctx, query, userID, at).Scan(&plan.ID, &plan.Name, &plan.Period, &plan.PriceCents, &plan.IncludedMinutes, &plan.MaxRentals)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// MaxRentals returns how many bikes a user with plan can ride at once, one
+// without a plan
+func MaxRentals(plan *models.Plan) int {
+	if plan == nil || plan.MaxRentals < 1 {
+		return 1
+	}
+	return plan.MaxRentals
+}
+
+// Tariff returns the tariff of a user with plan
+func Tariff(tariff *pricing.Tariff, plan *models.Plan) *pricing.Tariff {
+	if plan == nil {
+		return tariff
+	}
+	return tariff.Subscribed(plan.IncludedMinutes)
+}
+
+// NextExpiry returns the end of the period of plan starting at from
+func NextExpiry(plan models.Plan, from time.Time) time.Time {
+	return PeriodEnd(plan, from, 1)
+}
+
+// PeriodEnd returns the end of the nth period of plan started at start. A
+// period ending in a month lacking the day of start ends on its last day,
+// the next ones ending on the day of start again.
+func PeriodEnd(plan models.Plan, start time.Time, n int) time.Time {
+	months := n
+	if plan.Period == models.PeriodYear {
+		months = 12 * n
+	}
+	end := start.AddDate(0, months, 0)
+
+	// AddDate normalizes Jan 31 plus a month to early March
+	if end.Day() != start.Day() {
+		end = end.AddDate(0, 0, -end.Day())
+	}
+	return end
+}
+
+// nextPeriodEnd returns the end of the first period of plan started at start
+// ending after from
+func nextPeriodEnd(plan models.Plan, start, from time.Time) time.Time {
+	for n := 1; ; n++ {
+		if end := PeriodEnd(plan, start, n); end.After(from) {
+			return end
+		}
+	}
+}
+
+// Subscribe starts a subscription to plan for a user, its first period
+// paid from their wallet
+func Subscribe(ctx context.Context, db *sql.DB, subscription *models.Subscription, plan models.Plan, createdBy string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	subscription.PlanID = plan.ID
+	subscription.Status = models.SubscriptionActive
+	subscription.ExpiresAt = NextExpiry(plan, subscription.StartedAt)
+	query := `INSERT INTO subscriptions (user_id, plan_id, status, auto_renew, started_at, expires_at, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $5)
+	          RETURNING id`
+	if err := tx.QueryRowContext(ctx, query, subscription.UserID, subscription.PlanID, subscription.Status, subscription.AutoRenew, subscription.StartedAt, subscription.ExpiresAt).Scan(&subscription.ID); err != nil {
+		return err
+	}
+
+	if err := pay(ctx, tx, subscription.UserID, plan, subscription.StartedAt, subscription.ExpiresAt, subscription.StartedAt, createdBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Renew pays the next period of a subscription from the wallet of its user
+// and pushes its expiry back
+func Renew(ctx context.Context, db *sql.DB, subscription *models.Subscription, plan models.Plan, at time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The new period starts when the previous one ended. It is counted from
+	// the start of the subscription, for the periods ending on the 31st not
+	// to end on the 28th once they crossed February.
+	expiresAt := nextPeriodEnd(plan, subscription.StartedAt, subscription.ExpiresAt)
+	if err := pay(ctx, tx, subscription.UserID, plan, subscription.ExpiresAt, expiresAt, at, ""); err != nil {
+		return err
+	}
+
+	query := "UPDATE subscriptions SET expires_at = $1, updated_at = $2 WHERE id = $3 AND status = 'active'"
+	if _, err := tx.ExecContext(ctx, query, expiresAt, at, subscription.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	subscription.ExpiresAt = expiresAt
+	return nil
+}
+
+// pay debits at the price of plan for the period from start to end
+func pay(ctx context.Context, tx *sql.Tx, userID string, plan models.Plan, start, end, at time.Time, createdBy string) error {
+	if plan.PriceCents == 0 {
+		return nil
+	}
+
+	balance, err := wallet.Balance(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if balance < plan.PriceCents {
+		return ErrInsufficientBalance
+	}
+
+	transaction := models.WalletTransaction{
+		Kind:        models.WalletSubscription,
+		AmountCents: plan.PriceCents,
+		Description: sql.NullString{String: fmt.Sprintf("%s from %s to %s", plan.Name, start.Format(time.DateOnly), end.Format(time.DateOnly)), Valid: true},
+		CreatedBy:   sql.NullString{String: createdBy, Valid: createdBy != ""},
+		CreatedAt:   at,
+	}
+	return wallet.TransferTx(ctx, tx, wallet.User(userID), wallet.System(wallet.AccountRevenue), &transaction)
+}
@@ -0,0 +1,125 @@
+package subscriptions
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/database/models"
+	"github.com/yourusername/bike-rental/src/pricing"
+)
+
+func TestNextExpiry(t *testing.T) {
+	monthly := models.Plan{Period: models.PeriodMonth}
+	annual := models.Plan{Period: models.PeriodYear}
+
+	tests := []struct {
+		name string
+		plan models.Plan
+		from time.Time
+		want time.Time
+	}{
+		{"month", monthly, time.Date(2024, 8, 21, 7, 0, 0, 0, time.UTC), time.Date(2024, 9, 21, 7, 0, 0, 0, time.UTC)},
+		{"month ending on a shorter one", monthly, time.Date(2025, 1, 31, 7, 0, 0, 0, time.UTC), time.Date(2025, 2, 28, 7, 0, 0, 0, time.UTC)},
+		{"year", annual, time.Date(2024, 8, 21, 7, 0, 0, 0, time.UTC), time.Date(2025, 8, 21, 7, 0, 0, 0, time.UTC)},
+		{"year from a leap day", annual, time.Date(2024, 2, 29, 7, 0, 0, 0, time.UTC), time.Date(2025, 2, 28, 7, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NextExpiry(tt.plan, tt.from))
+		})
+	}
+}
+
+func TestPeriodEnd(t *testing.T) {
+	annual := models.Plan{Period: models.PeriodYear}
+	leapDay := time.Date(2024, 2, 29, 7, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2025, 2, 28, 7, 0, 0, 0, time.UTC), PeriodEnd(annual, leapDay, 1))
+	assert.Equal(t, time.Date(2028, 2, 29, 7, 0, 0, 0, time.UTC), PeriodEnd(annual, leapDay, 4))
+}
+
+func TestRenew_EndOfMonth(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	plan := models.Plan{ID: "free", Name: "Free pass", Period: models.PeriodMonth}
+	startedAt := time.Date(2024, 1, 31, 7, 0, 0, 0, time.UTC)
+	subscription := models.Subscription{ID: 4, UserID: "user-1", StartedAt: startedAt, ExpiresAt: NextExpiry(plan, startedAt)}
+	assert.Equal(t, time.Date(2024, 2, 29, 7, 0, 0, 0, time.UTC), subscription.ExpiresAt)
+
+	// The periods end on the 31st again after February, or on the last day
+	// of the shorter months
+	for _, want := range []time.Time{
+		time.Date(2024, 3, 31, 7, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 30, 7, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 31, 7, 0, 0, 0, time.UTC),
+	} {
+		at := subscription.ExpiresAt.Add(time.Hour)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE subscriptions SET expires_at = \$1, updated_at = \$2 WHERE id = \$3 AND status = 'active'`).
+			WithArgs(want, at, 4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, Renew(context.Background(), db, &subscription, plan, at))
+		assert.Equal(t, want, subscription.ExpiresAt)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestActivePlan(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2024, 8, 21, 7, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT p.id, p.name, p.period, p.price_cents, p.included_minutes, p.max_rentals FROM subscriptions s`).
+		WithArgs("user-1", at).
+		WillReturnError(sql.ErrNoRows)
+
+	// Without a subscription, the plain tariff applies to a single rental
+	plan, err := ActivePlan(context.Background(), db, "user-1", at)
+	assert.NoError(t, err)
+	assert.Nil(t, plan)
+	assert.Equal(t, 1, MaxRentals(plan))
+
+	tariff := &pricing.Tariff{UnlockFee: 100}
+	assert.Same(t, tariff, Tariff(tariff, plan))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRenew_InsufficientBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	expiresAt := time.Date(2024, 8, 21, 7, 0, 0, 0, time.UTC)
+	subscription := models.Subscription{ID: 4, UserID: "user-1", ExpiresAt: expiresAt}
+	plan := models.Plan{ID: "monthly", Name: "Monthly pass", Period: models.PeriodMonth, PriceCents: 1990}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(e.amount_cents\), 0\) FROM wallet_entries`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+	mock.ExpectRollback()
+
+	err = Renew(context.Background(), db, &subscription, plan, expiresAt.Add(time.Hour))
+	assert.Equal(t, ErrInsufficientBalance, err)
+	assert.Equal(t, expiresAt, subscription.ExpiresAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
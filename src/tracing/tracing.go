@@ -0,0 +1,186 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationPrefix = "github.com/yourusername/bike-rental/src/"
+
+// Config holds the [tracing] section of the configuration file
+type Config struct {
+	Enabled     bool    `toml:"enabled"`
+	Exporter    string  `toml:"exporter"`     // "otlp" (default) or "stdout"
+	Endpoint    string  `toml:"endpoint"`     // OTLP/HTTP collector, e.g. "otel-collector:4318"
+	Insecure    bool    `toml:"insecure"`     // plain HTTP to the collector
+	ServiceName string  `toml:"service_name"` // defaults to "bike-rental"
+	SampleRatio float64 `toml:"sample_ratio"` // defaults to 1, every trace
+}
+
+// Init installs the global tracer and meter providers, exporting to the
+// same destination. It returns a function flushing and stopping the
+// exporters, to be called on shutdown.
+func Init(ctx context.Context, config *Config) (func(context.Context) error, error) {
+	if !config.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var metricExporter sdkmetric.Exporter
+	var err error
+	switch strings.ToLower(config.Exporter) {
+	case "", "otlp":
+		options := []otlptracehttp.Option{}
+		metricOptions := []otlpmetrichttp.Option{}
+		if config.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+			metricOptions = append(metricOptions, otlpmetrichttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+			metricOptions = append(metricOptions, otlpmetrichttp.WithInsecure())
+		}
+		if exporter, err = otlptracehttp.New(ctx, options...); err == nil {
+			metricExporter, err = otlpmetrichttp.New(ctx, metricOptions...)
+		}
+	case "stdout":
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout)); err == nil {
+			metricExporter, err = stdoutmetric.New(stdoutmetric.WithWriter(os.Stdout))
+		}
+	default:
+		err = fmt.Errorf("unknown trace exporter %q", config.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	ratio := config.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	provider := NewProvider(exporter, config.ServiceName, sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))))
+	meterProvider := NewMeterProvider(sdkmetric.NewPeriodicReader(metricExporter), config.ServiceName)
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
+
+// NewProvider builds a tracer provider batching spans to the exporter and
+// installs it globally. Tests can pass a tracetest.InMemoryExporter.
+func NewProvider(exporter sdktrace.SpanExporter, serviceName string, options ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	if serviceName == "" {
+		serviceName = "bike-rental"
+	}
+
+	options = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	}, options...)
+	provider := sdktrace.NewTracerProvider(options...)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider
+}
+
+// NewMeterProvider builds a meter provider collected by the reader and
+// installs it globally. Tests can pass a sdkmetric.ManualReader.
+func NewMeterProvider(reader sdkmetric.Reader, serviceName string) *sdkmetric.MeterProvider {
+	if serviceName == "" {
+		serviceName = "bike-rental"
+	}
+
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetMeterProvider(provider)
+
+	return provider
+}
+
+// Meter returns the meter of a package of this module
+func Meter(pkg string) metric.Meter {
+	return otel.Meter(instrumentationPrefix + pkg)
+}
+
+// Tracer returns the tracer of a package of this module
+func Tracer(pkg string) trace.Tracer {
+	return otel.Tracer(instrumentationPrefix + pkg)
+}
+
+// OpenDB opens a database whose statements are all recorded as spans,
+// children of the span found in the context they are issued with
+func OpenDB(driverName, dsn string) (*sql.DB, error) {
+	return otelsql.Open(driverName, dsn,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
+}
+
+// Middleware starts a server span per request, named after the chi route
+// pattern once the request has been routed
+func Middleware(next http.Handler) http.Handler {
+	tracer := Tracer("http")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddleware_RouteAndSQLSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(exporter, "test")
+	defer provider.Shutdown(context.Background())
+
+	// Open a traced mock database
+	_, mock, err := sqlmock.NewWithDSN("tracing-test")
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	db, err := OpenDB("sqlmock", "tracing-test")
+	if err != nil {
+		t.Fatalf("Failed to open traced database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id FROM bikes WHERE id = \\$1").
+		WithArgs("bike-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("bike-1"))
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/bikes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var id string
+		if err := db.QueryRowContext(r.Context(), "SELECT id FROM bikes WHERE id = $1", chi.URLParam(r, "id")).Scan(&id); err != nil {
+			http.Error(w, "Failed to fetch bike", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(id))
+	})
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bikes/bike-1", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	assert.NoError(t, provider.ForceFlush(context.Background()))
+	spans := exporter.GetSpans()
+
+	// The route span is named after the chi pattern and parents the SQL span
+	var route, query *tracetest.SpanStub
+	for i := range spans {
+		switch spans[i].Name {
+		case "GET /bikes/{id}":
+			route = &spans[i]
+		case "sql.conn.query":
+			query = &spans[i]
+		}
+	}
+	if assert.NotNil(t, route, "route span not recorded") && assert.NotNil(t, query, "SQL span not recorded") {
+		assert.Equal(t, route.SpanContext.TraceID(), query.SpanContext.TraceID())
+		assert.Equal(t, route.SpanContext.SpanID(), query.Parent.SpanID())
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMeter_GlobalProvider(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := NewMeterProvider(reader, "test")
+	defer provider.Shutdown(context.Background())
+
+	// Meters taken from the global provider are collected by the reader
+	counter, err := Meter("api").Int64Counter("rides.started")
+	if err != nil {
+		t.Fatalf("Failed to create counter: %v", err)
+	}
+	counter.Add(context.Background(), 2)
+
+	var collected metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &collected))
+	if assert.Len(t, collected.ScopeMetrics, 1) && assert.Len(t, collected.ScopeMetrics[0].Metrics, 1) {
+		assert.Equal(t, instrumentationPrefix+"api", collected.ScopeMetrics[0].Scope.Name)
+		sum := collected.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+		assert.Equal(t, int64(2), sum.DataPoints[0].Value)
+	}
+}
@@ -0,0 +1,184 @@
+package wallet
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+// System accounts of the ledger
+const (
+	AccountTopUps  = "topups"  // where the money topped up comes from
+	AccountRevenue = "revenue" // where the ride charges go
+)
+
+// ErrRefundExceedsFare is returned by Refund when the refunds of a ride would
+// exceed its fare altogether
+var ErrRefundExceedsFare = errors.New("wallet: refunds exceed the fare of the ride")
+
+// Account is a user account, or a system account when UserID is empty
+type Account struct {
+	UserID string
+	Name   string
+}
+
+// User returns the account of a user
+func User(userID string) Account {
+	return Account{UserID: userID}
+}
+
+// System returns a system account
+func System(name string) Account {
+	return Account{Name: name}
+}
+
+// Queryer is satisfied by both *sql.DB and *sql.Tx
+type Queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Balance returns the balance of a user in cents, 0 if they have no account
+func Balance(ctx context.Context, db Queryer, userID string) (int64, error) {
+	var balance int64
+	query := `SELECT COALESCE(SUM(e.amount_cents), 0)
+	          FROM wallet_entries e
+	          INNER JOIN wallet_accounts a ON a.id = e.account_id
+	          WHERE a.user_id = $1`
+	err := db.QueryRowContext(ctx, query, userID).Scan(&balance)
+	return balance, err
+}
+
+// Transfer moves transaction.AmountCents from an account to another, as a
+// transaction of two balanced entries
+func Transfer(ctx context.Context, db *sql.DB, from, to Account, transaction *models.WalletTransaction) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := TransferTx(ctx, tx, from, to, transaction); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TransferTx is Transfer within tx, for the callers changing other records
+// along with the money
+func TransferTx(ctx context.Context, tx *sql.Tx, from, to Account, transaction *models.WalletTransaction) error {
+	if transaction.AmountCents <= 0 {
+		return fmt.Errorf("wallet: transfer of %d cents", transaction.AmountCents)
+	}
+
+	debited, err := accountID(ctx, tx, from)
+	if err != nil {
+		return err
+	}
+	credited, err := accountID(ctx, tx, to)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO wallet_transactions (kind, assignment_id, description, created_by, created_at)
+	          VALUES ($1, $2, $3, $4, $5)
+	          RETURNING id`
+	if err := tx.QueryRowContext(ctx, query, transaction.Kind, transaction.AssignmentID, transaction.Description, transaction.CreatedBy, transaction.CreatedAt).Scan(&transaction.ID); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO wallet_entries (transaction_id, account_id, amount_cents)
+	         VALUES ($1, $2, $3), ($1, $4, $5)`
+	_, err = tx.ExecContext(ctx, query, transaction.ID, debited, -transaction.AmountCents, credited, transaction.AmountCents)
+	return err
+}
+
+// Refund credits transaction.AmountCents to a user from the revenue. The
+// refunds of a ride, transaction.AssignmentID being set, must not exceed its
+// fare altogether: the assignment is locked while the earlier ones are
+// summed, and sql.ErrNoRows is returned when it is not a ride of the user.
+func Refund(ctx context.Context, db *sql.DB, userID string, transaction *models.WalletTransaction) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if transaction.AssignmentID.Valid {
+		var fare sql.NullInt64
+		query := "SELECT fare_cents FROM assignments WHERE id = $1 AND user_id = $2 FOR UPDATE"
+		if err := tx.QueryRowContext(ctx, query, transaction.AssignmentID.Int64, userID).Scan(&fare); err != nil {
+			return err
+		}
+
+		var refunded int64
+		query = `SELECT COALESCE(SUM(e.amount_cents), 0)
+		         FROM wallet_transactions t
+		         INNER JOIN wallet_entries e ON e.transaction_id = t.id
+		         INNER JOIN wallet_accounts a ON a.id = e.account_id
+		         WHERE t.assignment_id = $1 AND t.kind = $2 AND a.user_id = $3`
+		if err := tx.QueryRowContext(ctx, query, transaction.AssignmentID.Int64, models.WalletRefund, userID).Scan(&refunded); err != nil {
+			return err
+		}
+		if refunded+transaction.AmountCents > fare.Int64 {
+			return ErrRefundExceedsFare
+		}
+	}
+
+	if err := TransferTx(ctx, tx, System(AccountRevenue), User(userID), transaction); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ChargeRide debits the fare of a ride from the wallet of its user, within
+// the transaction closing the ride. Free rides are not recorded.
+func ChargeRide(ctx context.Context, tx *sql.Tx, userID string, assignmentID uint, fareCents int64, at time.Time) error {
+	return chargeTx(ctx, tx, models.WalletRideCharge, userID, assignmentID, fareCents, at)
+}
+
+// ChargePenalty debits the overdue penalty of a ride still going on from the
+// wallet of its user, within the transaction flagging the ride
+func ChargePenalty(ctx context.Context, tx *sql.Tx, userID string, assignmentID uint, penaltyCents int64, at time.Time) error {
+	return chargeTx(ctx, tx, models.WalletPenalty, userID, assignmentID, penaltyCents, at)
+}
+
+// ChargeReplacement debits the replacement fee of a bike lost or stolen
+// during a ride from the wallet of its user, within the transaction
+// declaring the bike
+func ChargeReplacement(ctx context.Context, tx *sql.Tx, userID string, assignmentID uint, feeCents int64, at time.Time) error {
+	return chargeTx(ctx, tx, models.WalletReplacement, userID, assignmentID, feeCents, at)
+}
+
+func chargeTx(ctx context.Context, tx *sql.Tx, kind, userID string, assignmentID uint, cents int64, at time.Time) error {
+	if cents == 0 {
+		return nil
+	}
+
+	transaction := models.WalletTransaction{
+		Kind:         kind,
+		AmountCents:  cents,
+		AssignmentID: sql.NullInt64{Int64: int64(assignmentID), Valid: assignmentID != 0},
+		CreatedAt:    at,
+	}
+	return TransferTx(ctx, tx, User(userID), System(AccountRevenue), &transaction)
+}
+
+// accountID returns the ID of an account, opening it for a user without one
+func accountID(ctx context.Context, tx *sql.Tx, account Account) (int64, error) {
+	var id int64
+	if account.UserID == "" {
+		query := "SELECT id FROM wallet_accounts WHERE name = $1"
+		err := tx.QueryRowContext(ctx, query, account.Name).Scan(&id)
+		return id, err
+	}
+
+	query := `INSERT INTO wallet_accounts (user_id) VALUES ($1)
+	          ON CONFLICT (user_id) DO UPDATE SET user_id = EXCLUDED.user_id
+	          RETURNING id`
+	err := tx.QueryRowContext(ctx, query, account.UserID).Scan(&id)
+	return id, err
+}
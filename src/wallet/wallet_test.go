@@ -0,0 +1,144 @@
+package wallet
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/bike-rental/src/database/models"
+)
+
+func TestChargeRide(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO wallet_accounts \(user_id\) VALUES \(\$1\) ON CONFLICT \(user_id\) DO UPDATE SET user_id = EXCLUDED.user_id RETURNING id`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`SELECT id FROM wallet_accounts WHERE name = \$1`).
+		WithArgs("revenue").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`INSERT INTO wallet_transactions \(kind, assignment_id, description, created_by, created_at\)`).
+		WithArgs("ride_charge", int64(12), nil, nil, at).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(40))
+
+	// The user is debited what the revenue account is credited
+	mock.ExpectExec(`INSERT INTO wallet_entries \(transaction_id, account_id, amount_cents\) VALUES \(\$1, \$2, \$3\), \(\$1, \$4, \$5\)`).
+		WithArgs(40, int64(3), int64(-550), int64(2), int64(550)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, ChargeRide(context.Background(), tx, "user-1", 12, 550, at))
+	assert.NoError(t, tx.Commit())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChargeRide_RolledBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO wallet_accounts`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`SELECT id FROM wallet_accounts WHERE name = \$1`).
+		WithArgs("revenue").
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	assert.Error(t, ChargeRide(context.Background(), tx, "user-1", 12, 550, time.Now()))
+	assert.NoError(t, tx.Rollback())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChargeRide_Free(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+
+	// Nothing is recorded
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, ChargeRide(context.Background(), tx, "user-1", 12, 0, time.Now()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefund(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2024, 8, 21, 7, 33, 52, 0, time.UTC)
+
+	// The ride was charged 700 and refunded 300 already
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT fare_cents FROM assignments WHERE id = \$1 AND user_id = \$2 FOR UPDATE`).
+		WithArgs(int64(12), "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"fare_cents"}).AddRow(700))
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(e.amount_cents\), 0\) FROM wallet_transactions t`).
+		WithArgs(int64(12), "refund", "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(300))
+	mock.ExpectQuery(`SELECT id FROM wallet_accounts WHERE name = \$1`).
+		WithArgs("revenue").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`INSERT INTO wallet_accounts`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO wallet_transactions`).
+		WithArgs("refund", sqlmock.AnyArg(), nil, nil, at).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(41))
+	mock.ExpectExec(`INSERT INTO wallet_entries`).
+		WithArgs(41, int64(2), int64(-400), int64(3), int64(400)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	transaction := models.WalletTransaction{Kind: models.WalletRefund, AmountCents: 400, AssignmentID: sql.NullInt64{Int64: 12, Valid: true}, CreatedAt: at}
+	assert.NoError(t, Refund(context.Background(), db, "user-1", &transaction))
+	assert.Equal(t, uint(41), transaction.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefund_ExceedsFare(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// The refunds of the ride would add up to more than its fare
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT fare_cents FROM assignments WHERE id = \$1 AND user_id = \$2 FOR UPDATE`).
+		WithArgs(int64(12), "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"fare_cents"}).AddRow(700))
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(e.amount_cents\), 0\) FROM wallet_transactions t`).
+		WithArgs(int64(12), "refund", "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(700))
+	mock.ExpectRollback()
+
+	transaction := models.WalletTransaction{Kind: models.WalletRefund, AmountCents: 1, AssignmentID: sql.NullInt64{Int64: 12, Valid: true}, CreatedAt: time.Now()}
+	assert.Equal(t, ErrRefundExceedsFare, Refund(context.Background(), db, "user-1", &transaction))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}